@@ -0,0 +1,75 @@
+// Command phpser-replace replaces text in a MySQL or MariaDB dump without
+// breaking php serialized values, their lengths are recomputed after the
+// replacement.
+//
+//	phpser-replace [-dry-run] [-repair] [-o out.sql] old new [dump.sql]
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+
+	dryRun := flag.Bool("dry-run", false, "only report what would be replaced")
+	repair := flag.Bool("repair", false, "repair serialized values with wrong lengths")
+	output := flag.String("o", "", "write the result to `file` instead of stdout")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: phpser-replace [flags] old new [dump.sql]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 || flag.NArg() > 3 || flag.Arg(0) == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var in io.Reader = os.Stdin
+	if flag.NArg() == 3 {
+		f, err := os.Open(flag.Arg(2))
+		if err != nil {
+			fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var out io.Writer = os.Stdout
+	report := os.Stderr
+	switch {
+	case *dryRun:
+		out = io.Discard
+		report = os.Stdout
+	case *output != "":
+		f, err := os.Create(*output)
+		if err != nil {
+			fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w := bufio.NewWriter(out)
+	rw := newRewriter(bufio.NewReader(in), w, []byte(flag.Arg(0)), []byte(flag.Arg(1)))
+	rw.repair = *repair
+	if err := rw.run(); err != nil {
+		fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		fatal(err)
+	}
+	rw.writeReport(report)
+
+}
+
+func fatal(err error) {
+
+	fmt.Fprintln(os.Stderr, "phpser-replace:", err)
+	os.Exit(1)
+
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/zengxinqian/phpserialize"
+)
+
+type tableReport struct {
+	values       int // values changed
+	replacements int
+	repaired     int
+	skipped      int // custom values left untouched
+}
+
+// rewriter copies a sql dump and runs the replacement on every single quoted
+// string in it. Identifiers, double quoted strings and comments are copied
+// as they are.
+type rewriter struct {
+	r      *bufio.Reader
+	w      *bufio.Writer
+	old    []byte
+	new    []byte
+	repair bool
+
+	stmt   []byte // start of the current statement
+	table  string
+	tables map[string]*tableReport
+}
+
+func newRewriter(r *bufio.Reader, w *bufio.Writer, old, new []byte) *rewriter {
+	return &rewriter{r: r, w: w, old: old, new: new, tables: make(map[string]*tableReport)}
+}
+
+func (rw *rewriter) run() error {
+
+	for {
+
+		c, err := rw.r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch c {
+		case '\'':
+			raw, err := rw.quoted(c)
+			if err != nil {
+				return err
+			}
+			rw.value(raw)
+		case '"':
+			raw, err := rw.quoted(c)
+			if err != nil {
+				return err
+			}
+			rw.w.WriteByte(c)
+			rw.w.Write(raw)
+			rw.w.WriteByte(c)
+		case '`':
+			name, err := rw.identifier()
+			if err != nil {
+				return err
+			}
+			rw.w.WriteByte(c)
+			rw.w.Write(name)
+			rw.w.WriteByte(c)
+			if isInsert(rw.stmt) {
+				rw.table = string(bytes.ReplaceAll(name, []byte("``"), []byte("`")))
+			}
+			rw.addStmt(name)
+		case '#':
+			rw.w.WriteByte(c)
+			if err := rw.copyUntil("\n"); err != nil {
+				return err
+			}
+		case '-', '/':
+			rw.w.WriteByte(c)
+			next, _ := rw.r.Peek(2)
+			if c == '-' && len(next) == 2 && next[0] == '-' && (next[1] == ' ' || next[1] == '\t' || next[1] == '\n') {
+				if err := rw.copyUntil("\n"); err != nil {
+					return err
+				}
+			} else if c == '/' && len(next) > 0 && next[0] == '*' {
+				if err := rw.copyUntil("*/"); err != nil {
+					return err
+				}
+			} else {
+				rw.addStmt([]byte{c})
+			}
+		case ';':
+			rw.w.WriteByte(c)
+			rw.stmt = rw.stmt[:0]
+		default:
+			rw.w.WriteByte(c)
+			rw.addStmt([]byte{c})
+		}
+
+	}
+
+}
+
+// addStmt remembers the start of the current statement, long enough to tell
+// an insert from other statements.
+func (rw *rewriter) addStmt(b []byte) {
+
+	if len(rw.stmt) < 64 {
+		rw.stmt = append(rw.stmt, b...)
+	}
+
+}
+
+func isInsert(stmt []byte) bool {
+
+	s := bytes.ToUpper(bytes.Join(bytes.Fields(stmt), []byte(" ")))
+	return bytes.HasSuffix(s, []byte("INTO")) && (bytes.HasPrefix(s, []byte("INSERT")) || bytes.HasPrefix(s, []byte("REPLACE")))
+
+}
+
+// quoted reads a string up to the closing quote and returns it still escaped.
+func (rw *rewriter) quoted(quote byte) ([]byte, error) {
+
+	var raw []byte
+	for {
+
+		c, err := rw.r.ReadByte()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		switch c {
+		case '\\':
+			next, err := rw.r.ReadByte()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			raw = append(raw, c, next)
+			continue
+		case quote:
+			if next, _ := rw.r.Peek(1); len(next) == 0 || next[0] != quote {
+				return raw, nil
+			}
+			rw.r.ReadByte()
+			raw = append(raw, c)
+		}
+		raw = append(raw, c)
+
+	}
+
+}
+
+func (rw *rewriter) identifier() ([]byte, error) {
+
+	var name []byte
+	for {
+
+		c, err := rw.r.ReadByte()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if c == '`' {
+			if next, _ := rw.r.Peek(1); len(next) == 0 || next[0] != '`' {
+				return name, nil
+			}
+			rw.r.ReadByte()
+			name = append(name, c)
+		}
+		name = append(name, c)
+
+	}
+
+}
+
+func (rw *rewriter) copyUntil(end string) error {
+
+	var last []byte
+	for {
+
+		c, err := rw.r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rw.w.WriteByte(c)
+		last = append(last, c)
+		if len(last) > len(end) {
+			last = last[1:]
+		}
+		if string(last) == end {
+			return nil
+		}
+
+	}
+
+}
+
+// value runs the replacement on the contents of a single quoted string. The
+// original bytes are kept when nothing changes.
+func (rw *rewriter) value(raw []byte) {
+
+	data := unescape(raw)
+	repaired := false
+	if rw.repair && looksSerialized(data) && !phpserialize.Valid(data) {
+		if fixed, fixes, err := phpserialize.Repair(data); err == nil && len(fixes) > 0 {
+			data, repaired = fixed, true
+		}
+	}
+
+	result, n, skipped := phpserialize.ReplaceReport(data, rw.old, rw.new)
+	if len(skipped) > 0 {
+		rw.report().skipped += len(skipped)
+	}
+
+	rw.w.WriteByte('\'')
+	if n == 0 && !repaired {
+		rw.w.Write(raw)
+	} else {
+		rw.w.Write(escape(result))
+		report := rw.report()
+		report.values++
+		report.replacements += n
+		if repaired {
+			report.repaired++
+		}
+	}
+	rw.w.WriteByte('\'')
+
+}
+
+func (rw *rewriter) report() *tableReport {
+
+	report := rw.tables[rw.table]
+	if report == nil {
+		report = &tableReport{}
+		rw.tables[rw.table] = report
+	}
+	return report
+
+}
+
+func (rw *rewriter) writeReport(w io.Writer) {
+
+	names := make([]string, 0, len(rw.tables))
+	for name := range rw.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var total tableReport
+	for _, name := range names {
+		report := rw.tables[name]
+		if name == "" {
+			name = "(no table)"
+		}
+		report.write(w, name)
+		total.values += report.values
+		total.replacements += report.replacements
+		total.repaired += report.repaired
+		total.skipped += report.skipped
+	}
+	total.write(w, "total")
+
+}
+
+func (report *tableReport) write(w io.Writer, name string) {
+
+	fmt.Fprintf(w, "%s: %d replacements in %d values, %d repaired", name, report.replacements, report.values, report.repaired)
+	if report.skipped > 0 {
+		fmt.Fprintf(w, ", %d custom values of unknown format skipped", report.skipped)
+	}
+	fmt.Fprintln(w)
+
+}
+
+func looksSerialized(data []byte) bool {
+
+	if len(data) < 2 {
+		return false
+	}
+	return bytes.IndexByte([]byte("sabidOCN"), data[0]) >= 0 && (data[1] == ':' || data[1] == ';')
+
+}
+
+func unescape(raw []byte) []byte {
+
+	if bytes.IndexByte(raw, '\\') < 0 && bytes.IndexByte(raw, '\'') < 0 {
+		return raw
+	}
+
+	data := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if c == '\'' && i+1 < len(raw) && raw[i+1] == '\'' {
+			i++
+		} else if c == '\\' && i+1 < len(raw) {
+			i++
+			switch c = raw[i]; c {
+			case '0':
+				c = 0
+			case 'b':
+				c = '\b'
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'Z':
+				c = 0x1a
+			case '%', '_':
+				data = append(data, '\\')
+			}
+		}
+		data = append(data, c)
+	}
+	return data
+
+}
+
+// escape escapes data the way mysqldump does.
+func escape(data []byte) []byte {
+
+	raw := make([]byte, 0, len(data)+len(data)/8)
+	for _, c := range data {
+		switch c {
+		case 0:
+			raw = append(raw, '\\', '0')
+		case '\n':
+			raw = append(raw, '\\', 'n')
+		case '\r':
+			raw = append(raw, '\\', 'r')
+		case 0x1a:
+			raw = append(raw, '\\', 'Z')
+		case '\\', '\'', '"':
+			raw = append(raw, '\\', c)
+		default:
+			raw = append(raw, c)
+		}
+	}
+	return raw
+
+}
+
+func unexpectedEOF(err error) error {
+
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+
+}
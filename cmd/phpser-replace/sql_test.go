@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRewriter(t *testing.T) {
+
+	dump := "-- dump of 'http://old.test'\n" +
+		"/* 'http://old.test' */\n" +
+		"INSERT INTO `wp_options` VALUES (1,'siteurl','http://old.test','yes'),(2,'widget','a:1:{i:0;s:20:\\\"http://old.test/page\\\";}','no');\n" +
+		"INSERT INTO `wp_posts` VALUES (1,'it''s http://old.test\\nnext','s:2:\\\"ab\\\";');\n" +
+		"INSERT INTO `wp_meta` VALUES (1,'s:3:\\\"http://old.test\\\";');\n"
+
+	expect := "-- dump of 'http://old.test'\n" +
+		"/* 'http://old.test' */\n" +
+		"INSERT INTO `wp_options` VALUES (1,'siteurl','https://new.test','yes'),(2,'widget','a:1:{i:0;s:21:\\\"https://new.test/page\\\";}','no');\n" +
+		"INSERT INTO `wp_posts` VALUES (1,'it\\'s https://new.test\\nnext','s:2:\\\"ab\\\";');\n" +
+		"INSERT INTO `wp_meta` VALUES (1,'s:16:\\\"https://new.test\\\";');\n"
+
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	rw := newRewriter(bufio.NewReader(strings.NewReader(dump)), w, []byte("http://old.test"), []byte("https://new.test"))
+	rw.repair = true
+	if err := rw.run(); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+
+	if out.String() != expect {
+		t.Fatalf("expect:\n%s\ngot:\n%s", expect, out.String())
+	}
+
+	var report bytes.Buffer
+	rw.writeReport(&report)
+	expectReport := "wp_meta: 1 replacements in 1 values, 1 repaired\n" +
+		"wp_options: 2 replacements in 2 values, 0 repaired\n" +
+		"wp_posts: 1 replacements in 1 values, 0 repaired\n" +
+		"total: 4 replacements in 4 values, 1 repaired\n"
+	if report.String() != expectReport {
+		t.Fatalf("expect:\n%s\ngot:\n%s", expectReport, report.String())
+	}
+
+}
+
+func TestRewriter_SkippedCustom(t *testing.T) {
+
+	dump := "INSERT INTO `wp_options` VALUES (1,'C:3:\\\"Foo\\\":15:{http://old.test}');\n"
+
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	rw := newRewriter(bufio.NewReader(strings.NewReader(dump)), w, []byte("http://old.test"), []byte("https://new.test"))
+	if err := rw.run(); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+
+	if out.String() != dump {
+		t.Fatalf("expect:\n%s\ngot:\n%s", dump, out.String())
+	}
+
+	var report bytes.Buffer
+	rw.writeReport(&report)
+	expectReport := "wp_options: 0 replacements in 0 values, 0 repaired, 1 custom values of unknown format skipped\n" +
+		"total: 0 replacements in 0 values, 0 repaired, 1 custom values of unknown format skipped\n"
+	if report.String() != expectReport {
+		t.Fatalf("expect:\n%s\ngot:\n%s", expectReport, report.String())
+	}
+
+}
@@ -0,0 +1,426 @@
+// Command phpser inspects php serialized values, the way one would with
+// unserialize and var_dump in a php one-liner.
+//
+//	phpser validate [-v] [file ...]
+//	phpser pretty [-r] [file ...]
+//	phpser json [-objects inline|nested|plain] [-strings replace|escape|base64] [-lists=false] [file ...]
+//	phpser from-json [file ...]
+//	phpser get [-text] path [file ...]
+//	phpser classes [file ...]
+//	phpser stats [file ...]
+//
+// Input is read from the files, or from stdin when there are none or a file
+// is "-". An input can hold several values, one after the other or separated
+// by whitespace. Paths are keys separated by dots, like user.roles.0.
+//
+// The exit status is 0 on success, 1 when a value is invalid, a path is not
+// found or an input can't be read, and 2 for usage errors.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/zengxinqian/phpserialize"
+)
+
+const usage = `usage: phpser command [flags] [file ...]
+
+commands:
+  validate   check the values and report syntax errors
+  pretty     print the values like var_dump, or print_r with -r
+  json       convert the values to JSON, one per line
+  from-json  convert JSON values to serialized values, one per line
+  get        print the value at a path like user.roles.0
+  classes    list the object classes used and how often
+  stats      print a summary of the values
+`
+
+// commands define their flags on the flag set and parse the arguments after
+// the command name.
+var commands = map[string]func(c *cli, flags *flag.FlagSet, args []string) error{
+	"validate":  validate,
+	"pretty":    pretty,
+	"json":      toJSON,
+	"from-json": fromJSON,
+	"get":       get,
+	"classes":   classes,
+	"stats":     stats,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+type cli struct {
+	stdin  io.Reader
+	stdout *bufio.Writer
+	stderr io.Writer
+	failed bool // a value was invalid or not found
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "phpser: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("phpser "+args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	c := &cli{stdin: stdin, stdout: bufio.NewWriter(stdout), stderr: stderr}
+	err := cmd(c, flags, args[1:])
+	if ferr := c.stdout.Flush(); err == nil {
+		err = ferr
+	}
+
+	switch {
+	case err == flag.ErrHelp || err == errUsage:
+		return 2
+	case err != nil:
+		fmt.Fprintln(stderr, "phpser:", err)
+		return 1
+	case c.failed:
+		return 1
+	}
+	return 0
+
+}
+
+var errUsage = errors.New("usage")
+
+// parse parses the flags and returns the n leading arguments and the files.
+func parse(flags *flag.FlagSet, args []string, n int, names ...string) ([]string, []string, error) {
+
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if flags.NArg() < n {
+		fmt.Fprintf(flags.Output(), "usage: %s [flags] %s [file ...]\n", flags.Name(), strings.Join(names, " "))
+		flags.PrintDefaults()
+		return nil, nil, errUsage
+	}
+	return flags.Args()[:n], flags.Args()[n:], nil
+
+}
+
+// value is one serialized value of an input. For a broken value data holds
+// what was read of it and err the syntax error.
+type value struct {
+	input string
+	index int // position of the value in its input, from 1
+	data  []byte
+	err   error
+}
+
+func (v *value) String() string {
+	return fmt.Sprintf("%s:%d", v.input, v.index)
+}
+
+// values calls fn with every value of the inputs. A broken value ends its
+// input, as it is not known where the next value would start.
+func (c *cli) values(files []string, fn func(v *value) error) error {
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, name := range files {
+		if err := c.input(name, func(r io.Reader) error { return c.decode(name, r, fn) }); err != nil {
+			return err
+		}
+	}
+	return nil
+
+}
+
+func (c *cli) input(name string, fn func(r io.Reader) error) error {
+
+	if name == "-" {
+		return fn(c.stdin)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return fn(f)
+
+}
+
+func (c *cli) decode(name string, r io.Reader, fn func(v *value) error) error {
+
+	if name == "-" {
+		name = "<stdin>"
+	}
+	dec := phpserialize.NewDecoder(r)
+	for i := 1; ; i++ {
+
+		var raw phpserialize.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			return nil
+		}
+
+		var syntax *phpserialize.SyntaxError
+		if err != nil && !errors.As(err, &syntax) && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		if err != nil {
+			// the decoder keeps the broken value buffered
+			data, _ := io.ReadAll(dec.Buffered())
+			if derr := valueError(data); derr != nil {
+				err = derr
+			}
+			err = fn(&value{input: name, index: i, data: data, err: err})
+			if err == nil {
+				c.failed = true
+			}
+			return err
+		}
+		if err = fn(&value{input: name, index: i, data: raw}); err != nil {
+			return err
+		}
+
+	}
+
+}
+
+// valueError returns the syntax error of a broken value with its offset in
+// the value instead of the input.
+func valueError(data []byte) error {
+	return phpserialize.Dump(io.Discard, data)
+}
+
+// report writes the error of a broken value with the data around it.
+func (c *cli) report(w io.Writer, v *value) {
+
+	fmt.Fprintf(w, "%s: %s\n", v, strings.TrimSpace(v.err.Error()))
+
+	var syntax *phpserialize.SyntaxError
+	if !errors.As(v.err, &syntax) {
+		return
+	}
+	off := int(syntax.Offset)
+	if off > len(v.data) {
+		off = len(v.data)
+	}
+	start, end := off-30, off+30
+	if start < 0 {
+		start = 0
+	}
+	if end > len(v.data) {
+		end = len(v.data)
+	}
+	line := []rune(printable(v.data[start:end]))
+	caret := len([]rune(printable(v.data[start:off])))
+	fmt.Fprintf(w, "\t%s\n\t%s^\n", string(line), strings.Repeat(" ", caret))
+
+}
+
+// printable replaces control characters so the data fits on one line.
+func printable(b []byte) string {
+
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return '.'
+		}
+		return r
+	}, string(b))
+
+}
+
+func validate(c *cli, flags *flag.FlagSet, args []string) error {
+
+	verbose := flags.Bool("v", false, "print the number of valid values of every input")
+	_, files, err := parse(flags, args, 0)
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[string]int)
+	var inputs []string
+	err = c.values(files, func(v *value) error {
+		if len(inputs) == 0 || inputs[len(inputs)-1] != v.input {
+			inputs = append(inputs, v.input)
+		}
+		if v.err != nil {
+			c.report(c.stdout, v)
+			return nil
+		}
+		counts[v.input]++
+		return nil
+	})
+	if *verbose {
+		for _, input := range inputs {
+			fmt.Fprintf(c.stdout, "%s: %d valid\n", input, counts[input])
+		}
+	}
+	return err
+
+}
+
+func pretty(c *cli, flags *flag.FlagSet, args []string) error {
+
+	printR := flags.Bool("r", false, "print like print_r instead of var_dump")
+	_, files, err := parse(flags, args, 0)
+	if err != nil {
+		return err
+	}
+
+	return c.values(files, func(v *value) error {
+		c.print(v.data, *printR)
+		if v.err != nil {
+			c.stdout.Flush()
+			c.report(c.stderr, v)
+		}
+		return nil
+	})
+
+}
+
+// print writes data like var_dump or print_r, ending with a newline.
+func (c *cli) print(data []byte, printR bool) {
+
+	var out strings.Builder
+	if printR {
+		phpserialize.PrintR(&out, data)
+	} else {
+		phpserialize.Dump(&out, data)
+	}
+	if !strings.HasSuffix(out.String(), "\n") {
+		out.WriteByte('\n')
+	}
+	c.stdout.WriteString(out.String())
+
+}
+
+func toJSON(c *cli, flags *flag.FlagSet, args []string) error {
+
+	objects := flags.String("objects", "inline", "write objects `inline`, nested or plain")
+	strs := flags.String("strings", "replace", "`replace`, escape or base64 strings that are not UTF-8")
+	lists := flags.Bool("lists", true, "write arrays with the keys 0..n-1 as lists")
+	_, files, err := parse(flags, args, 0)
+	if err != nil {
+		return err
+	}
+
+	opts := &phpserialize.JSONOptions{ArraysAsObjects: !*lists}
+	switch *objects {
+	case "inline":
+		opts.Objects = phpserialize.JSONObjectInline
+	case "nested":
+		opts.Objects = phpserialize.JSONObjectNested
+	case "plain":
+		opts.Objects = phpserialize.JSONObjectPlain
+	default:
+		return fmt.Errorf("unknown -objects %q", *objects)
+	}
+	switch *strs {
+	case "replace":
+		opts.Strings = phpserialize.JSONStringReplace
+	case "escape":
+		opts.Strings = phpserialize.JSONStringEscape
+	case "base64":
+		opts.Strings = phpserialize.JSONStringBase64
+	default:
+		return fmt.Errorf("unknown -strings %q", *strs)
+	}
+
+	return c.values(files, func(v *value) error {
+		if v.err != nil {
+			c.stdout.Flush()
+			c.report(c.stderr, v)
+			return nil
+		}
+		return opts.ToJSON(c.stdout, bytes.NewReader(v.data))
+	})
+
+}
+
+func get(c *cli, flags *flag.FlagSet, args []string) error {
+
+	text := flags.Bool("text", false, "print the value like print_r, scalars as php echoes them")
+	pos, files, err := parse(flags, args, 1, "path")
+	if err != nil {
+		return err
+	}
+
+	var path []interface{}
+	if pos[0] != "" {
+		for _, key := range strings.Split(pos[0], ".") {
+			path = append(path, key)
+		}
+	}
+
+	return c.values(files, func(v *value) error {
+		if v.err != nil {
+			c.stdout.Flush()
+			c.report(c.stderr, v)
+			return nil
+		}
+		raw, err := phpserialize.GetRaw(v.data, path...)
+		var notFound *phpserialize.PathError
+		if errors.As(err, &notFound) {
+			c.stdout.Flush()
+			fmt.Fprintf(c.stderr, "%s: %s not found\n", v, pos[0])
+			c.failed = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !*text {
+			c.stdout.Write(raw)
+			return c.stdout.WriteByte('\n')
+		}
+		c.print(raw, true)
+		return nil
+	})
+
+}
+
+func fromJSON(c *cli, flags *flag.FlagSet, args []string) error {
+
+	_, files, err := parse(flags, args, 0)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	for _, name := range files {
+		err := c.input(name, func(r io.Reader) error {
+			dec := json.NewDecoder(r)
+			for {
+				var raw json.RawMessage
+				if err := dec.Decode(&raw); err == io.EOF {
+					return nil
+				} else if err != nil {
+					return fmt.Errorf("%s: %v", name, err)
+				}
+				if err := phpserialize.FromJSON(c.stdout, bytes.NewReader(raw)); err != nil {
+					return err
+				}
+				c.stdout.WriteByte('\n')
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+
+}
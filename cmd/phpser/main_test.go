@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+
+	input := "a:2:{s:1:\"a\";i:1;s:1:\"o\";O:3:\"Foo\":1:{s:1:\"x\";a:1:{i:0;O:3:\"Bar\":0:{}}}}\ns:2:\"hi\";\n"
+
+	testList := []struct {
+		args   []string
+		input  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{"validate", "-v"}, input, 0, "<stdin>: 2 valid\n", ""},
+		{[]string{"validate"}, "i:1;a:2:{i:0;i:1x;}", 1,
+			"<stdin>:2: invalid character 'x' in int value, offset: 12\n\ta:2:{i:0;i:1x;}\n\t            ^\n", ""},
+		{[]string{"pretty"}, "i:1; b:1;", 0, "int(1)\nbool(true)\n", ""},
+		{[]string{"pretty", "-r"}, `a:1:{i:0;s:2:"ab";}`, 0, "Array\n(\n    [0] => ab\n)\n", ""},
+		{[]string{"pretty"}, `a:1:{i:0;s:3:"ab`, 1, "array(1) {\n  [0]=>\n  *** unexpected end of php serialize data ***\n",
+			"<stdin>:1: unexpected end of php serialize data\n\ta:1:{i:0;s:3:\"ab\n\t                ^\n"},
+		{[]string{"json"}, input, 0, "{\"a\":1,\"o\":{\"__class\":\"Foo\",\"x\":[{\"__class\":\"Bar\"}]}}\n\"hi\"\n", ""},
+		{[]string{"json", "-objects", "plain", "-lists=false"}, input, 0, "{\"a\":1,\"o\":{\"x\":{\"0\":{}}}}\n\"hi\"\n", ""},
+		{[]string{"from-json"}, "{\"a\":[1,\"x\"]} null", 0, "a:1:{s:1:\"a\";a:2:{i:0;i:1;i:1;s:1:\"x\";}}\nN;\n", ""},
+		{[]string{"get", "o.x.0"}, input, 1, "O:3:\"Bar\":0:{}\n", "<stdin>:2: o.x.0 not found\n"},
+		{[]string{"get", "-text", "a"}, input[:strings.IndexByte(input, '\n')], 0, "1\n", ""},
+		{[]string{"classes"}, input, 0, "Bar\t1\nFoo\t1\n", ""},
+		{[]string{"stats"}, "a:1:{i:0;s:3:\"abc\";}C:3:\"Foo\":2:{xy}", 0,
+			"values          2\ninvalid         0\nbytes           36\nnull            0\nbool            0\nint             0\nfloat           0\n" +
+				"string          1\narray           1\nobject          0\ncustom          1\nclasses         1\nmax depth       1\nlongest string  3\n", ""},
+		{[]string{"unknown"}, "", 2, "", "phpser: unknown command \"unknown\"\n\n" + usage},
+	}
+
+	for i, test := range testList {
+
+		var stdout, stderr bytes.Buffer
+		code := run(test.args, strings.NewReader(test.input), &stdout, &stderr)
+		if code != test.code {
+			t.Fatalf("Test fail at index %d, expect exit code %d got %d: %s", i, test.code, code, stderr.String())
+		}
+		if stdout.String() != test.stdout {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.stdout, stdout.String())
+		}
+		if stderr.String() != test.stderr {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.stderr, stderr.String())
+		}
+
+	}
+
+}
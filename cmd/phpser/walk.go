@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+)
+
+// summary counts what the values of the inputs are made of.
+type summary struct {
+	values    int
+	invalid   int
+	bytes     int
+	types     map[byte]int
+	classes   map[string]int
+	maxDepth  int
+	maxString int
+}
+
+func newSummary() *summary {
+	return &summary{types: make(map[byte]int), classes: make(map[string]int)}
+}
+
+var typeNames = []struct {
+	tag  byte
+	name string
+}{
+	{'N', "null"},
+	{'b', "bool"},
+	{'i', "int"},
+	{'d', "float"},
+	{'s', "string"},
+	{'a', "array"},
+	{'O', "object"},
+	{'C', "custom"},
+}
+
+func (s *summary) add(v *value) {
+
+	if v.err != nil {
+		s.invalid++
+		return
+	}
+	s.values++
+	s.bytes += len(v.data)
+	s.walk(v.data, 0, 0, false)
+
+}
+
+// walk counts the valid value at off and returns the offset after it. Keys
+// are jumped over without being counted.
+func (s *summary) walk(data []byte, off int, depth int, key bool) int {
+
+	tag := data[off]
+	if !key {
+		s.types[tag]++
+		if depth > s.maxDepth {
+			s.maxDepth = depth
+		}
+	}
+
+	switch tag {
+	case 'N':
+		return off + 2
+	case 'b', 'i', 'd':
+		for data[off] != ';' {
+			off++
+		}
+		return off + 1
+	case 's':
+		n, start := length(data, off+2)
+		if !key && n > s.maxString {
+			s.maxString = n
+		}
+		return quotedEnd(data, start+1+n) + 1
+	case 'a':
+		n, start := length(data, off+2)
+		return s.elements(data, start+1, n, depth)
+	case 'O', 'C':
+		n, start := length(data, off+2)
+		class := string(data[start+1 : start+1+n])
+		if !key {
+			s.classes[class]++
+		}
+		n, start = length(data, quotedEnd(data, start+1+n)+1)
+		if tag == 'C' {
+			return start + 1 + n + 1
+		}
+		return s.elements(data, start+1, n, depth)
+	}
+	panic("phpser: walk out of sync with the decoder")
+
+}
+
+func (s *summary) elements(data []byte, off int, n int, depth int) int {
+
+	for i := 0; i < n; i++ {
+		off = s.walk(data, off, depth+1, true)
+		off = s.walk(data, off, depth+1, false)
+	}
+	return off + 1 //skip }
+
+}
+
+// length reads the length at off and returns it with the offset of the byte
+// after the ':'.
+func length(data []byte, off int) (int, int) {
+
+	n := 0
+	for ; data[off] != ':'; off++ {
+		n = n*10 + int(data[off]-'0')
+	}
+	return n, off + 1
+
+}
+
+// quotedEnd returns the offset after the closing quote at off, like php the
+// decoder accepts a missing one.
+func quotedEnd(data []byte, off int) int {
+
+	if data[off] == '"' {
+		return off + 1
+	}
+	return off
+
+}
+
+func classes(c *cli, flags *flag.FlagSet, args []string) error {
+
+	_, files, err := parse(flags, args, 0)
+	if err != nil {
+		return err
+	}
+
+	s := newSummary()
+	err = c.values(files, func(v *value) error {
+		if v.err != nil {
+			c.report(c.stderr, v)
+		}
+		s.add(v)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(s.classes))
+	for name := range s.classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(c.stdout, "%s\t%d\n", name, s.classes[name])
+	}
+	return nil
+
+}
+
+func stats(c *cli, flags *flag.FlagSet, args []string) error {
+
+	_, files, err := parse(flags, args, 0)
+	if err != nil {
+		return err
+	}
+
+	s := newSummary()
+	err = c.values(files, func(v *value) error {
+		if v.err != nil {
+			c.report(c.stderr, v)
+		}
+		s.add(v)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "values\t%d\n", s.values)
+	fmt.Fprintf(w, "invalid\t%d\n", s.invalid)
+	fmt.Fprintf(w, "bytes\t%d\n", s.bytes)
+	for _, t := range typeNames {
+		fmt.Fprintf(w, "%s\t%d\n", t.name, s.types[t.tag])
+	}
+	fmt.Fprintf(w, "classes\t%d\n", len(s.classes))
+	fmt.Fprintf(w, "max depth\t%d\n", s.maxDepth)
+	fmt.Fprintf(w, "longest string\t%d\n", s.maxString)
+	return w.Flush()
+
+}
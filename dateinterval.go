@@ -0,0 +1,235 @@
+package phpserialize
+
+import (
+	"errors"
+	"time"
+)
+
+// DateLayout selects the properties DateInterval and DatePeriod are written
+// with, php 8.2 changed them.
+type DateLayout int
+
+const (
+	// DateLayoutPHP82 is the layout of php 8.2 and later
+	DateLayoutPHP82 DateLayout = iota
+	// DateLayoutPHP7 is the layout of php 7 up to 8.1, with the relative
+	// weekday and special fields
+	DateLayoutPHP7
+)
+
+// DateInterval is php's DateInterval. The fields are kept as php has them,
+// they are not normalized, so 36 hours stay 36 hours.
+type DateInterval struct {
+	Y, M, D int
+	H, I, S int
+	F       float64 // fraction of a second
+
+	Invert bool
+	// Days is the total number of days of an interval made by
+	// DateTime::diff, nil where php has false.
+	Days *int
+
+	// DateString is set for intervals made by createFromDateString, which
+	// php 8.2 keeps as the string instead of the fields.
+	DateString string
+
+	Layout DateLayout
+
+	// weekday, weekday_behavior, first_last_day_of, special_type,
+	// special_amount, have_weekday_relative and have_special_relative of the
+	// php 7 layout
+	relative [7]int
+}
+
+var relativeProperties = [...]string{
+	"weekday", "weekday_behavior", "first_last_day_of",
+	"special_type", "special_amount", "have_weekday_relative", "have_special_relative",
+}
+
+func (di DateInterval) MarshalPHP() ([]byte, error) {
+
+	var days interface{} = false
+	if di.Days != nil {
+		days = *di.Days
+	}
+	invert := 0
+	if di.Invert {
+		invert = 1
+	}
+
+	props := []interface{}{"y", di.Y, "m", di.M, "d", di.D, "h", di.H, "i", di.I, "s", di.S, "f", di.F}
+	switch {
+	case di.Layout == DateLayoutPHP7:
+		for i, name := range relativeProperties[:3] {
+			props = append(props, name, di.relative[i])
+		}
+		props = append(props, "invert", invert, "days", days)
+		for i, name := range relativeProperties[3:] {
+			props = append(props, name, di.relative[i+3])
+		}
+	case di.DateString != "":
+		props = []interface{}{"from_string", true, "date_string", di.DateString}
+	default:
+		props = append(props, "invert", invert, "days", days, "from_string", false)
+	}
+	return marshalObject("DateInterval", props...)
+
+}
+
+func (di *DateInterval) UnmarshalPHP(data []byte) error {
+
+	var p struct {
+		Y          int         `php:"y"`
+		M          int         `php:"m"`
+		D          int         `php:"d"`
+		H          int         `php:"h"`
+		I          int         `php:"i"`
+		S          int         `php:"s"`
+		F          float64     `php:"f"`
+		Invert     int         `php:"invert"`
+		Days       interface{} `php:"days"`
+		FromString bool        `php:"from_string"`
+		DateString string      `php:"date_string"`
+	}
+	if err := Unmarshal(data, &p); err != nil {
+		return err
+	}
+	var props map[string]interface{}
+	if err := Unmarshal(data, &props); err != nil {
+		return err
+	}
+
+	*di = DateInterval{Y: p.Y, M: p.M, D: p.D, H: p.H, I: p.I, S: p.S, F: p.F, Invert: p.Invert != 0}
+	if days, ok := p.Days.(int64); ok {
+		n := int(days)
+		di.Days = &n
+	}
+	if p.FromString {
+		di.DateString = p.DateString
+	}
+	if _, ok := props["weekday_behavior"]; ok {
+		di.Layout = DateLayoutPHP7
+		for i, name := range relativeProperties {
+			if n, ok := props[name].(int64); ok {
+				di.relative[i] = int(n)
+			}
+		}
+	}
+	return nil
+
+}
+
+// AddTo adds the interval to t the way DateTime::add does, years, months
+// and days on the calendar, overflowing like php into the next month, then
+// the time. Intervals with a DateString are not applied.
+func (di DateInterval) AddTo(t time.Time) time.Time {
+
+	sign := 1
+	if di.Invert {
+		sign = -1
+	}
+	t = t.AddDate(sign*di.Y, sign*di.M, sign*di.D)
+	d := time.Duration(di.H)*time.Hour + time.Duration(di.I)*time.Minute + time.Duration(di.S)*time.Second +
+		time.Duration(di.F*float64(time.Second))
+	return t.Add(time.Duration(sign) * d)
+
+}
+
+// Duration returns the interval as a time.Duration. It reports false when
+// the interval has years, months or days, whose length depends on the date
+// it is added to.
+func (di DateInterval) Duration() (time.Duration, bool) {
+
+	if di.Y != 0 || di.M != 0 || di.D != 0 || di.DateString != "" {
+		return 0, false
+	}
+	d := time.Duration(di.H)*time.Hour + time.Duration(di.I)*time.Minute + time.Duration(di.S)*time.Second +
+		time.Duration(di.F*float64(time.Second))
+	if di.Invert {
+		d = -d
+	}
+	return d, true
+
+}
+
+// DatePeriod is php's DatePeriod. Recurrences is the property as php writes
+// it, which counts the start date when IncludeStartDate is set.
+type DatePeriod struct {
+	Start            time.Time
+	Current          *time.Time
+	End              *time.Time
+	Interval         DateInterval
+	Recurrences      int
+	IncludeStartDate bool
+	IncludeEndDate   bool // php 8.2 and later
+
+	Layout DateLayout
+}
+
+func (dp DatePeriod) MarshalPHP() ([]byte, error) {
+
+	var current, end interface{}
+	if dp.Current != nil {
+		current = *dp.Current
+	}
+	if dp.End != nil {
+		end = *dp.End
+	}
+
+	interval := dp.Interval
+	interval.Layout = dp.Layout
+	props := []interface{}{"start", dp.Start, "current", current, "end", end, "interval", interval,
+		"recurrences", dp.Recurrences, "include_start_date", dp.IncludeStartDate}
+	if dp.Layout == DateLayoutPHP82 {
+		props = append(props, "include_end_date", dp.IncludeEndDate)
+	}
+	return marshalObject("DatePeriod", props...)
+
+}
+
+var errDatePeriodStart = errors.New("php serialize: DatePeriod without a start date")
+
+func (dp *DatePeriod) UnmarshalPHP(data []byte) error {
+
+	var p struct {
+		Start            *time.Time   `php:"start"`
+		Current          *time.Time   `php:"current"`
+		End              *time.Time   `php:"end"`
+		Interval         DateInterval `php:"interval"`
+		Recurrences      int          `php:"recurrences"`
+		IncludeStartDate bool         `php:"include_start_date"`
+		IncludeEndDate   *bool        `php:"include_end_date"`
+	}
+	if err := Unmarshal(data, &p); err != nil {
+		return err
+	}
+	if p.Start == nil {
+		return errDatePeriodStart
+	}
+
+	*dp = DatePeriod{Start: *p.Start, Current: p.Current, End: p.End, Interval: p.Interval,
+		Recurrences: p.Recurrences, IncludeStartDate: p.IncludeStartDate, Layout: DateLayoutPHP7}
+	if p.IncludeEndDate != nil {
+		dp.IncludeEndDate = *p.IncludeEndDate
+		dp.Layout = DateLayoutPHP82
+	}
+	return nil
+
+}
+
+// marshalObject writes an object with the properties in order, names and
+// values alternating.
+func marshalObject(class string, props ...interface{}) ([]byte, error) {
+
+	var body []byte
+	for i := 0; i < len(props); i += 2 {
+		body = appendPHPString(body, []byte(props[i].(string)))
+		v, err := Marshal(props[i+1])
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, v...)
+	}
+	return appendContainer(nil, phpTypeObject, class, len(props)/2, body), nil
+
+}
@@ -0,0 +1,119 @@
+package phpserialize
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateInterval(t *testing.T) {
+
+	testList := []string{
+		// php 8.2 DateTime::diff
+		`O:12:"DateInterval":10:{s:1:"y";i:0;s:1:"m";i:2;s:1:"d";i:4;s:1:"h";i:10;s:1:"i";i:0;s:1:"s";i:0;s:1:"f";d:0.5;s:6:"invert";i:1;s:4:"days";i:64;s:11:"from_string";b:0;}`,
+		// php 8.2 createFromDateString
+		`O:12:"DateInterval":2:{s:11:"from_string";b:1;s:11:"date_string";s:6:"3 days";}`,
+		// php 7 new DateInterval('P1D')
+		`O:12:"DateInterval":16:{s:1:"y";i:0;s:1:"m";i:0;s:1:"d";i:1;s:1:"h";i:0;s:1:"i";i:0;s:1:"s";i:0;s:1:"f";d:0;` +
+			`s:7:"weekday";i:0;s:16:"weekday_behavior";i:0;s:17:"first_last_day_of";i:0;s:6:"invert";i:0;s:4:"days";b:0;` +
+			`s:12:"special_type";i:0;s:14:"special_amount";i:0;s:21:"have_weekday_relative";i:0;s:21:"have_special_relative";i:0;}`,
+	}
+
+	for i, test := range testList {
+
+		var di DateInterval
+		if err := Unmarshal([]byte(test), &di); err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		data, err := Marshal(di)
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(data) != test {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test, data)
+		}
+
+	}
+
+	var di DateInterval
+	Unmarshal([]byte(testList[0]), &di)
+	if di.M != 2 || di.H != 10 || di.F != 0.5 || !di.Invert || di.Days == nil || *di.Days != 64 || di.Layout != DateLayoutPHP82 {
+		t.Fatalf("unexpected interval %+v", di)
+	}
+
+	data, _ := Marshal(DateInterval{H: 1, Layout: DateLayoutPHP7})
+	var props map[string]interface{}
+	if err := Unmarshal(data, &props); err != nil || len(props) != 16 || props["days"] != false {
+		t.Fatalf("unexpected php 7 layout %s", data)
+	}
+
+}
+
+func TestDateInterval_AddTo(t *testing.T) {
+
+	start := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+
+	testList := []struct {
+		interval DateInterval
+		expect   time.Time
+	}{
+		{DateInterval{M: 1}, time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)},
+		{DateInterval{Y: 1, D: 1, H: 14, F: 0.25}, time.Date(2025, 2, 2, 0, 0, 0, 250000000, time.UTC)},
+		{DateInterval{D: 31, I: 30, Invert: true}, time.Date(2023, 12, 31, 9, 30, 0, 0, time.UTC)},
+		{DateInterval{DateString: "next monday"}, start},
+	}
+
+	for i, test := range testList {
+		if got := test.interval.AddTo(start); !got.Equal(test.expect) {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.expect, got)
+		}
+	}
+
+	if d, ok := (DateInterval{H: 1, I: 30, F: 0.5, Invert: true}).Duration(); !ok || d != -(90*time.Minute+500*time.Millisecond) {
+		t.Fatalf("unexpected duration %s %v", d, ok)
+	}
+	if _, ok := (DateInterval{D: 1}).Duration(); ok {
+		t.Fatal("expect days not to convert to a duration")
+	}
+
+}
+
+func TestDatePeriod(t *testing.T) {
+
+	testList := []string{
+		// php 8.2 new DatePeriod(new DateTime('2024-01-01'), new DateInterval('P1W'), 3)
+		`O:10:"DatePeriod":7:{s:5:"start";O:8:"DateTime":3:{s:4:"date";s:26:"2024-01-01 00:00:00.000000";s:13:"timezone_type";i:3;s:8:"timezone";s:3:"UTC";}` +
+			`s:7:"current";N;s:3:"end";N;` +
+			`s:8:"interval";O:12:"DateInterval":10:{s:1:"y";i:0;s:1:"m";i:0;s:1:"d";i:7;s:1:"h";i:0;s:1:"i";i:0;s:1:"s";i:0;s:1:"f";d:0;s:6:"invert";i:0;s:4:"days";b:0;s:11:"from_string";b:0;}` +
+			`s:11:"recurrences";i:4;s:18:"include_start_date";b:1;s:16:"include_end_date";b:0;}`,
+		// php 7 with an end date
+		`O:10:"DatePeriod":6:{s:5:"start";O:8:"DateTime":3:{s:4:"date";s:26:"2024-01-01 00:00:00.000000";s:13:"timezone_type";i:3;s:8:"timezone";s:3:"UTC";}` +
+			`s:7:"current";N;s:3:"end";O:8:"DateTime":3:{s:4:"date";s:26:"2024-02-01 00:00:00.000000";s:13:"timezone_type";i:3;s:8:"timezone";s:3:"UTC";}` +
+			`s:8:"interval";O:12:"DateInterval":16:{s:1:"y";i:0;s:1:"m";i:0;s:1:"d";i:7;s:1:"h";i:0;s:1:"i";i:0;s:1:"s";i:0;s:1:"f";d:0;` +
+			`s:7:"weekday";i:0;s:16:"weekday_behavior";i:0;s:17:"first_last_day_of";i:0;s:6:"invert";i:0;s:4:"days";b:0;` +
+			`s:12:"special_type";i:0;s:14:"special_amount";i:0;s:21:"have_weekday_relative";i:0;s:21:"have_special_relative";i:0;}` +
+			`s:11:"recurrences";i:1;s:18:"include_start_date";b:1;}`,
+	}
+
+	for i, test := range testList {
+
+		var dp DatePeriod
+		if err := Unmarshal([]byte(test), &dp); err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		data, err := Marshal(dp)
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(data) != test {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test, data)
+		}
+
+	}
+
+	var dp DatePeriod
+	Unmarshal([]byte(testList[1]), &dp)
+	if dp.End == nil || dp.End.Month() != time.February || dp.Interval.D != 7 || dp.Layout != DateLayoutPHP7 {
+		t.Fatalf("unexpected period %+v", dp)
+	}
+
+}
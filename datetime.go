@@ -0,0 +1,305 @@
+package phpserialize
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	locationType    = reflect.TypeOf(time.Location{})
+	locationPtrType = reflect.TypeOf((*time.Location)(nil))
+)
+
+// the properties php writes for DateTime and DateTimeZone
+type phpDateTime struct {
+	Date         string `php:"date"`
+	TimezoneType int    `php:"timezone_type"`
+	Timezone     string `php:"timezone"`
+}
+
+const (
+	timezoneOffset       = 1
+	timezoneAbbreviation = 2
+	timezoneIdentifier   = 3
+)
+
+const phpDateLayout = "2006-01-02 15:04:05.000000"
+
+type dateTimeEncoder string // class, the Encoder's DateTimeClass when empty
+
+func (class dateTimeEncoder) encode(e *encodeState, v reflect.Value) {
+
+	t := v.Interface().(time.Time)
+	typ, zone := phpTimezone(t)
+
+	body := appendPHPString(nil, []byte("date"))
+	body = appendPHPString(body, []byte(t.Format(phpDateLayout)))
+	body = appendTimezone(body, typ, zone)
+	e.Write(appendContainer(nil, phpTypeObject, orDefault(string(class), orDefault(e.dateTimeClass, "DateTime")), 3, body))
+
+}
+
+type dateTimeZoneEncoder string // class, the Encoder's DateTimeZoneClass when empty
+
+func (class dateTimeZoneEncoder) encode(e *encodeState, v reflect.Value) {
+
+	if v.IsNil() {
+		e.WriteString(phpNullValue)
+		return
+	}
+	typ, zone := phpLocation(v.Interface().(*time.Location))
+
+	body := appendTimezone(nil, typ, zone)
+	e.Write(appendContainer(nil, phpTypeObject, orDefault(string(class), orDefault(e.dateTimeZoneClass, "DateTimeZone")), 2, body))
+
+}
+
+// classEncoder returns the encoder of a field with a class tag option, the
+// option only applies to time.Time and *time.Location.
+func classEncoder(t reflect.Type, class string, enc encoderFunc) encoderFunc {
+
+	switch t {
+	case timeType:
+		return dateTimeEncoder(class).encode
+	case reflect.PtrTo(timeType):
+		return ptrEncoder{dateTimeEncoder(class).encode}.encode
+	case locationPtrType:
+		return dateTimeZoneEncoder(class).encode
+	}
+	return enc
+
+}
+
+func appendTimezone(b []byte, typ int, zone string) []byte {
+
+	b = appendPHPString(b, []byte("timezone_type"))
+	b = append(b, byte(phpTypeInteger), phpSeparator)
+	b = strconv.AppendInt(b, int64(typ), 10)
+	b = append(b, phpTerminator)
+	b = appendPHPString(b, []byte("timezone"))
+	return appendPHPString(b, []byte(zone))
+
+}
+
+// phpTimezone returns how php describes the location of t. Names with a
+// slash and UTC are identifiers, other fixed zone names abbreviations. Zones
+// without a usable name, like time.Local, are written as their offset.
+func phpTimezone(t time.Time) (int, string) {
+
+	name := t.Location().String()
+	if name == "UTC" || strings.Contains(name, "/") {
+		return timezoneIdentifier, name
+	}
+
+	abbr, offset := t.Zone()
+	if name != "" && name != "Local" && isAbbreviation(abbr) {
+		return timezoneAbbreviation, abbr
+	}
+	return timezoneOffset, formatOffset(offset)
+
+}
+
+// phpLocation returns how php describes loc on its own, as a DateTimeZone.
+// Names that are no identifiers are abbreviations when php knows them, other
+// zones are written as their offset at the Unix epoch, which is their only
+// offset for fixed zones.
+func phpLocation(loc *time.Location) (int, string) {
+
+	name := loc.String()
+	if name == "UTC" || strings.Contains(name, "/") {
+		return timezoneIdentifier, name
+	}
+	if _, ok := timezoneAbbreviations[strings.ToUpper(name)]; ok {
+		return timezoneAbbreviation, name
+	}
+	_, offset := time.Unix(0, 0).In(loc).Zone()
+	return timezoneOffset, formatOffset(offset)
+
+}
+
+// formatOffset writes an offset in seconds as php's +hh:mm.
+func formatOffset(offset int) string {
+
+	b := []byte{'+'}
+	if offset < 0 {
+		b[0] = '-'
+		offset = -offset
+	}
+	b = append(b, byte('0'+offset/36000), byte('0'+offset/3600%10), ':')
+	b = append(b, byte('0'+offset/600%6), byte('0'+offset/60%10))
+	return string(b)
+
+}
+
+func isAbbreviation(s string) bool {
+
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') {
+			return false
+		}
+	}
+	return true
+
+}
+
+// timezoneAbbreviations holds the offsets of the abbreviations php writes
+// with timezone_type 2.
+var timezoneAbbreviations = map[string]int{
+	"UTC": 0, "GMT": 0, "Z": 0, "WET": 0, "WEST": 3600, "BST": 3600,
+	"CET": 3600, "CEST": 7200, "MET": 3600, "MEST": 7200,
+	"EET": 7200, "EEST": 10800, "MSK": 10800,
+	"PKT": 18000, "WIB": 25200, "HKT": 28800, "AWST": 28800,
+	"JST": 32400, "KST": 32400, "ACST": 34200, "ACDT": 37800,
+	"AEST": 36000, "AEDT": 39600, "NZST": 43200, "NZDT": 46800,
+	"HST": -36000, "AKST": -32400, "AKDT": -28800,
+	"PST": -28800, "PDT": -25200, "MST": -25200, "MDT": -21600,
+	"CST": -21600, "CDT": -18000, "EST": -18000, "EDT": -14400,
+	"AST": -14400, "ADT": -10800, "NST": -12600, "NDT": -9000,
+}
+
+// location returns the location described by the timezone properties.
+func (p *phpDateTime) location() (*time.Location, bool) {
+
+	switch p.TimezoneType {
+	case timezoneOffset:
+		s := p.Timezone
+		if len(s) != 6 || (s[0] != '+' && s[0] != '-') || s[3] != ':' {
+			return nil, false
+		}
+		h, err1 := strconv.Atoi(s[1:3])
+		m, err2 := strconv.Atoi(s[4:6])
+		if err1 != nil || err2 != nil {
+			return nil, false
+		}
+		offset := h*3600 + m*60
+		if s[0] == '-' {
+			offset = -offset
+		}
+		return time.FixedZone("", offset), true
+	case timezoneAbbreviation:
+		abbr := strings.ToUpper(p.Timezone)
+		offset, ok := timezoneAbbreviations[abbr]
+		if !ok {
+			return nil, false
+		}
+		return time.FixedZone(abbr, offset), true
+	case timezoneIdentifier:
+		if p.Timezone == "UTC" {
+			return time.UTC, true
+		}
+		loc, err := time.LoadLocation(p.Timezone)
+		return loc, err == nil
+	}
+	return nil, false
+
+}
+
+// dateTime stores a DateTime object in t. Strings are read as RFC 3339 or
+// php's Y-m-d H:i:s format in UTC, null as the zero time.
+func (d *decodeState) dateTime(t *time.Time) error {
+
+	start := d.readIndex()
+	d.skip()
+	raw := d.data[start:d.off]
+
+	switch phpValueType(raw[0]) {
+	case phpTypeNull:
+		*t = time.Time{}
+		return nil
+	case phpTypeString:
+		var s string
+		if err := Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02"} {
+			if value, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+				*t = value
+				return nil
+			}
+		}
+		d.saveError(&UnmarshalTypeError{Value: "date " + strconv.Quote(s), Type: timeType, Offset: int64(start)})
+		return nil
+	case phpTypeObject:
+		var p phpDateTime
+		if err := Unmarshal(raw, &p); err != nil {
+			return err
+		}
+		loc, ok := p.location()
+		if !ok {
+			d.saveError(&UnmarshalTypeError{Value: "timezone " + strconv.Quote(p.Timezone), Type: timeType, Offset: int64(start)})
+			return nil
+		}
+		value, err := time.ParseInLocation("2006-01-02 15:04:05.999999999", p.Date, loc)
+		if err != nil {
+			d.saveError(&UnmarshalTypeError{Value: "date " + strconv.Quote(p.Date), Type: timeType, Offset: int64(start)})
+			return nil
+		}
+		*t = value
+		return nil
+	}
+
+	d.saveError(&UnmarshalTypeError{Value: phpTypeName(raw[0]), Type: timeType, Offset: int64(start)})
+	return nil
+
+}
+
+// dateTimeZone stores a DateTimeZone object in v, a *time.Location or a
+// time.Location that can't be replaced by a pointer.
+func (d *decodeState) dateTimeZone(v reflect.Value) error {
+
+	start := d.readIndex()
+	d.skip()
+	raw := d.data[start:d.off]
+
+	if phpValueType(raw[0]) == phpTypeNull {
+		if v.Kind() == reflect.Ptr {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+	if phpValueType(raw[0]) != phpTypeObject {
+		d.saveError(&UnmarshalTypeError{Value: phpTypeName(raw[0]), Type: locationPtrType, Offset: int64(start)})
+		return nil
+	}
+
+	var p phpDateTime
+	if err := Unmarshal(raw, &p); err != nil {
+		return err
+	}
+	loc, ok := p.location()
+	if !ok {
+		d.saveError(&UnmarshalTypeError{Value: "timezone " + strconv.Quote(p.Timezone), Type: locationPtrType, Offset: int64(start)})
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.ValueOf(loc))
+	} else {
+		v.Set(reflect.ValueOf(loc).Elem())
+	}
+	return nil
+
+}
+
+func phpTypeName(tag byte) string {
+
+	switch phpValueType(tag) {
+	case phpTypeBoolean:
+		return "bool"
+	case phpTypeInteger, phpTypeFloat:
+		return "number"
+	case phpTypeString:
+		return "string"
+	case phpTypeArray:
+		return "array"
+	case phpTypeCustom:
+		return "custom"
+	}
+	return "object"
+
+}
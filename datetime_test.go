@@ -0,0 +1,174 @@
+package phpserialize
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+type testDates struct {
+	Created time.Time      `php:"created"`
+	Updated *time.Time     `php:"updated,class=Carbon\\CarbonImmutable"`
+	Deleted *time.Time     `php:"deleted,class=Carbon\\Carbon"`
+	Zone    *time.Location `php:"zone,class=Carbon\\CarbonTimeZone"`
+}
+
+func TestDateTime(t *testing.T) {
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	testList := []struct {
+		value  time.Time
+		expect string
+	}{
+		{time.Date(2024, 1, 2, 3, 4, 5, 0, berlin),
+			`O:8:"DateTime":3:{s:4:"date";s:26:"2024-01-02 03:04:05.000000";s:13:"timezone_type";i:3;s:8:"timezone";s:13:"Europe/Berlin";}`},
+		{time.Date(2024, 7, 1, 12, 0, 0, 123456000, time.UTC),
+			`O:8:"DateTime":3:{s:4:"date";s:26:"2024-07-01 12:00:00.123456";s:13:"timezone_type";i:3;s:8:"timezone";s:3:"UTC";}`},
+		{time.Date(2024, 7, 1, 12, 0, 0, 0, time.FixedZone("", -(5*3600+1800))),
+			`O:8:"DateTime":3:{s:4:"date";s:26:"2024-07-01 12:00:00.000000";s:13:"timezone_type";i:1;s:8:"timezone";s:6:"-05:30";}`},
+		{time.Date(2024, 7, 1, 12, 0, 0, 0, time.FixedZone("EDT", -4*3600)),
+			`O:8:"DateTime":3:{s:4:"date";s:26:"2024-07-01 12:00:00.000000";s:13:"timezone_type";i:2;s:8:"timezone";s:3:"EDT";}`},
+	}
+
+	for i, test := range testList {
+
+		data, err := Marshal(test.value)
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(data) != test.expect {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.expect, data)
+		}
+
+		var value time.Time
+		if err = Unmarshal(data, &value); err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if !value.Equal(test.value) || value.Location().String() != test.value.Location().String() {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.value, value)
+		}
+
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.DateTimeClass("DateTimeImmutable")
+	enc.DateTimeZoneClass("Carbon\\CarbonTimeZone")
+	if err = enc.Encode([]interface{}{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), time.UTC}); err != nil {
+		t.Fatal(err)
+	}
+	expect := `a:2:{i:0;O:17:"DateTimeImmutable":3:{s:4:"date";s:26:"2024-01-02 00:00:00.000000";s:13:"timezone_type";i:3;s:8:"timezone";s:3:"UTC";}` +
+		`i:1;O:21:"Carbon\CarbonTimeZone":2:{s:13:"timezone_type";i:3;s:8:"timezone";s:3:"UTC";}}`
+	if buf.String() != expect {
+		t.Fatalf("expect:%s got %s", expect, buf.String())
+	}
+
+}
+
+func TestDateTimeZone(t *testing.T) {
+
+	testList := []struct {
+		value  *time.Location
+		expect string
+	}{
+		{time.UTC, `O:12:"DateTimeZone":2:{s:13:"timezone_type";i:3;s:8:"timezone";s:3:"UTC";}`},
+		{time.FixedZone("", 5*3600+1800), `O:12:"DateTimeZone":2:{s:13:"timezone_type";i:1;s:8:"timezone";s:6:"+05:30";}`},
+		{time.FixedZone("EDT", -4*3600), `O:12:"DateTimeZone":2:{s:13:"timezone_type";i:2;s:8:"timezone";s:3:"EDT";}`},
+	}
+	if cet, err := time.LoadLocation("CET"); err == nil {
+		// the same in winter and summer
+		testList = append(testList, struct {
+			value  *time.Location
+			expect string
+		}{cet, `O:12:"DateTimeZone":2:{s:13:"timezone_type";i:2;s:8:"timezone";s:3:"CET";}`})
+	}
+
+	for i, test := range testList {
+
+		data, err := Marshal(test.value)
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(data) != test.expect {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.expect, data)
+		}
+
+	}
+
+}
+
+func TestDateTime_Fields(t *testing.T) {
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	updated := time.Date(2024, 1, 2, 3, 4, 5, 6000, berlin)
+	v := testDates{Created: time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC), Updated: &updated, Zone: berlin}
+	data, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `a:4:{s:7:"created";O:8:"DateTime":3:{s:4:"date";s:26:"2023-12-31 23:59:59.000000";s:13:"timezone_type";i:3;s:8:"timezone";s:3:"UTC";}` +
+		`s:7:"updated";O:22:"Carbon\CarbonImmutable":3:{s:4:"date";s:26:"2024-01-02 03:04:05.000006";s:13:"timezone_type";i:3;s:8:"timezone";s:13:"Europe/Berlin";}` +
+		`s:7:"deleted";N;` +
+		`s:4:"zone";O:21:"Carbon\CarbonTimeZone":2:{s:13:"timezone_type";i:3;s:8:"timezone";s:13:"Europe/Berlin";}}`
+	if string(data) != expect {
+		t.Fatalf("expect:%s got %s", expect, data)
+	}
+
+	var decoded testDates
+	if err = Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Created.Equal(v.Created) || !decoded.Updated.Equal(updated) || decoded.Deleted != nil || decoded.Zone.String() != "Europe/Berlin" {
+		t.Fatalf("unexpected value %+v", decoded)
+	}
+
+	var zone *time.Location
+	if err = Unmarshal([]byte(`O:12:"DateTimeZone":2:{s:13:"timezone_type";i:1;s:8:"timezone";s:6:"+02:00";}`), &zone); err != nil {
+		t.Fatal(err)
+	}
+	if _, offset := time.Now().In(zone).Zone(); offset != 7200 {
+		t.Fatalf("expect offset 7200 got %d", offset)
+	}
+
+}
+
+func TestDateTime_Decode(t *testing.T) {
+
+	testList := []struct {
+		data   string
+		expect time.Time
+	}{
+		{`s:19:"2024-01-02 03:04:05";`, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{`s:25:"2024-01-02T03:04:05+01:00";`, time.Date(2024, 1, 2, 2, 4, 5, 0, time.UTC)},
+		{`N;`, time.Time{}},
+		{`O:13:"Carbon\Carbon":3:{s:4:"date";s:26:"2024-01-02 03:04:05.500000";s:13:"timezone_type";i:2;s:8:"timezone";s:3:"pst";}`,
+			time.Date(2024, 1, 2, 11, 4, 5, 500000000, time.UTC)},
+	}
+
+	for i, test := range testList {
+
+		var value time.Time
+		if err := Unmarshal([]byte(test.data), &value); err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if !value.Equal(test.expect) {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.expect, value)
+		}
+
+	}
+
+	var value time.Time
+	err := Unmarshal([]byte(`O:8:"DateTime":3:{s:4:"date";s:26:"2024-01-02 03:04:05.000000";s:13:"timezone_type";i:2;s:8:"timezone";s:3:"XYZ";}`), &value)
+	if _, ok := err.(*UnmarshalTypeError); !ok {
+		t.Fatalf("expect an UnmarshalTypeError got %v", err)
+	}
+
+}
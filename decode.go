@@ -1,11 +1,13 @@
 package phpserialize
 
 import (
+	"bytes"
 	"encoding"
 	"errors"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const phasePanicMsg = "php serialize decoder out of sync - data changing underfoot?"
@@ -31,6 +33,18 @@ type UnSerializer interface {
 	UnSerializePHP([]byte) error
 }
 
+// DataUnserializer is a class with __unserialize. UnserializePHPData gets
+// the body of the object as a serialized array.
+type DataUnserializer interface {
+	UnserializePHPData([]byte) error
+}
+
+// Wakeuper is a class with __wakeup, WakeupPHP is called after the
+// properties are decoded.
+type Wakeuper interface {
+	WakeupPHP() error
+}
+
 type UnmarshalTypeError struct {
 	Value  string
 	Type   reflect.Type
@@ -69,12 +83,15 @@ type decodeState struct {
 	data         []byte
 	off          int
 	parserState  int
+	beginDepth   int // parser depth before the last value tag was scanned
 	scan         scanner
 	errorContext struct {
 		Struct     reflect.Type
 		FieldStack []string
 	}
 	savedError error
+
+	onUnknownClass func(class string) (reflect.Type, error)
 }
 
 func (d *decodeState) readIndex() int {
@@ -135,6 +152,11 @@ func (d *decodeState) skip() {
 
 	s, data, i := &d.scan, d.data, d.off
 	depth := s.parserDepth()
+	switch d.parserState {
+	case scanBeginScalarValue, scanBeginArray, scanBeginObject, scanBeginCustom:
+		// the tag of the value is already consumed, skip the rest of it
+		depth = d.beginDepth
+	}
 	for {
 		op := s.step(data[i])
 		i++
@@ -150,6 +172,8 @@ func (d *decodeState) skip() {
 func (d *decodeState) scanNext() int {
 
 	if d.off < len(d.data) {
+		d.beginDepth = d.scan.parserDepth()
+		d.scan.bytes = int64(d.off)
 		d.parserState = d.scan.step(d.data[d.off])
 		d.off++
 	} else {
@@ -177,7 +201,14 @@ func (d *decodeState) scanUntil(parserState int) {
 
 func (d *decodeState) dataBetween(s1, s2 int) []byte {
 
-	d.scanUntil(s1)
+	for d.scanNext() != s1 {
+		switch d.parserState {
+		case s2, scanEnd, scanError:
+			// nothing in between, e.g. an empty string
+			offset := d.readIndex()
+			return d.data[offset:offset]
+		}
+	}
 	offset1 := d.readIndex()
 	d.scanUntil(s2)
 	offset2 := d.readIndex()
@@ -189,20 +220,28 @@ func (d *decodeState) value(v reflect.Value) error {
 
 	d.scanNext()
 
-	u, ut, pv := indirect(v)
+	decodingNull := d.parserState == scanBeginScalarValue && phpValueType(d.data[d.readIndex()]) == phpTypeNull
+	u, ut, pv := indirect(v, decodingNull)
 	if u != nil {
 		start := d.readIndex()
 		d.skip()
-		return u.UnmarshalPHP(d.data[start:d.readIndex()])
+		return u.UnmarshalPHP(d.data[start:d.off])
+	}
+
+	if t, ok := ut.(*time.Time); ok {
+		return d.dateTime(t)
 	}
 
 	if ut != nil {
 		start := d.readIndex()
 		d.skip()
-		return ut.UnmarshalText(d.data[start:d.readIndex()])
+		return ut.UnmarshalText(d.data[start:d.off])
 	}
 
 	v = pv
+	if v.Type() == locationPtrType || v.Type() == locationType {
+		return d.dateTimeZone(v)
+	}
 
 	switch d.parserState {
 	case scanBeginScalarValue:
@@ -271,7 +310,7 @@ func (d *decodeState) scalarValueStore(tag phpValueType, data []byte, v reflect.
 
 	case phpTypeBoolean:
 
-		value := data[0] == '1'
+		value := len(data) == 1 && data[0] == '1'
 		switch v.Kind() {
 		case reflect.Bool:
 			v.SetBool(value)
@@ -532,6 +571,12 @@ func (d *decodeState) array(v reflect.Value) error {
 
 func (d *decodeState) object(v reflect.Value) error {
 
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(DataUnserializer); ok {
+			return d.objectData(u, v.Type())
+		}
+	}
+
 	switch v.Kind() {
 	case reflect.Map, reflect.Struct:
 		break
@@ -539,7 +584,18 @@ func (d *decodeState) object(v reflect.Value) error {
 	case reflect.Interface:
 
 		if v.NumMethod() == 0 {
-			v.Set(reflect.ValueOf(d.arrayInterface()))
+			v.Set(reflect.ValueOf(d.objectInterface()))
+			return nil
+		}
+		if v.Type() == errorType {
+			// an error holds a *PHPException
+			start := d.readIndex()
+			d.skip()
+			e := &PHPException{}
+			if err := e.UnmarshalPHP(d.data[start:d.off]); err != nil {
+				d.saveError(err)
+			}
+			v.Set(reflect.ValueOf(e))
 			return nil
 		}
 		fallthrough
@@ -574,15 +630,44 @@ func (d *decodeState) object(v reflect.Value) error {
 	if err != nil {
 		return err
 	}
+	if v.CanAddr() {
+		if w, ok := v.Addr().Interface().(Wakeuper); ok {
+			return w.WakeupPHP()
+		}
+	}
 	return nil
 
 }
 
+// objectData passes the body of an object to UnserializePHPData as an
+// array.
+func (d *decodeState) objectData(u DataUnserializer, t reflect.Type) error {
+
+	start := d.readIndex()
+	d.skip()
+	data := d.data[start:d.off]
+
+	n, off := readLength(data, 2)
+	className := string(data[off+1 : off+1+n])
+	if c, ok := u.(PHPClass); ok && c.GetPHPClassName() != className {
+		d.saveError(&UnmarshalTypeError{Value: "class " + className, Type: t, Offset: int64(start)})
+		return nil
+	}
+
+	array := append([]byte{byte(phpTypeArray), phpSeparator}, data[off+n+3:]...)
+	return u.UnserializePHPData(array)
+
+}
+
 var unSerializerType = reflect.TypeOf((*UnSerializer)(nil)).Elem()
 
 func (d *decodeState) custom(v reflect.Value) error {
 
 	t := v.Type()
+	if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		v.Set(reflect.ValueOf(d.customInterface()))
+		return nil
+	}
 	if !reflect.PtrTo(t).Implements(unSerializerType) {
 		d.saveError(&UnmarshalTypeError{Value: "custom", Type: t, Offset: int64(d.off)})
 		d.skip()
@@ -643,7 +728,9 @@ func (d *decodeState) mapKv(kvLength int, className string, v reflect.Value) err
 	default:
 		if !reflect.PtrTo(t.Key()).Implements(textUnmarshalerType) {
 			d.saveError(&UnmarshalTypeError{Value: "map", Type: t, Offset: int64(d.off)})
-			d.skip()
+			for index := 0; index < kvLength*2; index++ {
+				d.skip()
+			}
 			return nil
 		}
 	}
@@ -672,7 +759,7 @@ func (d *decodeState) mapKv(kvLength int, className string, v reflect.Value) err
 		if className != "" &&
 			mapKey.Kind() == reflect.String {
 			key := mapKey.String()
-			if key[0] == 0 {
+			if len(key) > len(className)+1 && key[0] == 0 {
 				if key[1:len(className)+1] == className {
 					key = key[len(className)+2:]
 					mapKey.SetString(key)
@@ -683,6 +770,9 @@ func (d *decodeState) mapKv(kvLength int, className string, v reflect.Value) err
 		elemType := t.Elem()
 		if elemType.Kind() == reflect.Interface {
 			mapElem = reflect.ValueOf(d.valueInterface())
+			if !mapElem.IsValid() {
+				mapElem = reflect.Zero(elemType)
+			}
 		} else {
 
 			if !mapElem.IsValid() {
@@ -709,6 +799,8 @@ func (d *decodeState) mapKv(kvLength int, className string, v reflect.Value) err
 func (d *decodeState) structKv(kvLength int, className string, v reflect.Value) error {
 
 	fields := cachedTypeFields(v.Type())
+	// the rank of the property each field was set from, see propertyRank
+	var ranks map[int]int
 
 	for index := 0; index < kvLength; index++ {
 
@@ -718,15 +810,29 @@ func (d *decodeState) structKv(kvLength int, className string, v reflect.Value)
 			return err
 		}
 
-		if key[0] == 0 && className != "" {
-			if key[1:len(className)+1] == className {
-				key = key[len(className)+2:]
-			}
-		}
+		// protected and private properties are matched by their name
+		key, class := unmangleProperty(key)
 
 		if i, ok := fields.nameIndex[key]; ok {
 
-			err = d.value(v.Field(i))
+			rank := propertyRank(class, className)
+			if set, ok := ranks[i]; ok && set >= rank {
+				d.skip()
+				continue
+			}
+			fv, ok := fieldByIndex(v, fields.list[i].index)
+			if !ok {
+				d.skip()
+				continue
+			}
+			if ranks == nil {
+				ranks = make(map[int]int)
+			}
+			if _, ok := ranks[i]; ok {
+				fv.Set(reflect.Zero(fv.Type()))
+			}
+			ranks[i] = rank
+			err = d.value(fv)
 			if err != nil {
 				return err
 			}
@@ -741,13 +847,49 @@ func (d *decodeState) structKv(kvLength int, className string, v reflect.Value)
 
 }
 
+// propertyRank orders the properties of the same name in an object of
+// className: public ones first, then protected and the class's own private
+// ones, then private ones of parent classes. A field is set from the
+// property of the highest rank, the first one among equals.
+func propertyRank(class, className string) int {
+
+	switch class {
+	case "":
+		return 2
+	case "*", className:
+		return 1
+	}
+	return 0
+
+}
+
+// fieldByIndex returns the field of v at index, allocating the embedded
+// pointers on the way.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, true
+
+}
+
 func (d *decodeState) arrayInterface() (val interface{}) {
 
 	d.scanUntil(scanEndKeyValueLength)
 	arrayLength := d.scan.lastLength() / 2
 
-	d.scanNext() //skip {
-	defer d.scanUntil(scanEndArray)
+	d.scanNext()       //skip {
+	defer d.scanNext() //skip }
 
 	if arrayLength > 0 {
 
@@ -761,8 +903,8 @@ func (d *decodeState) arrayInterface() (val interface{}) {
 
 		//check all the keys in arrayMap
 		for k := range arrayMap {
-			//key is not int, just return arrayMap
-			if _, ok := k.(int64); !ok {
+			//key is not a list index, just return arrayMap
+			if i, ok := k.(int64); !ok || i < 0 || i >= int64(arrayLength) {
 				val = arrayMap
 				return
 			}
@@ -783,16 +925,55 @@ func (d *decodeState) arrayInterface() (val interface{}) {
 
 }
 
+// arrayEach reads an array or object and calls f with every key, f has to
+// consume the value that follows the key.
+func (d *decodeState) arrayEach(f func(key interface{}) error) error {
+
+	switch d.scanNext() {
+	case scanBeginArray, scanBeginObject:
+	case scanBeginScalarValue:
+		if phpValueType(d.data[d.readIndex()]) == phpTypeNull {
+			d.scanUntil(scanEndScalarValue)
+			return nil
+		}
+		fallthrough
+	default:
+		return &UnmarshalTypeError{Value: "non-array value", Type: reflect.TypeOf(map[interface{}]interface{}{}), Offset: int64(d.off)}
+	}
+
+	d.scanUntil(scanEndKeyValueLength)
+	arrayLength := d.scan.lastLength() / 2
+
+	d.scanNext() //skip {
+	for index := 0; index < arrayLength; index++ {
+		if err := f(d.valueInterface()); err != nil {
+			return err
+		}
+	}
+	d.scanNext() //skip }
+
+	return nil
+
+}
+
 func (d *decodeState) valueInterface() (val interface{}) {
 
 	d.scanNext()
 
 	switch d.parserState {
 
-	case scanBeginArray, scanBeginObject:
+	case scanBeginArray:
 
 		val = d.arrayInterface()
 
+	case scanBeginObject:
+
+		val = d.objectInterface()
+
+	case scanBeginCustom:
+
+		val = d.customInterface()
+
 	case scanBeginScalarValue:
 
 		tag := phpValueType(d.data[d.readIndex()])
@@ -813,6 +994,70 @@ func (d *decodeState) valueInterface() (val interface{}) {
 
 }
 
+// objectInterface decodes objects of the built-in classes into their Go
+// types, stdClass objects into maps and others into the type OnUnknownClass
+// returns or IncompleteObject, which keeps their class.
+func (d *decodeState) objectInterface() interface{} {
+
+	if val, ok := d.classInterface(); ok {
+		return val
+	}
+	if bytes.HasPrefix(d.data[d.readIndex():], []byte(`O:8:"stdClass":`)) {
+		return d.arrayInterface()
+	}
+	return d.incompleteInterface()
+
+}
+
+// customInterface decodes custom values of the built-in classes into their
+// Go types, others into the type OnUnknownClass returns or IncompleteObject.
+func (d *decodeState) customInterface() interface{} {
+
+	if val, ok := d.classInterface(); ok {
+		return val
+	}
+	return d.incompleteInterface()
+
+}
+
+func (d *decodeState) incompleteInterface() interface{} {
+
+	start := d.readIndex()
+	d.skip()
+	var o IncompleteObject
+	if err := o.UnmarshalPHP(d.data[start:d.off]); err != nil {
+		d.saveError(err)
+	}
+	return o
+
+}
+
+func (d *decodeState) classInterface() (interface{}, bool) {
+
+	start := d.readIndex()
+	n, off := 0, start+2
+	for ; off < len(d.data) && d.data[off] >= '0' && d.data[off] <= '9'; off++ {
+		n = n*10 + int(d.data[off]-'0')
+	}
+	off += 2 //skip :"
+	if off+n > len(d.data) {
+		return nil, false
+	}
+	class := string(d.data[off : off+n])
+	newValue, ok := builtinClasses[class]
+	if !ok {
+		return d.unknownClass(class, start)
+	}
+
+	d.skip()
+	u := newValue()
+	if err := u.UnmarshalPHP(d.data[start:d.off]); err != nil {
+		d.saveError(err)
+	}
+	return reflect.ValueOf(u).Elem().Interface(), true
+
+}
+
 func (d *decodeState) scalarInterface(tag phpValueType, data []byte) interface{} {
 
 	switch tag {
@@ -822,7 +1067,7 @@ func (d *decodeState) scalarInterface(tag phpValueType, data []byte) interface{}
 
 	case phpTypeBoolean:
 
-		return data[0] == '1'
+		return len(data) == 1 && data[0] == '1'
 
 	case phpTypeInteger:
 
@@ -853,7 +1098,9 @@ func (d *decodeState) scalarInterface(tag phpValueType, data []byte) interface{}
 
 }
 
-func indirect(v reflect.Value) (Unmarshaler, encoding.TextUnmarshaler, reflect.Value) {
+// indirect walks down v allocating pointers as needed. When decodingNull is
+// set it stops at the last settable pointer so it can be set to nil.
+func indirect(v reflect.Value, decodingNull bool) (Unmarshaler, encoding.TextUnmarshaler, reflect.Value) {
 
 	v0 := v
 	haveAddr := false
@@ -878,7 +1125,7 @@ func indirect(v reflect.Value) (Unmarshaler, encoding.TextUnmarshaler, reflect.V
 			break
 		}
 
-		if v.Elem().Kind() != reflect.Ptr && v.CanSet() {
+		if decodingNull && v.CanSet() {
 			break
 		}
 
@@ -887,6 +1134,11 @@ func indirect(v reflect.Value) (Unmarshaler, encoding.TextUnmarshaler, reflect.V
 			break
 		}
 
+		// locations are replaced, not decoded into
+		if v.Type() == locationPtrType && v.CanSet() {
+			break
+		}
+
 		if v.IsNil() {
 			v.Set(reflect.New(v.Type().Elem()))
 		}
@@ -1,6 +1,7 @@
 package phpserialize
 
 import (
+	"io"
 	"log"
 	"strings"
 	"testing"
@@ -56,6 +57,28 @@ func TestDecoder_Decode(t *testing.T) {
 
 }
 
+func TestDecoder_Whitespace(t *testing.T) {
+
+	dec := NewDecoder(strings.NewReader("i:1;\ni:2;i:3;\r\n\t \n"))
+
+	var values []int
+	for {
+		var n int
+		err := dec.Decode(&n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, n)
+	}
+	if len(values) != 3 || values[0] != 1 || values[2] != 3 {
+		t.Fatalf("expect [1 2 3] got %v", values)
+	}
+
+}
+
 func TestUnmarshal(t *testing.T) {
 
 	var err error
@@ -231,3 +254,241 @@ func TestUnmarshal(t *testing.T) {
 	t.Logf("%+v", tkm)
 
 }
+
+func TestUnmarshal_SkipMismatch(t *testing.T) {
+
+	testList := []string{
+		"a:2:{s:1:\"a\";O:3:\"Foo\":1:{s:1:\"x\";a:1:{i:0;i:1;}}s:1:\"b\";i:3;}",
+		"a:2:{s:1:\"a\";C:3:\"Foo\":1:{x}s:1:\"b\";i:3;}",
+	}
+
+	for i, test := range testList {
+
+		var s struct {
+			A int `php:"a"`
+			B int `php:"b"`
+		}
+		err := Unmarshal([]byte(test), &s)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Fatalf("Test fail at index %d, expect an UnmarshalTypeError got %v", i, err)
+		}
+		if s.B != 3 {
+			t.Fatalf("Test fail at index %d, expect:3 got %d", i, s.B)
+		}
+
+	}
+
+}
+
+func TestUnmarshal_RawMessage(t *testing.T) {
+
+	testList := []string{
+		"i:5;",
+		"s:2:\"xy\";",
+		"a:1:{i:0;s:2:\"xy\";}",
+		"O:3:\"Foo\":1:{s:1:\"a\";N;}",
+		"C:3:\"Foo\":1:{x}",
+	}
+
+	for i, test := range testList {
+
+		var s struct {
+			A RawMessage `php:"a"`
+			B int        `php:"b"`
+		}
+		err := Unmarshal([]byte("a:2:{s:1:\"a\";"+test+"s:1:\"b\";i:3;}"), &s)
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(s.A) != test || s.B != 3 {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test, s.A)
+		}
+
+	}
+
+}
+
+func TestUnmarshal_EmptyString(t *testing.T) {
+
+	var s struct {
+		A string         `php:"a"`
+		B map[string]int `php:"b"`
+		C int            `php:"c"`
+	}
+	err := Unmarshal([]byte("a:3:{s:1:\"a\";s:0:\"\";s:1:\"b\";a:1:{s:0:\"\";i:1;}s:1:\"c\";i:3;}"), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.A != "" || s.B[""] != 1 || s.C != 3 {
+		t.Fatalf("unexpected value %+v", s)
+	}
+
+}
+
+func TestUnmarshal_EmptyBool(t *testing.T) {
+
+	for i, test := range []string{"b:;", "a:1:{i:0;b:;}", "b:10;"} {
+
+		var v interface{}
+		if err := Unmarshal([]byte(test), &v); err == nil {
+			t.Fatalf("Test fail at index %d, expect an error for %s", i, test)
+		}
+		var b bool
+		if err := Unmarshal([]byte(test), &b); err == nil {
+			t.Fatalf("Test fail at index %d, expect an error for %s", i, test)
+		}
+
+	}
+
+}
+
+func TestUnmarshal_MapKeyType(t *testing.T) {
+
+	var s struct {
+		A map[float64]string `php:"a"`
+		B int                `php:"b"`
+	}
+	err := Unmarshal([]byte("a:2:{s:1:\"a\";a:2:{i:0;s:1:\"x\";i:1;a:1:{i:0;i:1;}}s:1:\"b\";i:3;}"), &s)
+	if _, ok := err.(*UnmarshalTypeError); !ok {
+		t.Fatalf("expect an UnmarshalTypeError got %v", err)
+	}
+	if s.B != 3 {
+		t.Fatalf("expect:3 got %d", s.B)
+	}
+
+}
+
+func TestUnmarshal_ShortPropertyName(t *testing.T) {
+
+	data := []byte("O:3:\"Foo\":3:{s:0:\"\";i:1;s:2:\"\x00a\";i:2;s:1:\"x\";i:3;}")
+
+	var s struct {
+		X int `php:"x"`
+	}
+	if err := Unmarshal(data, &s); err != nil || s.X != 3 {
+		t.Fatalf("unexpected value %+v %v", s, err)
+	}
+
+	var m map[string]int
+	if err := Unmarshal(data, &m); err != nil || len(m) != 3 || m["x"] != 3 {
+		t.Fatalf("unexpected value %+v %v", m, err)
+	}
+
+}
+
+func TestUnmarshal_PropertyCollision(t *testing.T) {
+
+	testList := []struct {
+		data   string
+		expect int
+	}{
+		// a parent's private property does not override the child's
+		{"O:5:\"Child\":2:{s:8:\"\x00Child\x00x\";i:1;s:9:\"\x00Parent\x00x\";i:2;}", 1},
+		{"O:5:\"Child\":2:{s:9:\"\x00Parent\x00x\";i:2;s:8:\"\x00Child\x00x\";i:1;}", 1},
+		{"O:5:\"Child\":2:{s:4:\"\x00*\x00x\";i:1;s:9:\"\x00Parent\x00x\";i:2;}", 1},
+		// public properties come first
+		{"O:5:\"Child\":2:{s:1:\"x\";i:3;s:8:\"\x00Child\x00x\";i:1;}", 3},
+		{"O:5:\"Child\":2:{s:8:\"\x00Child\x00x\";i:1;s:1:\"x\";i:3;}", 3},
+		{"O:5:\"Child\":1:{s:9:\"\x00Parent\x00x\";i:2;}", 2},
+	}
+
+	for i, test := range testList {
+
+		var s struct {
+			X int `php:"x"`
+		}
+		if err := Unmarshal([]byte(test.data), &s); err != nil || s.X != test.expect {
+			t.Fatalf("Test fail at index %d, expect:%d got %d %v", i, test.expect, s.X, err)
+		}
+
+	}
+
+}
+
+func TestUnmarshal_NullInterface(t *testing.T) {
+
+	var m map[string]interface{}
+	if err := Unmarshal([]byte("a:2:{s:1:\"n\";N;s:1:\"a\";i:1;}"), &m); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := m["n"]; !ok || v != nil || m["a"] != int64(1) {
+		t.Fatalf("unexpected value %+v", m)
+	}
+
+}
+
+func TestUnmarshal_ObjectInterface(t *testing.T) {
+
+	var v interface{}
+	err := Unmarshal([]byte("a:2:{i:0;O:8:\"stdClass\":1:{s:1:\"a\";i:1;}i:1;i:2;}"), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, ok := v.([]interface{})
+	if !ok || len(list) != 2 || list[1] != int64(2) {
+		t.Fatalf("unexpected value %+v", v)
+	}
+	if m, ok := list[0].(map[interface{}]interface{}); !ok || m["a"] != int64(1) {
+		t.Fatalf("unexpected value %+v", list[0])
+	}
+
+}
+
+func TestUnmarshal_Nested(t *testing.T) {
+
+	var v interface{}
+	err := Unmarshal([]byte("a:3:{s:0:\"\";i:5;s:1:\"o\";O:8:\"stdClass\":1:{s:1:\"a\";N;}s:1:\"b\";a:1:{i:3;s:0:\"\";}}"), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%+v", v)
+
+	m := v.(map[interface{}]interface{})
+	if m[""] != int64(5) || m["b"].(map[interface{}]interface{})[int64(3)] != "" {
+		t.Fatalf("unexpected value %+v", v)
+	}
+
+	var s struct {
+		A RawMessage             `php:"a"`
+		B map[string]interface{} `php:"b"`
+	}
+	err = Unmarshal([]byte("a:3:{s:1:\"x\";a:0:{}s:1:\"a\";a:1:{i:0;s:2:\"xy\";}s:1:\"b\";a:1:{s:1:\"n\";N;}}"), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%+v", s)
+
+	if string(s.A) != "a:1:{i:0;s:2:\"xy\";}" {
+		t.Fatalf("unexpected raw message %s", s.A)
+	}
+	if _, ok := s.B["n"]; !ok {
+		t.Fatalf("null value missing in %+v", s.B)
+	}
+
+}
+
+func TestUnmarshal_Pointer(t *testing.T) {
+
+	var s struct {
+		A *string `php:"a"`
+		B *int    `php:"b"`
+		C **int   `php:"c"`
+	}
+	err := Unmarshal([]byte("a:3:{s:1:\"a\";s:1:\"x\";s:1:\"b\";i:2;s:1:\"c\";i:3;}"), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.A == nil || *s.A != "x" || s.B == nil || *s.B != 2 || s.C == nil || **s.C != 3 {
+		t.Fatalf("unexpected value %+v", s)
+	}
+
+	if err = Unmarshal([]byte("a:1:{s:1:\"b\";N;}"), &s); err != nil || s.B != nil {
+		t.Fatalf("expect a nil pointer got %v %v", s.B, err)
+	}
+
+	var p *int
+	if err = Unmarshal([]byte("i:4;"), &p); err != nil || p == nil || *p != 4 {
+		t.Fatalf("unexpected value %v %v", p, err)
+	}
+
+}
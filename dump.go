@@ -0,0 +1,413 @@
+package phpserialize
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Dump writes the serialized value in data the way var_dump prints it after
+// unserialize. Invalid data is printed up to the error, followed by a marker
+// line, and the syntax error is returned. Custom serialized objects are
+// printed with their raw payload under a [serialized] key.
+func Dump(w io.Writer, data []byte) error {
+	return dump(w, data, false)
+}
+
+// PrintR writes the serialized value in data the way print_r prints it, see
+// Dump.
+func PrintR(w io.Writer, data []byte) error {
+	return dump(w, data, true)
+}
+
+// Sprint returns v in var_dump format, as Marshal encodes it.
+func Sprint(v interface{}) string {
+	return sprint(v, false)
+}
+
+// SprintR returns v in print_r format, as Marshal encodes it.
+func SprintR(v interface{}) string {
+	return sprint(v, true)
+}
+
+func sprint(v interface{}, printR bool) string {
+
+	data, err := Marshal(v)
+	if err != nil {
+		return dumpMarker(err)
+	}
+
+	var b strings.Builder
+	dump(&b, data, printR)
+	return b.String()
+
+}
+
+func dump(w io.Writer, data []byte, printR bool) error {
+
+	d := dumper{printR: printR}
+	err := d.run(data)
+	if err != nil {
+		d.marker(err)
+	}
+	if _, werr := w.Write(d.out); werr != nil {
+		return werr
+	}
+	return err
+
+}
+
+func dumpMarker(err error) string {
+	return "*** " + strings.TrimSpace(err.Error()) + " ***\n"
+}
+
+type dumpFrame struct {
+	object    bool
+	expectKey bool
+	key       string
+	intKey    bool
+	indent    int // print_r indentation of the container
+}
+
+// dumper prints values as the scanner completes them, so everything before
+// a syntax error is already written when the scanner fails.
+type dumper struct {
+	out     []byte
+	printR  bool
+	frames  []dumpFrame
+	objects int
+	start   int // offset of the value being scanned
+	custom  bool
+}
+
+func (d *dumper) run(data []byte) error {
+
+	scan := &scanner{}
+	scan.reset()
+	for i, c := range data {
+		state := scan.step(c)
+		switch state {
+		case scanError:
+			return scan.err
+		case scanBeginScalarValue, scanBeginArray, scanBeginObject:
+			d.start = i
+		case scanBeginCustom:
+			d.start = i
+			d.custom = true
+		case scanEndKeyValueLength:
+			d.open(data, i)
+		case scanEndScalarValue, scanEndArray, scanEndObject, scanEndCustom, scanEnd:
+			//skip the closing quote of strings
+			if c == phpTerminator {
+				d.scalar(data[d.start : i+1])
+			} else if c == phpRightBraces && d.custom {
+				d.customObject(data[d.start : i+1])
+			} else if c == phpRightBraces {
+				d.close()
+			}
+			if state == scanEnd {
+				return nil
+			}
+		}
+		scan.bytes++
+	}
+	if scan.eof() == scanError {
+		return scan.err
+	}
+	return nil
+
+}
+
+func (d *dumper) indent(n int) {
+	for i := 0; i < n; i++ {
+		d.out = append(d.out, ' ')
+	}
+}
+
+// element writes the key of the value that follows, or records the value as
+// the next key when the container expects one. It reports whether the value
+// has to be printed.
+func (d *dumper) element(key string, intKey bool) bool {
+
+	if len(d.frames) == 0 {
+		return true
+	}
+	f := &d.frames[len(d.frames)-1]
+	if f.expectKey {
+		f.expectKey = false
+		f.key, f.intKey = key, intKey
+		return false
+	}
+	f.expectKey = true
+
+	if d.printR {
+		d.indent(f.indent + 4)
+		d.out = append(d.out, '[')
+		if name, class := unmangleProperty(f.key); f.object && class != "" {
+			d.out = append(d.out, name...)
+			if class == "*" {
+				d.out = append(d.out, ":protected"...)
+			} else {
+				d.out = append(d.out, ':')
+				d.out = append(d.out, class...)
+				d.out = append(d.out, ":private"...)
+			}
+		} else {
+			d.out = append(d.out, f.key...)
+		}
+		d.out = append(d.out, "] => "...)
+		return true
+	}
+
+	d.indent(2 * len(d.frames))
+	d.out = append(d.out, '[')
+	// objects keep integer keys as property names
+	if f.intKey && !f.object {
+		d.out = append(d.out, f.key...)
+	} else if name, class := unmangleProperty(f.key); f.object && class == "*" {
+		d.out = append(d.out, '"')
+		d.out = append(d.out, name...)
+		d.out = append(d.out, "\":protected"...)
+	} else if f.object && class != "" {
+		d.out = append(d.out, '"')
+		d.out = append(d.out, name...)
+		d.out = append(d.out, "\":\""...)
+		d.out = append(d.out, class...)
+		d.out = append(d.out, "\":private"...)
+	} else {
+		d.out = append(d.out, '"')
+		d.out = append(d.out, f.key...)
+		d.out = append(d.out, '"')
+	}
+	d.out = append(d.out, "]=>\n"...)
+	d.indent(2 * len(d.frames))
+	return true
+
+}
+
+// end finishes the line of a printed value.
+func (d *dumper) end() {
+
+	if !d.printR {
+		d.out = append(d.out, '\n')
+	} else if len(d.frames) > 0 {
+		d.out = append(d.out, '\n')
+	}
+
+}
+
+func (d *dumper) scalar(v []byte) {
+
+	var key string
+	intKey := false
+	switch phpValueType(v[0]) {
+	case phpTypeInteger:
+		key, intKey = string(v[2:len(v)-1]), true
+	case phpTypeString:
+		n, start := readLength(v, 2)
+		key = string(v[start+1 : start+1+n])
+		if len(d.frames) > 0 && !d.frames[len(d.frames)-1].object {
+			_, intKey = phpIntKey(key)
+		}
+	}
+	if !d.element(key, intKey) {
+		return
+	}
+
+	switch phpValueType(v[0]) {
+	case phpTypeNull:
+		if !d.printR {
+			d.out = append(d.out, "NULL"...)
+		}
+	case phpTypeBoolean:
+		switch {
+		case !d.printR:
+			d.out = append(d.out, "bool("...)
+			if v[2] == '1' {
+				d.out = append(d.out, "true)"...)
+			} else {
+				d.out = append(d.out, "false)"...)
+			}
+		case v[2] == '1':
+			d.out = append(d.out, '1')
+		}
+	case phpTypeInteger:
+		if d.printR {
+			d.out = append(d.out, key...)
+		} else {
+			d.out = append(d.out, "int("...)
+			d.out = append(d.out, key...)
+			d.out = append(d.out, ')')
+		}
+	case phpTypeFloat:
+		s := string(v[2 : len(v)-1])
+		if !d.printR {
+			d.out = append(d.out, "float("...)
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil || s == "INF" || s == "-INF" || s == "NAN" {
+			d.out = appendDumpFloat(d.out, s, f, d.printR)
+		} else {
+			d.out = append(d.out, s...)
+		}
+		if !d.printR {
+			d.out = append(d.out, ')')
+		}
+	case phpTypeString:
+		if d.printR {
+			d.out = append(d.out, key...)
+		} else {
+			d.out = append(d.out, "string("...)
+			d.out = strconv.AppendInt(d.out, int64(len(key)), 10)
+			d.out = append(d.out, ") \""...)
+			d.out = append(d.out, key...)
+			d.out = append(d.out, '"')
+		}
+	}
+	d.end()
+
+}
+
+// appendDumpFloat formats like var_dump with serialize_precision -1, or like
+// print_r with precision 14.
+func appendDumpFloat(b []byte, s string, f float64, printR bool) []byte {
+
+	switch s {
+	case "INF":
+		return append(b, "INF"...)
+	case "-INF":
+		return append(b, "-INF"...)
+	case "NAN":
+		return append(b, "NAN"...)
+	}
+	if !printR {
+		return appendPHPFloat(b, f, 17)
+	}
+	return appendPrecisionFloat(b, f)
+
+}
+
+func (d *dumper) open(data []byte, off int) {
+
+	v := data[d.start:off]
+	object := phpValueType(v[0]) == phpTypeObject
+	count := string(v[bytes.LastIndexByte(v, phpSeparator)+1:])
+	var class string
+	if object {
+		n, start := readLength(v, 2)
+		class = string(v[start+1 : start+1+n])
+	}
+
+	d.element("", false)
+	indent := 0
+	if n := len(d.frames); n > 0 {
+		indent = d.frames[n-1].indent + 8
+	}
+
+	switch {
+	case d.printR && object:
+		d.out = append(d.out, class...)
+		d.out = append(d.out, " Object\n"...)
+	case d.printR:
+		d.out = append(d.out, "Array\n"...)
+	case object:
+		d.objects++
+		d.out = append(d.out, "object("...)
+		d.out = append(d.out, class...)
+		d.out = append(d.out, ")#"...)
+		d.out = strconv.AppendInt(d.out, int64(d.objects), 10)
+		d.out = append(d.out, " ("...)
+		d.out = append(d.out, count...)
+		d.out = append(d.out, ") {\n"...)
+	default:
+		d.out = append(d.out, "array("...)
+		d.out = append(d.out, count...)
+		d.out = append(d.out, ") {\n"...)
+	}
+	if d.printR {
+		d.indent(indent)
+		d.out = append(d.out, "(\n"...)
+	}
+
+	d.frames = append(d.frames, dumpFrame{object: object, expectKey: true, indent: indent})
+
+}
+
+func (d *dumper) close() {
+
+	f := d.frames[len(d.frames)-1]
+	d.frames = d.frames[:len(d.frames)-1]
+	if d.printR {
+		d.indent(f.indent)
+		d.out = append(d.out, ")\n"...)
+	} else {
+		d.indent(2 * len(d.frames))
+		d.out = append(d.out, '}')
+	}
+	d.end()
+
+}
+
+func (d *dumper) customObject(v []byte) {
+
+	d.custom = false
+	n, start := readLength(v, 2)
+	class := string(v[start+1 : start+1+n])
+	n, start = readLength(v, start+n+3)
+	payload := v[start+1 : start+1+n]
+
+	d.element("", false)
+	indent := 0
+	if n := len(d.frames); n > 0 {
+		indent = d.frames[n-1].indent + 8
+	}
+
+	if d.printR {
+		d.out = append(d.out, class...)
+		d.out = append(d.out, " Object\n"...)
+		d.indent(indent)
+		d.out = append(d.out, "(\n"...)
+		d.indent(indent + 4)
+		d.out = append(d.out, "[serialized] => "...)
+		d.out = append(d.out, payload...)
+		d.out = append(d.out, '\n')
+		d.indent(indent)
+		d.out = append(d.out, ")\n"...)
+	} else {
+		d.objects++
+		d.out = append(d.out, "object("...)
+		d.out = append(d.out, class...)
+		d.out = append(d.out, ")#"...)
+		d.out = strconv.AppendInt(d.out, int64(d.objects), 10)
+		d.out = append(d.out, " (1) {\n"...)
+		d.indent(2*len(d.frames) + 2)
+		d.out = append(d.out, "[serialized]=>\n"...)
+		d.indent(2*len(d.frames) + 2)
+		d.out = append(d.out, "string("...)
+		d.out = strconv.AppendInt(d.out, int64(len(payload)), 10)
+		d.out = append(d.out, ") \""...)
+		d.out = append(d.out, payload...)
+		d.out = append(d.out, "\"\n"...)
+		d.indent(2 * len(d.frames))
+		d.out = append(d.out, '}')
+	}
+	d.end()
+
+}
+
+// marker writes the syntax error where the value would have continued,
+// after the key of an unfinished element.
+func (d *dumper) marker(err error) {
+
+	if n := len(d.frames); n > 0 {
+		if !d.frames[n-1].expectKey {
+			d.element("", false)
+		} else if d.printR {
+			d.indent(d.frames[n-1].indent + 4)
+		} else {
+			d.indent(2 * n)
+		}
+	}
+	d.out = append(d.out, dumpMarker(err)...)
+
+}
@@ -0,0 +1,122 @@
+package phpserialize
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+
+	object := `O:3:"Foo":3:{s:3:"pub";b:1;s:6:"` + "\x00*\x00" + `pro";N;s:8:"` + "\x00Foo\x00" + `pri";s:2:"hi";}`
+
+	testList := []struct {
+		data   string
+		dump   string
+		printR string
+	}{
+		{`N;`, "NULL\n", ""},
+		{`b:0;`, "bool(false)\n", ""},
+		{`i:-3;`, "int(-3)\n", "-3"},
+		{`d:0.30000000000000004;`, "float(0.30000000000000004)\n", "0.3"},
+		{`d:1;`, "float(1)\n", "1"},
+		{`d:-INF;`, "float(-INF)\n", "-INF"},
+		{`s:5:"a;b}c";`, "string(5) \"a;b}c\"\n", "a;b}c"},
+		{`a:0:{}`, "array(0) {\n}\n", "Array\n(\n)\n"},
+		{`a:2:{s:1:"a";i:1;s:1:"5";a:1:{i:0;d:0.1;}}`,
+			"array(2) {\n  [\"a\"]=>\n  int(1)\n  [5]=>\n  array(1) {\n    [0]=>\n    float(0.1)\n  }\n}\n",
+			"Array\n(\n    [a] => 1\n    [5] => Array\n        (\n            [0] => 0.1\n        )\n\n)\n"},
+		{object,
+			"object(Foo)#1 (3) {\n  [\"pub\"]=>\n  bool(true)\n  [\"pro\":protected]=>\n  NULL\n  [\"pri\":\"Foo\":private]=>\n  string(2) \"hi\"\n}\n",
+			"Foo Object\n(\n    [pub] => 1\n    [pro:protected] => \n    [pri:Foo:private] => hi\n)\n"},
+		{`a:2:{i:0;O:8:"stdClass":0:{}i:1;O:8:"stdClass":1:{i:0;i:1;}}`,
+			"array(2) {\n  [0]=>\n  object(stdClass)#1 (0) {\n  }\n  [1]=>\n  object(stdClass)#2 (1) {\n    [\"0\"]=>\n    int(1)\n  }\n}\n",
+			"Array\n(\n    [0] => stdClass Object\n        (\n        )\n\n    [1] => stdClass Object\n        (\n            [0] => 1\n        )\n\n)\n"},
+		{`C:11:"ArrayObject":3:{abc}`,
+			"object(ArrayObject)#1 (1) {\n  [serialized]=>\n  string(3) \"abc\"\n}\n",
+			"ArrayObject Object\n(\n    [serialized] => abc\n)\n"},
+	}
+
+	for i, test := range testList {
+
+		var b bytes.Buffer
+		if err := Dump(&b, []byte(test.data)); err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if b.String() != test.dump {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.dump, b.String())
+		}
+
+		b.Reset()
+		if err := PrintR(&b, []byte(test.data)); err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if b.String() != test.printR {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.printR, b.String())
+		}
+
+	}
+
+}
+
+func TestDump_Invalid(t *testing.T) {
+
+	testList := []struct {
+		data   string
+		dump   string
+		printR string
+	}{
+		{`a:2:{i:0;i:1;i:1;x`,
+			"array(2) {\n  [0]=>\n  int(1)\n  [1]=>\n  *** invalid character 'x' of php type identifier, offset: 17 ***\n",
+			"Array\n(\n    [0] => 1\n    [1] => *** invalid character 'x' of php type identifier, offset: 17 ***\n"},
+		{`a:1:{s:1:"a";a:1:{i:0;`,
+			"array(1) {\n  [\"a\"]=>\n  array(1) {\n    [0]=>\n    *** unexpected end of php serialize data ***\n",
+			"Array\n(\n    [a] => Array\n        (\n            [0] => *** unexpected end of php serialize data ***\n"},
+		{`s:3:"abcd";`, "*** invalid character 'd' after string value, offset: 8 ***\n", ""},
+	}
+
+	for i, test := range testList {
+
+		var b bytes.Buffer
+		if _, ok := Dump(&b, []byte(test.data)).(*SyntaxError); !ok {
+			t.Fatalf("Test fail at index %d, expect a syntax error", i)
+		}
+		if b.String() != test.dump {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.dump, b.String())
+		}
+
+		if test.printR == "" {
+			continue
+		}
+		b.Reset()
+		PrintR(&b, []byte(test.data))
+		if b.String() != test.printR {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.printR, b.String())
+		}
+
+	}
+
+}
+
+func TestSprint(t *testing.T) {
+
+	queue := "default"
+	v := map[string]interface{}{"job": testVisibility{Name: "a", Queue: &queue}, "n": math.Pi}
+
+	data, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	Dump(&b, data)
+	if s := Sprint(v); s != b.String() {
+		t.Fatalf("expect:%s got %s", b.String(), s)
+	}
+	if s := SprintR(1.0 / 3); s != "0.33333333333333" {
+		t.Fatalf("expect:0.33333333333333 got %s", s)
+	}
+	if s := Sprint(make(chan int)); s != "*** php serialize: unsupported type: chan int ***\n" {
+		t.Fatalf("unexpected output %s", s)
+	}
+
+}
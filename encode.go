@@ -45,6 +45,19 @@ type Serializer interface {
 	SerializePHP() ([]byte, error)
 }
 
+// DataSerializer is a class with __serialize. The array SerializePHPData
+// returns, a map, slice, Map or struct, is written as the body of the object
+// instead of the properties.
+type DataSerializer interface {
+	SerializePHPData() (interface{}, error)
+}
+
+// Sleeper is a class with __sleep, only the properties SleepPHP names are
+// written.
+type Sleeper interface {
+	SleepPHP() []string
+}
+
 type UnsupportedTypeError struct {
 	Type reflect.Type
 }
@@ -76,6 +89,15 @@ func (e *MarshalerError) Unwrap() error { return e.Err }
 type encodeState struct {
 	bytes.Buffer
 	scratch [64]byte
+	encOpts
+}
+
+// encOpts are the options set on an Encoder.
+type encOpts struct {
+	// dateTimeClass and dateTimeZoneClass are the classes time.Time and
+	// *time.Location are written as, DateTime and DateTimeZone when empty
+	dateTimeClass     string
+	dateTimeZoneClass string
 }
 
 var encodeStatePool sync.Pool
@@ -85,6 +107,7 @@ func newEncodeState() *encodeState {
 	if v := encodeStatePool.Get(); v != nil {
 		e := v.(*encodeState)
 		e.Reset()
+		e.encOpts = encOpts{}
 		return e
 	}
 	return new(encodeState)
@@ -221,14 +244,24 @@ func typeEncoder(t reflect.Type) encoderFunc {
 }
 
 var (
-	marshalerType     = reflect.TypeOf((*Marshaler)(nil)).Elem()
-	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
-	phpClassType      = reflect.TypeOf((*PHPClass)(nil)).Elem()
-	serializerType    = reflect.TypeOf((*Serializer)(nil)).Elem()
+	marshalerType      = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	textMarshalerType  = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	phpClassType       = reflect.TypeOf((*PHPClass)(nil)).Elem()
+	serializerType     = reflect.TypeOf((*Serializer)(nil)).Elem()
+	dataSerializerType = reflect.TypeOf((*DataSerializer)(nil)).Elem()
+	sleeperType        = reflect.TypeOf((*Sleeper)(nil)).Elem()
 )
 
 func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
 
+	//check time.Time and *time.Location, written as DateTime and DateTimeZone
+	switch t {
+	case timeType:
+		return dateTimeEncoder("").encode
+	case locationPtrType:
+		return dateTimeZoneEncoder("").encode
+	}
+
 	//check Marshaler
 	if t.Implements(marshalerType) {
 		return marshalerEncoder
@@ -254,6 +287,19 @@ func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
 		return newCondAddrEncoder(addrSerializerEncoder, newTypeEncoder(t, false))
 	}
 
+	//check DataSerializer
+	if t.Implements(dataSerializerType) && t.Implements(phpClassType) {
+		return dataSerializerEncoder
+	}
+	if t.Kind() != reflect.Ptr && allowAddr &&
+		reflect.PtrTo(t).Implements(dataSerializerType) && reflect.PtrTo(t).Implements(phpClassType) {
+		return newCondAddrEncoder(addrDataSerializerEncoder, newTypeEncoder(t, false))
+	}
+	if t.Implements(dataSerializerType) && !t.Implements(phpClassType) ||
+		t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(dataSerializerType) && !reflect.PtrTo(t).Implements(phpClassType) {
+		return dataSerializerClassEncoder
+	}
+
 	switch t.Kind() {
 	case reflect.Bool:
 		return boolEncoder
@@ -422,6 +468,69 @@ func addrSerializerEncoder(e *encodeState, v reflect.Value) {
 
 }
 
+func dataSerializerEncoder(e *encodeState, v reflect.Value) {
+
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		e.WriteString(phpNullValue)
+		return
+	}
+	e.writeData(v.Type(), v.Interface())
+
+}
+
+func addrDataSerializerEncoder(e *encodeState, v reflect.Value) {
+
+	va := v.Addr()
+	if va.IsNil() {
+		e.WriteString(phpNullValue)
+		return
+	}
+	e.writeData(v.Type(), va.Interface())
+
+}
+
+var (
+	errSerializeData      = errors.New("SerializePHPData did not return an array")
+	errSerializeDataClass = errors.New("DataSerializer does not implement PHPClass")
+)
+
+// dataSerializerClassEncoder rejects a DataSerializer without a class name
+// to write the data with.
+func dataSerializerClassEncoder(e *encodeState, v reflect.Value) {
+	e.error(&MarshalerError{v.Type(), errSerializeDataClass})
+}
+
+// writeData writes the object with the array SerializePHPData returns as
+// its body.
+func (e *encodeState) writeData(t reflect.Type, v interface{}) {
+
+	data, err := v.(DataSerializer).SerializePHPData()
+	if err != nil {
+		e.error(&MarshalerError{t, err})
+	}
+	dataEncodeState := newEncodeState()
+	defer encodeStatePool.Put(dataEncodeState)
+	dataEncodeState.encOpts = e.encOpts
+	if err = dataEncodeState.marshal(data); err != nil {
+		e.error(err)
+	}
+	b := dataEncodeState.Bytes()
+	if phpValueType(b[0]) != phpTypeArray {
+		e.error(&MarshalerError{t, errSerializeData})
+	}
+
+	className := v.(PHPClass).GetPHPClassName()
+	e.writeTag(phpTypeObject)
+	e.WriteString(strconv.Itoa(len(className)))
+	e.WriteByte(phpSeparator)
+	e.WriteByte(phpDoubleQuote)
+	e.WriteString(className)
+	e.WriteByte(phpDoubleQuote)
+	e.WriteByte(phpSeparator)
+	e.Write(b[2:])
+
+}
+
 func boolEncoder(e *encodeState, v reflect.Value) {
 
 	e.writeTag(phpTypeBoolean)
@@ -464,8 +573,17 @@ type floatEncoder int // number of bits
 func (bits floatEncoder) encode(e *encodeState, v reflect.Value) {
 
 	f := v.Float()
-	if math.IsInf(f, 0) || math.IsNaN(f) {
-		e.error(&UnsupportedValueError{v, strconv.FormatFloat(f, 'G', SerializePrecision, int(bits))})
+	e.writeTag(phpTypeFloat)
+	switch {
+	case math.IsInf(f, 1):
+		e.WriteString("INF;")
+		return
+	case math.IsInf(f, -1):
+		e.WriteString("-INF;")
+		return
+	case math.IsNaN(f):
+		e.WriteString("NAN;")
+		return
 	}
 
 	b := e.scratch[:0]
@@ -476,7 +594,6 @@ func (bits floatEncoder) encode(e *encodeState, v reflect.Value) {
 		b = b[:n-1]
 	}
 
-	e.writeTag(phpTypeFloat)
 	e.Write(b)
 	e.WriteByte(phpTerminator)
 
@@ -534,6 +651,14 @@ func (se structEncoder) encode(e *encodeState, v reflect.Value) {
 
 	fieldsCount := 0
 	structEncodeState := newEncodeState()
+	structEncodeState.encOpts = e.encOpts
+
+	phpClassName := ""
+	isPHPClass := v.Type().Implements(phpClassType)
+	if isPHPClass {
+		phpClassName = v.Interface().(PHPClass).GetPHPClassName()
+	}
+	sleep := sleepFields(v)
 
 FieldLoop:
 	for i := range se.fields.list {
@@ -553,20 +678,21 @@ FieldLoop:
 		if f.omitEmpty && isEmptyValue(fv) {
 			continue
 		}
+		if sleep != nil && !sleep[f.name] {
+			continue
+		}
 
 		fieldsCount++
 
 		//write filed name
-		stringEncoderRaw(structEncodeState, f.name)
+		stringEncoderRaw(structEncodeState, f.propertyName(phpClassName))
 		//write field value
 		f.encoder(structEncodeState, fv)
 
 	}
 
-	if v.Type().Implements(phpClassType) {
+	if isPHPClass {
 
-		phpClass := v.Interface().(PHPClass)
-		phpClassName := phpClass.GetPHPClassName()
 		e.writeTag(phpTypeObject)
 		e.WriteString(strconv.Itoa(len(phpClassName)))
 		e.WriteByte(phpSeparator)
@@ -590,6 +716,28 @@ FieldLoop:
 
 }
 
+// sleepFields returns the properties SleepPHP names, nil when v is not a
+// Sleeper.
+func sleepFields(v reflect.Value) map[string]bool {
+
+	var s Sleeper
+	switch {
+	case v.Type().Implements(sleeperType):
+		s = v.Interface().(Sleeper)
+	case v.CanAddr() && reflect.PtrTo(v.Type()).Implements(sleeperType):
+		s = v.Addr().Interface().(Sleeper)
+	default:
+		return nil
+	}
+
+	fields := make(map[string]bool)
+	for _, name := range s.SleepPHP() {
+		fields[name] = true
+	}
+	return fields
+
+}
+
 func newStructEncoder(t reflect.Type) encoderFunc {
 	se := structEncoder{fields: cachedTypeFields(t)}
 	return se.encode
@@ -1,7 +1,10 @@
 package phpserialize
 
 import (
+	"bytes"
+	"errors"
 	"testing"
+	"time"
 )
 
 type testObject struct {
@@ -13,7 +16,7 @@ func (t testObject) GetPHPClassName() string {
 }
 
 type testExotic struct {
-	EåäöÅÄÖüÜber string `php:åäöÅÄÖüÜber`
+	EåäöÅÄÖüÜber string
 }
 
 func (t testExotic) GetPHPClassName() string {
@@ -279,3 +282,182 @@ func TestEncoder_Encode_Precision(t *testing.T) {
 	t.Log("all tests passed.")
 
 }
+
+type testVisibility struct {
+	Name    string  `php:"name"`
+	Queue   *string `php:"queue,protected"`
+	Secret  string  `php:"secret,private"`
+	skipped int
+	Count   *int `php:"count"`
+}
+
+func (testVisibility) GetPHPClassName() string {
+	return "Job"
+}
+
+func TestMarshal_Visibility(t *testing.T) {
+
+	queue, count := "default", 3
+	value := testVisibility{Name: "a", Queue: &queue, Secret: "s", Count: &count}
+	expect := "O:3:\"Job\":4:{s:4:\"name\";s:1:\"a\";s:8:\"\x00*\x00queue\";s:7:\"default\";s:11:\"\x00Job\x00secret\";s:1:\"s\";s:5:\"count\";i:3;}"
+
+	result, err := Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != expect {
+		t.Fatalf("expect:%q got %q", expect, result)
+	}
+
+	var decoded testVisibility
+	if err = Unmarshal(result, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Name != "a" || *decoded.Queue != "default" || decoded.Secret != "s" || *decoded.Count != 3 {
+		t.Fatalf("unexpected value %+v", decoded)
+	}
+
+}
+
+type testMoney struct {
+	Amount   int
+	Currency string
+}
+
+func (testMoney) GetPHPClassName() string {
+	return "Money"
+}
+
+func (m testMoney) SerializePHPData() (interface{}, error) {
+	return []interface{}{m.Amount, m.Currency}, nil
+}
+
+func (m *testMoney) UnserializePHPData(data []byte) error {
+
+	var list []interface{}
+	if err := Unmarshal(data, &list); err != nil {
+		return err
+	}
+	if len(list) != 2 {
+		return errors.New("unexpected Money data")
+	}
+	amount, _ := list[0].(int64)
+	m.Amount, m.Currency = int(amount), list[1].(string)
+	return nil
+
+}
+
+type testSession struct {
+	User    string `php:"user"`
+	Token   string `php:"token"`
+	Expired bool   `php:"-"`
+}
+
+func (testSession) GetPHPClassName() string {
+	return "Session"
+}
+
+func (testSession) SleepPHP() []string {
+	return []string{"user"}
+}
+
+func (s *testSession) WakeupPHP() error {
+
+	if s.User == "" {
+		return errors.New("session without user")
+	}
+	s.Expired = s.Token == ""
+	return nil
+
+}
+
+func TestMarshal_SerializeData(t *testing.T) {
+
+	expect := `a:2:{i:0;O:5:"Money":2:{i:0;i:100;i:1;s:3:"EUR";}i:1;N;}`
+	result, err := Marshal([]*testMoney{{100, "EUR"}, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != expect {
+		t.Fatalf("expect:%s got %s", expect, result)
+	}
+
+	var decoded []testMoney
+	if err = Unmarshal(result, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[0] != (testMoney{100, "EUR"}) || decoded[1] != (testMoney{}) {
+		t.Fatalf("unexpected value %+v", decoded)
+	}
+
+	var other testMoney
+	if err = Unmarshal([]byte(`O:4:"Euro":2:{i:0;i:1;i:1;s:3:"EUR";}`), &other); err == nil {
+		t.Fatal("expect an error for another class")
+	}
+
+}
+
+type testEvent struct {
+	At time.Time
+}
+
+func (testEvent) GetPHPClassName() string {
+	return "Event"
+}
+
+func (e testEvent) SerializePHPData() (interface{}, error) {
+	return []interface{}{e.At}, nil
+}
+
+type testUnclassedData struct{}
+
+func (testUnclassedData) SerializePHPData() (interface{}, error) {
+	return []interface{}{}, nil
+}
+
+func TestMarshal_SerializeDataOptions(t *testing.T) {
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.DateTimeClass("DateTimeImmutable")
+	if err := enc.Encode(testEvent{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+	expect := `O:5:"Event":1:{i:0;O:17:"DateTimeImmutable":3:{s:4:"date";s:26:"2024-01-02 00:00:00.000000";s:13:"timezone_type";i:3;s:8:"timezone";s:3:"UTC";}}`
+	if buf.String() != expect {
+		t.Fatalf("expect:%s got %s", expect, buf.String())
+	}
+
+	if _, err := Marshal(testUnclassedData{}); err == nil {
+		t.Fatal("expect an error for a DataSerializer without a class")
+	}
+	if _, err := Marshal(&testUnclassedData{}); err == nil {
+		t.Fatal("expect an error for a DataSerializer without a class")
+	}
+
+}
+
+func TestMarshal_SleepWakeup(t *testing.T) {
+
+	expect := `O:7:"Session":1:{s:4:"user";s:5:"alice";}`
+	result, err := Marshal(testSession{User: "alice", Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != expect {
+		t.Fatalf("expect:%s got %s", expect, result)
+	}
+
+	var decoded testSession
+	if err = Unmarshal(result, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.User != "alice" || !decoded.Expired {
+		t.Fatalf("unexpected value %+v", decoded)
+	}
+	var empty testSession
+	if err = Unmarshal([]byte(`O:7:"Session":0:{}`), &empty); err == nil {
+		t.Fatal("expect the WakeupPHP error")
+	}
+
+}
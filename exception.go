@@ -0,0 +1,174 @@
+package phpserialize
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+)
+
+// PHPException is a php Exception or Error, of any subclass. It is a Go error
+// whose Unwrap follows the previous exception. Objects decode to it in a
+// PHPException or an error, in an interface{} only when OnUnknownClass
+// returns its type.
+type PHPException struct {
+	Class   string // "Exception" when empty
+	Message string
+	Code    int
+	// CodeString is the code of exceptions with a string code, like the
+	// SQLSTATE of PDOException. It is written instead of Code when set.
+	CodeString string
+	File       string
+	Line       int
+	Trace      []PHPTraceFrame
+
+	Previous *PHPException
+
+	// Properties are the properties subclasses add, kept to be written back.
+	Properties []IncompleteProperty
+
+	// Base is the class declaring the private properties, Exception or
+	// Error. When empty it is Error for php's core Error classes and
+	// Exception for others.
+	Base string
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// errorClasses are php's core subclasses of Error.
+var errorClasses = map[string]bool{
+	"Error": true, "ArithmeticError": true, "DivisionByZeroError": true,
+	"AssertionError": true, "CompileError": true, "ParseError": true,
+	"TypeError": true, "ArgumentCountError": true, "ValueError": true,
+	"UnhandledMatchError": true, "FiberError": true,
+}
+
+// PHPTraceFrame is a frame of the trace of a PHPException.
+type PHPTraceFrame struct {
+	File     string        `php:"file,omitempty"`
+	Line     int           `php:"line,omitempty"`
+	Function string        `php:"function"`
+	Class    string        `php:"class,omitempty"`
+	Type     string        `php:"type,omitempty"`
+	Args     []interface{} `php:"args,omitempty"`
+}
+
+// FromError converts err and the errors it wraps into a PHPException chain,
+// other errors than PHPExceptions become Exceptions with their message.
+func FromError(err error) *PHPException {
+
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*PHPException); ok {
+		return e
+	}
+	return &PHPException{Class: "Exception", Message: err.Error(), Previous: FromError(errors.Unwrap(err))}
+
+}
+
+func (e *PHPException) Error() string {
+
+	msg := orDefault(e.Class, "Exception") + ": " + e.Message
+	if e.File != "" {
+		msg += " in " + e.File + ":" + strconv.Itoa(e.Line)
+	}
+	return msg
+
+}
+
+func (e *PHPException) Unwrap() error {
+
+	if e.Previous == nil {
+		return nil
+	}
+	return e.Previous
+
+}
+
+func (e PHPException) MarshalPHP() ([]byte, error) {
+
+	class := orDefault(e.Class, "Exception")
+	base := e.Base
+	if base == "" {
+		base = "Exception"
+		if errorClasses[class] {
+			base = "Error"
+		}
+	}
+	private := "\x00" + base + "\x00"
+
+	trace := e.Trace
+	if trace == nil {
+		trace = []PHPTraceFrame{}
+	}
+	var previous interface{}
+	if e.Previous != nil {
+		previous = e.Previous
+	}
+	var code interface{} = e.Code
+	if e.CodeString != "" {
+		code = e.CodeString
+	}
+
+	props := []interface{}{"\x00*\x00message", e.Message, private + "string", "", "\x00*\x00code", code,
+		"\x00*\x00file", e.File, "\x00*\x00line", e.Line, private + "trace", trace, private + "previous", previous}
+	for _, p := range e.Properties {
+		if key, ok := p.Key.(string); ok {
+			props = append(props, key, p.Value)
+		}
+	}
+	return marshalObject(class, props...)
+
+}
+
+func (e *PHPException) UnmarshalPHP(data []byte) error {
+
+	var o IncompleteObject
+	if err := o.UnmarshalPHP(data); err != nil {
+		return err
+	}
+	if o.Custom {
+		return &UnmarshalTypeError{Value: "custom", Type: reflect.TypeOf(e)}
+	}
+	*e = PHPException{Class: o.Class}
+
+	for _, p := range o.Properties {
+		key, _ := p.Key.(string)
+		name, class := unmangleProperty(key)
+		if class == "Exception" || class == "Error" {
+			e.Base = class
+		}
+		private := class != "" && class == e.Base
+
+		var err error
+		switch {
+		case class == "*" && name == "message":
+			err = Unmarshal(p.Value, &e.Message)
+		case class == "*" && name == "code":
+			var code interface{}
+			err = Unmarshal(p.Value, &code)
+			switch c := code.(type) {
+			case int64:
+				e.Code = int(c)
+			case string:
+				e.CodeString = c
+			}
+		case class == "*" && name == "file":
+			err = Unmarshal(p.Value, &e.File)
+		case class == "*" && name == "line":
+			err = Unmarshal(p.Value, &e.Line)
+		case private && name == "trace":
+			err = Unmarshal(p.Value, &e.Trace)
+		case private && name == "previous":
+			err = Unmarshal(p.Value, &e.Previous)
+		case private && name == "string":
+		default:
+			e.Properties = append(e.Properties, p)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+
+}
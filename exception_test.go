@@ -0,0 +1,146 @@
+package phpserialize
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPHPException(t *testing.T) {
+
+	previous := `O:9:"TypeError":7:{s:10:"` + "\x00*\x00" + `message";s:3:"bad";s:13:"` + "\x00Error\x00" + `string";s:0:"";` +
+		`s:7:"` + "\x00*\x00" + `code";i:0;s:7:"` + "\x00*\x00" + `file";s:8:"/a/b.php";s:7:"` + "\x00*\x00" + `line";i:7;` +
+		`s:12:"` + "\x00Error\x00" + `trace";a:0:{}s:15:"` + "\x00Error\x00" + `previous";N;}`
+	test := `O:16:"RuntimeException":8:{s:10:"` + "\x00*\x00" + `message";s:6:"failed";s:17:"` + "\x00Exception\x00" + `string";s:0:"";` +
+		`s:7:"` + "\x00*\x00" + `code";i:3;s:7:"` + "\x00*\x00" + `file";s:8:"/a/c.php";s:7:"` + "\x00*\x00" + `line";i:12;` +
+		`s:16:"` + "\x00Exception\x00" + `trace";a:1:{i:0;a:4:{s:4:"file";s:8:"/a/d.php";s:4:"line";i:3;s:8:"function";s:3:"run";s:5:"class";s:3:"Job";}}` +
+		`s:19:"` + "\x00Exception\x00" + `previous";` + previous + `s:6:"` + "\x00*\x00" + `job";s:3:"foo";}`
+
+	var e PHPException
+	if err := Unmarshal([]byte(test), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Class != "RuntimeException" || e.Code != 3 || e.Line != 12 || len(e.Trace) != 1 || e.Trace[0].Class != "Job" ||
+		e.Previous == nil || e.Previous.Class != "TypeError" || len(e.Properties) != 1 {
+		t.Fatalf("unexpected exception %+v", e)
+	}
+	if expect := "RuntimeException: failed in /a/c.php:12"; e.Error() != expect {
+		t.Fatalf("expect:%s got %s", expect, e.Error())
+	}
+	var typeErr *PHPException
+	if !errors.As(errors.Unwrap(&e), &typeErr) || typeErr.Message != "bad" {
+		t.Fatalf("unexpected previous %v", typeErr)
+	}
+
+	data, err := Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != test {
+		t.Fatalf("expect:%q got %q", test, data)
+	}
+
+	var v interface{}
+	if err = Unmarshal([]byte(previous), &v); err != nil {
+		t.Fatal(err)
+	}
+	if o, ok := v.(IncompleteObject); !ok || o.Class != "TypeError" {
+		t.Fatalf("unexpected value %#v", v)
+	}
+
+	var target error
+	if err = Unmarshal([]byte(previous), &target); err != nil {
+		t.Fatal(err)
+	}
+	if typeErr, ok := target.(*PHPException); !ok || typeErr.Class != "TypeError" || typeErr.Base != "Error" || typeErr.Message != "bad" {
+		t.Fatalf("unexpected error %#v", target)
+	}
+
+	dec := NewDecoder(strings.NewReader(previous))
+	dec.OnUnknownClass(func(class string) (reflect.Type, error) {
+		return reflect.TypeOf(PHPException{}), nil
+	})
+	if err = dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if typeErr, ok := v.(PHPException); !ok || typeErr.Class != "TypeError" || typeErr.Message != "bad" {
+		t.Fatalf("unexpected value %#v", v)
+	}
+
+}
+
+func TestPHPException_Code(t *testing.T) {
+
+	test := `O:12:"PDOException":8:{s:10:"` + "\x00*\x00" + `message";s:5:"table";s:17:"` + "\x00Exception\x00" + `string";s:0:"";` +
+		`s:7:"` + "\x00*\x00" + `code";s:5:"42S02";s:7:"` + "\x00*\x00" + `file";s:0:"";s:7:"` + "\x00*\x00" + `line";i:0;` +
+		`s:16:"` + "\x00Exception\x00" + `trace";a:0:{}s:19:"` + "\x00Exception\x00" + `previous";N;s:9:"errorInfo";N;}`
+
+	var e PHPException
+	if err := Unmarshal([]byte(test), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.CodeString != "42S02" || e.Code != 0 {
+		t.Fatalf("unexpected code %d %q", e.Code, e.CodeString)
+	}
+	data, err := Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != test {
+		t.Fatalf("expect:%q got %q", test, data)
+	}
+
+}
+
+func TestPHPException_Base(t *testing.T) {
+
+	testList := []struct {
+		value PHPException
+		base  string
+	}{
+		{PHPException{Class: "TypeError"}, "Error"},
+		{PHPException{Class: "App\\PaymentError"}, "Exception"},
+		{PHPException{Class: "App\\Fatal", Base: "Error"}, "Error"},
+	}
+
+	for i, test := range testList {
+
+		data, err := Marshal(test.value)
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if !strings.Contains(string(data), "\x00"+test.base+"\x00trace") {
+			t.Fatalf("Test fail at index %d, expect the base %s got %q", i, test.base, data)
+		}
+
+	}
+
+}
+
+func TestFromError(t *testing.T) {
+
+	err := fmt.Errorf("save job: %w", errors.New("connection refused"))
+	e := FromError(err)
+	if e.Class != "Exception" || e.Message != err.Error() || e.Previous == nil || e.Previous.Message != "connection refused" {
+		t.Fatalf("unexpected exception %+v", e)
+	}
+
+	data, _ := Marshal(FromError(errors.New("x")))
+	expect := `O:9:"Exception":7:{s:10:"` + "\x00*\x00" + `message";s:1:"x";s:17:"` + "\x00Exception\x00" + `string";s:0:"";` +
+		`s:7:"` + "\x00*\x00" + `code";i:0;s:7:"` + "\x00*\x00" + `file";s:0:"";s:7:"` + "\x00*\x00" + `line";i:0;` +
+		`s:16:"` + "\x00Exception\x00" + `trace";a:0:{}s:19:"` + "\x00Exception\x00" + `previous";N;}`
+	if string(data) != expect {
+		t.Fatalf("expect:%q got %q", expect, data)
+	}
+
+	var target error
+	if err := Unmarshal(data, &target); err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := target.(*PHPException); !ok || e.Message != "x" {
+		t.Fatalf("unexpected error %#v", target)
+	}
+
+}
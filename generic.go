@@ -0,0 +1,444 @@
+package phpserialize
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Decode unmarshals data into a new T and returns it.
+func Decode[T any](data []byte) (T, error) {
+
+	var v T
+	err := Unmarshal(data, &v)
+	return v, err
+
+}
+
+// MarshalTo appends the serialized form of v to dst, dst is returned as it
+// was on errors.
+func MarshalTo[T any](dst []byte, v T) ([]byte, error) {
+
+	e := newEncodeState()
+	defer encodeStatePool.Put(e)
+
+	err := e.marshal(v)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, e.Bytes()...), nil
+
+}
+
+// List is a php array read as a list of values, the keys of the array are
+// ignored while decoding and rebuilt as 0..n-1 while encoding.
+type List[T any] []T
+
+func (l List[T]) MarshalPHP() ([]byte, error) {
+
+	e := newEncodeState()
+	defer encodeStatePool.Put(e)
+
+	e.writeTagAndLength(phpTypeArray, len(l))
+	e.WriteByte(phpLeftBraces)
+	for i := range l {
+		intEncoderRaw(e, i)
+		if err := e.marshal(l[i]); err != nil {
+			return nil, err
+		}
+	}
+	e.WriteByte(phpRightBraces)
+
+	return append([]byte(nil), e.Bytes()...), nil
+
+}
+
+func (l *List[T]) UnmarshalPHP(data []byte) error {
+
+	var d decodeState
+	d.init(data)
+	d.scan.reset()
+
+	list := (*l)[:0]
+	err := d.arrayEach(func(_ interface{}) error {
+		var elem T
+		if err := d.value(reflect.ValueOf(&elem)); err != nil {
+			return err
+		}
+		list = append(list, elem)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*l = list
+	return d.savedError
+
+}
+
+// MapKey are the key types of a Map, php array keys are strings or integers.
+type MapKey interface {
+	~string | ~int | ~int64
+}
+
+// Map is an ordered php array. Keys follow the php rules: a string key
+// holding a decimal integer is the same key as that integer.
+type Map[K MapKey, V any] struct {
+	keys   []K
+	values map[K]V
+}
+
+// Len returns the number of entries.
+func (m *Map[K, V]) Len() int {
+	return len(m.keys)
+}
+
+// Keys returns a copy of the keys in order.
+func (m *Map[K, V]) Keys() []K {
+	return append([]K(nil), m.keys...)
+}
+
+// Get returns the value of key and whether it is present.
+func (m *Map[K, V]) Get(key K) (V, bool) {
+
+	v, ok := m.values[key]
+	return v, ok
+
+}
+
+// Set sets the value of key, a new key is added at the end.
+func (m *Map[K, V]) Set(key K, value V) {
+
+	if m.values == nil {
+		m.values = make(map[K]V)
+	}
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+
+}
+
+// Delete removes key, the order of the other keys is kept.
+func (m *Map[K, V]) Delete(key K) {
+
+	if _, ok := m.values[key]; !ok {
+		return
+	}
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+
+}
+
+// Range calls f for each entry in order until it returns false.
+func (m *Map[K, V]) Range(f func(key K, value V) bool) {
+
+	for _, k := range m.keys {
+		if !f(k, m.values[k]) {
+			return
+		}
+	}
+
+}
+
+func (m Map[K, V]) MarshalPHP() ([]byte, error) {
+
+	e := newEncodeState()
+	defer encodeStatePool.Put(e)
+
+	e.writeTagAndLength(phpTypeArray, len(m.keys))
+	e.WriteByte(phpLeftBraces)
+	for _, k := range m.keys {
+		writeArrayKey(e, reflect.ValueOf(k))
+		if err := e.marshal(m.values[k]); err != nil {
+			return nil, err
+		}
+	}
+	e.WriteByte(phpRightBraces)
+
+	return append([]byte(nil), e.Bytes()...), nil
+
+}
+
+func (m *Map[K, V]) UnmarshalPHP(data []byte) error {
+
+	var d decodeState
+	d.init(data)
+	d.scan.reset()
+
+	m.keys = m.keys[:0]
+	m.values = make(map[K]V)
+
+	err := d.arrayEach(func(key interface{}) error {
+
+		var k K
+		kv := reflect.ValueOf(&k).Elem()
+		if !setArrayKey(kv, key) {
+			d.saveError(&UnmarshalTypeError{Value: fmt.Sprint("key ", key), Type: kv.Type(), Offset: int64(d.off)})
+			d.skip()
+			return nil
+		}
+
+		var elem V
+		if err := d.value(reflect.ValueOf(&elem)); err != nil {
+			return err
+		}
+		m.Set(k, elem)
+		return nil
+
+	})
+	if err != nil {
+		return err
+	}
+	return d.savedError
+
+}
+
+// Get walks a decoded value by array keys, struct field names or list
+// indexes and converts what it finds into T. Use GetRaw to look up a path in
+// serialized data.
+func Get[T any](value interface{}, path ...interface{}) (T, error) {
+
+	var result T
+
+	v := reflect.ValueOf(value)
+	for i, key := range path {
+		next, ok := pathElem(v, key)
+		if !ok {
+			return result, &PathError{Path: path[:i+1]}
+		}
+		v = next
+	}
+
+	rv := reflect.ValueOf(&result).Elem()
+	if err := convertValue(v, rv); err != nil {
+		return result, err
+	}
+	return result, nil
+
+}
+
+// PathError is returned when a path doesn't lead to a value, Path is the part
+// of it up to the first key that wasn't found.
+type PathError struct {
+	Path []interface{}
+}
+
+func (e *PathError) Error() string {
+	return "php serialize: path " + fmt.Sprint(e.Path) + " not found"
+}
+
+func pathElem(v reflect.Value, key interface{}) (reflect.Value, bool) {
+
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return reflect.Value{}, false
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+
+		k := reflect.New(v.Type().Key()).Elem()
+		if k.Kind() == reflect.Interface {
+			k.Set(reflect.ValueOf(normalizeArrayKey(key)))
+		} else if !setArrayKey(k, normalizeArrayKey(key)) {
+			return reflect.Value{}, false
+		}
+		e := v.MapIndex(k)
+		return e, e.IsValid()
+
+	case reflect.Slice, reflect.Array:
+
+		n, ok := normalizeArrayKey(key).(int64)
+		if !ok || n < 0 || n >= int64(v.Len()) {
+			return reflect.Value{}, false
+		}
+		return v.Index(int(n)), true
+
+	case reflect.Struct:
+
+		name, ok := key.(string)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		fields := cachedTypeFields(v.Type())
+		i, ok := fields.nameIndex[name]
+		if !ok {
+			return reflect.Value{}, false
+		}
+		for _, index := range fields.list[i].index {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return reflect.Value{}, false
+				}
+				v = v.Elem()
+			}
+			v = v.Field(index)
+		}
+		return v, true
+
+	}
+	return reflect.Value{}, false
+
+}
+
+func convertValue(src, dst reflect.Value) error {
+
+	for src.IsValid() && src.Kind() == reflect.Interface {
+		src = src.Elem()
+	}
+	if !src.IsValid() {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+
+	if isNumberKind(src.Kind()) && isNumberKind(dst.Kind()) {
+		dst.Set(src.Convert(dst.Type()))
+		return nil
+	}
+
+	// fall back to a round trip through the php serialize format
+	data, err := Marshal(src.Interface())
+	if err != nil {
+		return err
+	}
+	return Unmarshal(data, dst.Addr().Interface())
+
+}
+
+func isNumberKind(k reflect.Kind) bool {
+
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+
+}
+
+// normalizeArrayKey turns a path or map key into the key php would use,
+// an int64 or a string.
+func normalizeArrayKey(key interface{}) interface{} {
+
+	v := reflect.ValueOf(key)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(v.Uint())
+	case reflect.String:
+		if n, ok := phpIntKey(v.String()); ok {
+			return n
+		}
+		return v.String()
+	case reflect.Bool:
+		if v.Bool() {
+			return int64(1)
+		}
+		return int64(0)
+	}
+	return key
+
+}
+
+// phpIntKey reports whether s is a string php stores as an integer array key.
+func phpIntKey(s string) (int64, bool) {
+
+	if s == "" || len(s) > 20 {
+		return 0, false
+	}
+
+	digits := s
+	if s[0] == '-' {
+		digits = s[1:]
+	}
+	if digits == "" || (digits[0] == '0' && (len(digits) > 1 || s[0] == '-')) {
+		return 0, false
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, false
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+
+}
+
+func setArrayKey(v reflect.Value, key interface{}) bool {
+
+	switch k := key.(type) {
+	case int64:
+
+		switch v.Kind() {
+		case reflect.String:
+			v.SetString(strconv.FormatInt(k, 10))
+			return true
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.OverflowInt(k) {
+				return false
+			}
+			v.SetInt(k)
+			return true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if k < 0 || v.OverflowUint(uint64(k)) {
+				return false
+			}
+			v.SetUint(uint64(k))
+			return true
+		}
+
+	case string:
+
+		if v.Kind() == reflect.String {
+			v.SetString(k)
+			return true
+		}
+		if n, ok := phpIntKey(k); ok {
+			return setArrayKey(v, n)
+		}
+
+	}
+	return false
+
+}
+
+func writeArrayKey(e *encodeState, k reflect.Value) {
+
+	switch k.Kind() {
+	case reflect.String:
+		if n, ok := phpIntKey(k.String()); ok {
+			e.writeTag(phpTypeInteger)
+			e.Write(strconv.AppendInt(e.scratch[:0], n, 10))
+			e.WriteByte(phpTerminator)
+			return
+		}
+		stringEncoderRaw(e, k.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intEncoder(e, k)
+	default:
+		uintEncoder(e, k)
+	}
+
+}
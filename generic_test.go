@@ -0,0 +1,148 @@
+package phpserialize
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+
+	tk, err := Decode[token]([]byte("a:2:{s:12:\"access_token\";s:0:\"\";s:10:\"expires_in\";i:7200;}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tk.AccessToken != "" || tk.Expire != 7200 {
+		t.Fatalf("unexpected token %+v", tk)
+	}
+
+	f, err := Decode[float64]([]byte("d:5.2E+25;"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != 5.2e25 {
+		t.Fatalf("unexpected float %v", f)
+	}
+
+	raw, err := Decode[RawMessage]([]byte("a:1:{i:0;s:2:\"xy\";}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "a:1:{i:0;s:2:\"xy\";}" {
+		t.Fatalf("unexpected raw message %s", raw)
+	}
+
+	data, err := MarshalTo([]byte("prefix:"), []int{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "prefix:a:1:{i:0;i:1;}" {
+		t.Fatalf("unexpected data %s", data)
+	}
+
+}
+
+func TestList(t *testing.T) {
+
+	l, err := Decode[List[string]]([]byte("a:2:{i:3;s:1:\"a\";s:1:\"x\";s:1:\"b\";}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(l, List[string]{"a", "b"}) {
+		t.Fatalf("unexpected list %v", l)
+	}
+
+	data, err := Marshal(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a:2:{i:0;s:1:\"a\";i:1;s:1:\"b\";}" {
+		t.Fatalf("unexpected data %s", data)
+	}
+
+}
+
+func TestMap(t *testing.T) {
+
+	data := "a:3:{s:1:\"b\";i:1;i:5;a:1:{i:0;N;}s:1:\"a\";i:3;}"
+
+	m, err := Decode[Map[string, interface{}]]([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.Keys(), []string{"b", "5", "a"}) {
+		t.Fatalf("unexpected keys %v", m.Keys())
+	}
+
+	result, err := Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != data {
+		t.Fatalf("expect %s got %s", data, result)
+	}
+
+	var ints Map[int, string]
+	ints.Set(2, "x")
+	ints.Set(1, "y")
+	ints.Delete(2)
+	result, err = Marshal(&ints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "a:1:{i:1;s:1:\"y\";}" {
+		t.Fatalf("unexpected data %s", result)
+	}
+
+	err = Unmarshal([]byte("a:1:{s:1:\"x\";i:1;}"), &ints)
+	if _, ok := err.(*UnmarshalTypeError); !ok {
+		t.Fatalf("expect UnmarshalTypeError, got %v", err)
+	}
+
+}
+
+func TestGet(t *testing.T) {
+
+	var v interface{}
+	err := Unmarshal([]byte("a:1:{s:4:\"user\";a:2:{s:2:\"id\";i:42;s:5:\"roles\";a:2:{i:0;s:5:\"admin\";i:1;s:6:\"editor\";}}}"), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := Get[int](v, "user", "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 42 {
+		t.Fatalf("unexpected id %d", id)
+	}
+
+	role, err := Get[string](v, "user", "roles", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role != "editor" {
+		t.Fatalf("unexpected role %s", role)
+	}
+
+	roles, err := Get[[]string](v, "user", "roles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(roles, []string{"admin", "editor"}) {
+		t.Fatalf("unexpected roles %v", roles)
+	}
+
+	expire, err := Get[int64](token{Expire: 60}, "expires_in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expire != 60 {
+		t.Fatalf("unexpected expire %d", expire)
+	}
+
+	_, err = Get[int](v, "user", "name")
+	if _, ok := err.(*PathError); !ok {
+		t.Fatalf("expect PathError, got %v", err)
+	}
+
+}
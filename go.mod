@@ -1,5 +1,5 @@
 module github.com/zengxinqian/phpserialize
 
-go 1.15
+go 1.18
 
 require github.com/pkg/errors v0.9.1
@@ -0,0 +1,591 @@
+package phpserialize
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+)
+
+// igbinary value types
+const (
+	igNull         byte = 0x00
+	igRef8         byte = 0x01
+	igRef16        byte = 0x02
+	igRef32        byte = 0x03
+	igFalse        byte = 0x04
+	igTrue         byte = 0x05
+	igLong8p       byte = 0x06
+	igLong8n       byte = 0x07
+	igLong16p      byte = 0x08
+	igLong16n      byte = 0x09
+	igLong32p      byte = 0x0a
+	igLong32n      byte = 0x0b
+	igDouble       byte = 0x0c
+	igStringEmpty  byte = 0x0d
+	igStringID8    byte = 0x0e
+	igStringID16   byte = 0x0f
+	igStringID32   byte = 0x10
+	igString8      byte = 0x11
+	igString16     byte = 0x12
+	igString32     byte = 0x13
+	igArray8       byte = 0x14
+	igArray16      byte = 0x15
+	igArray32      byte = 0x16
+	igObject8      byte = 0x17
+	igObject16     byte = 0x18
+	igObject32     byte = 0x19
+	igObjectID8    byte = 0x1a
+	igObjectID16   byte = 0x1b
+	igObjectID32   byte = 0x1c
+	igObjectSer8   byte = 0x1d
+	igObjectSer16  byte = 0x1e
+	igObjectSer32  byte = 0x1f
+	igLong64p      byte = 0x20
+	igLong64n      byte = 0x21
+	igObjRef8      byte = 0x22
+	igObjRef16     byte = 0x23
+	igObjRef32     byte = 0x24
+	igRef          byte = 0x25
+	igbinaryHeader      = 2
+)
+
+var errIgbinaryRecursion = errors.New("php serialize: igbinary reference to a value that contains it")
+
+// MarshalIgbinary returns the igbinary_serialize encoding of v. Strings are
+// interned and integers written in the smallest size, like igbinary does
+// with igbinary.compact_strings on.
+func MarshalIgbinary(v interface{}) ([]byte, error) {
+
+	data, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return SerializedToIgbinary(data)
+
+}
+
+// UnmarshalIgbinary decodes igbinary data into v, which is filled exactly
+// like Unmarshal fills it. References are resolved into copies of the
+// referenced value.
+func UnmarshalIgbinary(data []byte, v interface{}) error {
+
+	text, err := IgbinaryToSerialized(data)
+	if err != nil {
+		return err
+	}
+	return Unmarshal(text, v)
+
+}
+
+// SerializedToIgbinary converts a serialized value to igbinary.
+func SerializedToIgbinary(data []byte) ([]byte, error) {
+
+	if len(data) == 0 {
+		return nil, &SyntaxError{"unexpected end of php serialize data", 0}
+	}
+	if err := checkValid(data, &scanner{}); err != nil {
+		return nil, err
+	}
+
+	e := igbinaryEncoder{strings: make(map[string]int)}
+	e.out = appendUint(make([]byte, 0, len(data)), igbinaryHeader, 4)
+	e.value(data, 0)
+	return e.out, nil
+
+}
+
+// IgbinaryToSerialized converts igbinary data to a serialized value.
+func IgbinaryToSerialized(data []byte) ([]byte, error) {
+
+	if len(data) < 4 {
+		return nil, &SyntaxError{"unexpected end of igbinary data", int64(len(data))}
+	}
+	if version := binary.BigEndian.Uint32(data); version != 1 && version != 2 {
+		return nil, &SyntaxError{"unsupported igbinary version " + strconv.FormatUint(uint64(version), 10), 0}
+	}
+
+	d := igbinaryDecoder{data: data, off: 4, out: make([]byte, 0, len(data)*2)}
+	if err := d.value(); err != nil {
+		return nil, err
+	}
+	if d.off != len(data) {
+		return nil, d.error("invalid data after igbinary value")
+	}
+	return d.out, nil
+
+}
+
+type igbinaryEncoder struct {
+	out     []byte
+	strings map[string]int
+}
+
+// value converts the valid serialized value at off and returns the offset
+// after it.
+func (e *igbinaryEncoder) value(data []byte, off int) int {
+
+	switch phpValueType(data[off]) {
+	case phpTypeNull:
+		e.out = append(e.out, igNull)
+		return off + 2
+	case phpTypeBoolean:
+		if data[off+2] == '1' {
+			e.out = append(e.out, igTrue)
+		} else {
+			e.out = append(e.out, igFalse)
+		}
+		return off + 4
+	case phpTypeInteger, phpTypeFloat:
+		end := off + 2
+		for data[end] != phpTerminator {
+			end++
+		}
+		s := string(data[off+2 : end])
+		if phpValueType(data[off]) == phpTypeInteger {
+			n, _ := strconv.ParseInt(s, 10, 64)
+			e.long(n)
+		} else {
+			f, _ := strconv.ParseFloat(s, 64)
+			e.out = append(e.out, igDouble)
+			e.out = appendUint(e.out, math.Float64bits(f), 8)
+		}
+		return end + 1
+	case phpTypeString:
+		n, start := readLength(data, off+2)
+		e.string(data[start+1 : start+1+n])
+		return start + n + 3
+	case phpTypeArray:
+		n, start := readLength(data, off+2)
+		e.sized(n, igArray8)
+		return e.elements(data, start+1, n)
+	case phpTypeObject, phpTypeCustom:
+		n, start := readLength(data, off+2)
+		e.className(data[start+1 : start+1+n])
+		n, start = readLength(data, start+n+3)
+		if phpValueType(data[off]) == phpTypeCustom {
+			e.sized(n, igObjectSer8)
+			e.out = append(e.out, data[start+1:start+1+n]...)
+			return start + n + 2
+		}
+		e.sized(n, igArray8)
+		return e.elements(data, start+1, n)
+	}
+	panic(phasePanicMsg)
+
+}
+
+func (e *igbinaryEncoder) elements(data []byte, off int, n int) int {
+
+	for i := 0; i < 2*n; i++ {
+		off = e.value(data, off)
+	}
+	return off + 1
+
+}
+
+func (e *igbinaryEncoder) long(n int64) {
+
+	tag, u := igLong8p, uint64(n)
+	if n < 0 {
+		tag, u = igLong8n, uint64(-n)
+	}
+	switch {
+	case u <= math.MaxUint8:
+		e.out = append(e.out, tag, byte(u))
+	case u <= math.MaxUint16:
+		e.out = appendUint(append(e.out, tag+igLong16p-igLong8p), u, 2)
+	case u <= math.MaxUint32:
+		e.out = appendUint(append(e.out, tag+igLong32p-igLong8p), u, 4)
+	default:
+		e.out = appendUint(append(e.out, tag+igLong64p-igLong8p), u, 8)
+	}
+
+}
+
+// sized writes tag8, tag16 or tag32 with n, tag8 given.
+func (e *igbinaryEncoder) sized(n int, tag8 byte) {
+
+	switch {
+	case n <= math.MaxUint8:
+		e.out = append(e.out, tag8, byte(n))
+	case n <= math.MaxUint16:
+		e.out = appendUint(append(e.out, tag8+1), uint64(n), 2)
+	default:
+		e.out = appendUint(append(e.out, tag8+2), uint64(n), 4)
+	}
+
+}
+
+func (e *igbinaryEncoder) string(s []byte) {
+
+	if len(s) == 0 {
+		e.out = append(e.out, igStringEmpty)
+		return
+	}
+	if id, ok := e.strings[string(s)]; ok {
+		e.sized(id, igStringID8)
+		return
+	}
+	e.strings[string(s)] = len(e.strings)
+	e.sized(len(s), igString8)
+	e.out = append(e.out, s...)
+
+}
+
+func (e *igbinaryEncoder) className(name []byte) {
+
+	if id, ok := e.strings[string(name)]; ok {
+		e.sized(id, igObjectID8)
+		return
+	}
+	e.strings[string(name)] = len(e.strings)
+	e.sized(len(name), igObject8)
+	e.out = append(e.out, name...)
+
+}
+
+// appendUint appends u as a big endian integer of size bytes.
+func appendUint(b []byte, u uint64, size int) []byte {
+
+	for i := size - 1; i >= 0; i-- {
+		b = append(b, byte(u>>(8*i)))
+	}
+	return b
+
+}
+
+type igbinaryRef struct {
+	start int
+	end   int
+	done  bool
+}
+
+type igbinaryDecoder struct {
+	data    []byte
+	off     int
+	out     []byte
+	strings [][]byte
+	refs    []igbinaryRef
+}
+
+func (d *igbinaryDecoder) error(msg string) error {
+	return &SyntaxError{msg + ", offset: " + strconv.Itoa(d.off), int64(d.off)}
+}
+
+func (d *igbinaryDecoder) next() (byte, error) {
+
+	if d.off >= len(d.data) {
+		return 0, d.error("unexpected end of igbinary data")
+	}
+	d.off++
+	return d.data[d.off-1], nil
+
+}
+
+// uint reads an unsigned big endian integer of size bytes.
+func (d *igbinaryDecoder) uint(size int) (uint64, error) {
+
+	if d.off+size > len(d.data) {
+		return 0, d.error("unexpected end of igbinary data")
+	}
+	var n uint64
+	for _, c := range d.data[d.off : d.off+size] {
+		n = n<<8 | uint64(c)
+	}
+	d.off += size
+	return n, nil
+
+}
+
+// sized reads the size that follows tag, a tag8, tag16 or tag32 type.
+func (d *igbinaryDecoder) sized(tag, tag8 byte) (int, error) {
+
+	n, err := d.uint(1 << (tag - tag8))
+	if n > uint64(len(d.data)) && tag8 != igStringID8 {
+		return 0, d.error("igbinary length out of range")
+	}
+	return int(n), err
+
+}
+
+func (d *igbinaryDecoder) bytes(n int) ([]byte, error) {
+
+	if d.off+n > len(d.data) {
+		return nil, d.error("unexpected end of igbinary data")
+	}
+	d.off += n
+	return d.data[d.off-n : d.off], nil
+
+}
+
+func (d *igbinaryDecoder) writeLength(tag phpValueType, n int) {
+
+	d.out = append(d.out, byte(tag), phpSeparator)
+	d.out = strconv.AppendInt(d.out, int64(n), 10)
+	d.out = append(d.out, phpSeparator)
+
+}
+
+func (d *igbinaryDecoder) writeString(s []byte) {
+
+	d.writeLength(phpTypeString, len(s))
+	d.out = append(d.out, phpDoubleQuote)
+	d.out = append(d.out, s...)
+	d.out = append(d.out, phpDoubleQuote, phpTerminator)
+
+}
+
+func (d *igbinaryDecoder) value() error {
+
+	tag, err := d.next()
+	if err != nil {
+		return err
+	}
+
+	switch tag {
+	case igNull:
+		d.out = append(d.out, phpNullValue...)
+	case igFalse:
+		d.out = append(d.out, "b:0;"...)
+	case igTrue:
+		d.out = append(d.out, "b:1;"...)
+	case igLong8p, igLong8n, igLong16p, igLong16n, igLong32p, igLong32n, igLong64p, igLong64n:
+		return d.long(tag)
+	case igDouble:
+		bits, err := d.uint(8)
+		if err != nil {
+			return err
+		}
+		b, _ := Marshal(math.Float64frombits(bits))
+		d.out = append(d.out, b...)
+	case igStringEmpty, igStringID8, igStringID16, igStringID32, igString8, igString16, igString32:
+		s, err := d.string(tag)
+		if err != nil {
+			return err
+		}
+		d.writeString(s)
+	case igArray8, igArray16, igArray32:
+		n, err := d.sized(tag, igArray8)
+		if err != nil {
+			return err
+		}
+		id := d.beginRef()
+		d.writeLength(phpTypeArray, n)
+		if err = d.elements(n); err != nil {
+			return err
+		}
+		d.endRef(id)
+	case igObject8, igObject16, igObject32, igObjectID8, igObjectID16, igObjectID32:
+		return d.object(tag)
+	case igRef8, igRef16, igRef32, igObjRef8, igObjRef16, igObjRef32:
+		size := igRef8
+		if tag >= igObjRef8 {
+			size = igObjRef8
+		}
+		id, err := d.sized(tag, size)
+		if err != nil {
+			return err
+		}
+		if id >= len(d.refs) {
+			return d.error("igbinary reference out of range")
+		}
+		ref := d.refs[id]
+		if !ref.done {
+			return errIgbinaryRecursion
+		}
+		d.out = append(d.out, d.out[ref.start:ref.end]...)
+	case igRef:
+		// a php reference, arrays and objects get their own reference id
+		if d.off < len(d.data) && d.data[d.off] >= igArray8 && d.data[d.off] <= igObjectID32 {
+			return d.value()
+		}
+		id := d.beginRef()
+		if err := d.value(); err != nil {
+			return err
+		}
+		d.endRef(id)
+	default:
+		d.off--
+		return d.error("invalid igbinary type " + strconv.Itoa(int(tag)))
+	}
+	return nil
+
+}
+
+func (d *igbinaryDecoder) beginRef() int {
+
+	d.refs = append(d.refs, igbinaryRef{start: len(d.out)})
+	return len(d.refs) - 1
+
+}
+
+func (d *igbinaryDecoder) endRef(id int) {
+
+	d.refs[id].end = len(d.out)
+	d.refs[id].done = true
+
+}
+
+func (d *igbinaryDecoder) long(tag byte) error {
+
+	size := 1 << ((tag - igLong8p) / 2)
+	if tag >= igLong64p {
+		size = 8
+	}
+	u, err := d.uint(size)
+	if err != nil {
+		return err
+	}
+
+	d.out = append(d.out, byte(phpTypeInteger), phpSeparator)
+	if (tag-igLong8p)%2 == 1 {
+		if u > 1<<63 {
+			return d.error("igbinary integer out of range")
+		}
+		d.out = strconv.AppendInt(d.out, -int64(u), 10)
+	} else {
+		if u > math.MaxInt64 {
+			return d.error("igbinary integer out of range")
+		}
+		d.out = strconv.AppendUint(d.out, u, 10)
+	}
+	d.out = append(d.out, phpTerminator)
+	return nil
+
+}
+
+func (d *igbinaryDecoder) string(tag byte) ([]byte, error) {
+
+	switch tag {
+	case igStringEmpty:
+		return []byte{}, nil
+	case igStringID8, igStringID16, igStringID32:
+		id, err := d.sized(tag, igStringID8)
+		if err != nil {
+			return nil, err
+		}
+		if id >= len(d.strings) {
+			return nil, d.error("igbinary string id out of range")
+		}
+		return d.strings[id], nil
+	}
+
+	n, err := d.sized(tag, igString8)
+	if err != nil {
+		return nil, err
+	}
+	s, err := d.bytes(n)
+	if err != nil {
+		return nil, err
+	}
+	d.strings = append(d.strings, s)
+	return s, nil
+
+}
+
+func (d *igbinaryDecoder) elements(n int) error {
+
+	d.out = append(d.out, phpLeftBraces)
+	for i := 0; i < n; i++ {
+
+		tag, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case tag >= igLong8p && tag <= igLong32n, tag == igLong64p, tag == igLong64n:
+			err = d.long(tag)
+		case tag >= igStringEmpty && tag <= igString32:
+			var s []byte
+			if s, err = d.string(tag); err == nil {
+				d.writeString(s)
+			}
+		default:
+			d.off--
+			err = d.error("invalid igbinary array key type " + strconv.Itoa(int(tag)))
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = d.value(); err != nil {
+			return err
+		}
+
+	}
+	d.out = append(d.out, phpRightBraces)
+	return nil
+
+}
+
+func (d *igbinaryDecoder) object(tag byte) error {
+
+	var name []byte
+	if tag >= igObjectID8 {
+		id, err := d.sized(tag, igObjectID8)
+		if err != nil {
+			return err
+		}
+		if id >= len(d.strings) {
+			return d.error("igbinary string id out of range")
+		}
+		name = d.strings[id]
+	} else {
+		n, err := d.sized(tag, igObject8)
+		if err != nil {
+			return err
+		}
+		if name, err = d.bytes(n); err != nil {
+			return err
+		}
+		d.strings = append(d.strings, name)
+	}
+
+	id := d.beginRef()
+	tag, err := d.next()
+	if err != nil {
+		return err
+	}
+
+	switch tag {
+	case igArray8, igArray16, igArray32:
+		n, err := d.sized(tag, igArray8)
+		if err != nil {
+			return err
+		}
+		d.writeLength(phpTypeObject, len(name))
+		d.out = append(d.out, phpDoubleQuote)
+		d.out = append(d.out, name...)
+		d.out = append(d.out, phpDoubleQuote, phpSeparator)
+		d.out = strconv.AppendInt(d.out, int64(n), 10)
+		d.out = append(d.out, phpSeparator)
+		if err = d.elements(n); err != nil {
+			return err
+		}
+	case igObjectSer8, igObjectSer16, igObjectSer32:
+		n, err := d.sized(tag, igObjectSer8)
+		if err != nil {
+			return err
+		}
+		data, err := d.bytes(n)
+		if err != nil {
+			return err
+		}
+		d.writeLength(phpTypeCustom, len(name))
+		d.out = append(d.out, phpDoubleQuote)
+		d.out = append(d.out, name...)
+		d.out = append(d.out, phpDoubleQuote, phpSeparator)
+		d.out = strconv.AppendInt(d.out, int64(n), 10)
+		d.out = append(d.out, phpSeparator, phpLeftBraces)
+		d.out = append(d.out, data...)
+		d.out = append(d.out, phpRightBraces)
+	default:
+		d.off--
+		return d.error("invalid igbinary object data type " + strconv.Itoa(int(tag)))
+	}
+
+	d.endRef(id)
+	return nil
+
+}
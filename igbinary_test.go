@@ -0,0 +1,119 @@
+package phpserialize
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIgbinary(t *testing.T) {
+
+	testList := []struct {
+		serialized string
+		igbinary   string
+	}{
+		{`N;`, "\x00"},
+		{`b:1;`, "\x05"},
+		{`b:0;`, "\x04"},
+		{`i:5;`, "\x06\x05"},
+		{`i:-1;`, "\x07\x01"},
+		{`i:300;`, "\x08\x01\x2c"},
+		{`i:-70000;`, "\x0b\x00\x01\x11\x70"},
+		{`i:4294967296;`, "\x20\x00\x00\x00\x01\x00\x00\x00\x00"},
+		{`d:1.5;`, "\x0c\x3f\xf8\x00\x00\x00\x00\x00\x00"},
+		{`s:0:"";`, "\x0d"},
+		{`s:3:"foo";`, "\x11\x03foo"},
+		{`a:2:{i:0;i:1;i:1;i:2;}`, "\x14\x02\x06\x00\x06\x01\x06\x01\x06\x02"},
+		{`a:2:{s:1:"a";s:1:"a";s:1:"b";s:1:"a";}`, "\x14\x02\x11\x01a\x0e\x00\x11\x01b\x0e\x00"},
+		{`O:3:"Foo":1:{s:1:"a";i:1;}`, "\x17\x03Foo\x14\x01\x11\x01a\x06\x01"},
+		{`a:2:{i:0;O:3:"Foo":0:{}i:1;O:3:"Foo":0:{}}`, "\x14\x02\x06\x00\x17\x03Foo\x14\x00\x06\x01\x1a\x00\x14\x00"},
+		{`C:3:"Bar":4:{data}`, "\x17\x03Bar\x1d\x04data"},
+	}
+
+	for i, test := range testList {
+
+		data, err := SerializedToIgbinary([]byte(test.serialized))
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(data) != "\x00\x00\x00\x02"+test.igbinary {
+			t.Fatalf("Test fail at index %d, expect:%q got %q", i, test.igbinary, data[4:])
+		}
+
+		text, err := IgbinaryToSerialized(data)
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(text) != test.serialized {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.serialized, text)
+		}
+
+	}
+
+}
+
+func TestIgbinary_References(t *testing.T) {
+
+	testList := []struct {
+		igbinary   string
+		serialized string
+	}{
+		// $v = 1; [&$v, &$v]
+		{"\x14\x02\x06\x00\x25\x06\x01\x06\x01\x01\x01", `a:2:{i:0;i:1;i:1;i:1;}`},
+		// $o = new Foo; [$o, $o]
+		{"\x14\x02\x06\x00\x17\x03Foo\x14\x01\x11\x01a\x06\x01\x06\x01\x22\x01", `a:2:{i:0;O:3:"Foo":1:{s:1:"a";i:1;}i:1;O:3:"Foo":1:{s:1:"a";i:1;}}`},
+		// $a = [1]; [&$a, &$a]
+		{"\x14\x02\x06\x00\x25\x14\x01\x06\x00\x06\x01\x06\x01\x01\x01", `a:2:{i:0;a:1:{i:0;i:1;}i:1;a:1:{i:0;i:1;}}`},
+	}
+
+	for i, test := range testList {
+
+		text, err := IgbinaryToSerialized([]byte("\x00\x00\x00\x02" + test.igbinary))
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(text) != test.serialized {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.serialized, text)
+		}
+
+	}
+
+	// $a = []; $a[0] = &$a;
+	if _, err := IgbinaryToSerialized([]byte("\x00\x00\x00\x02\x14\x01\x06\x00\x01\x00")); err != errIgbinaryRecursion {
+		t.Fatalf("expect errIgbinaryRecursion got %v", err)
+	}
+
+	for _, data := range []string{"", "\x00\x00\x00\x09\x00", "\x00\x00\x00\x02\x11\x05ab", "\x00\x00\x00\x02\x0e\x00", "\x00\x00\x00\x02\x00\x00"} {
+		if _, err := IgbinaryToSerialized([]byte(data)); err == nil {
+			t.Fatalf("expect an error for %q", data)
+		}
+	}
+
+}
+
+func TestMarshalIgbinary(t *testing.T) {
+
+	type Item struct {
+		Name  string `php:"name"`
+		Price float64
+		Tags  []string `php:"tags,protected"`
+	}
+
+	in := map[string]Item{"a": {"apple", 1.25, []string{"red", "apple"}}}
+	data, err := MarshalIgbinary(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out map[string]Item
+	if err = UnmarshalIgbinary(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("expect:%v got %v", in, out)
+	}
+
+	if _, err = SerializedToIgbinary(nil); err == nil {
+		t.Fatal("expect an error for empty data")
+	}
+
+}
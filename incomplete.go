@@ -0,0 +1,147 @@
+package phpserialize
+
+import (
+	"reflect"
+)
+
+const (
+	incompleteClass     = "__PHP_Incomplete_Class"
+	incompleteClassName = "__PHP_Incomplete_Class_Name"
+)
+
+// IncompleteObject is an object of a class there is no Go type for, kept as
+// it was read so it is written back unchanged. Objects of
+// __PHP_Incomplete_Class take the class in __PHP_Incomplete_Class_Name and
+// are written with it, like php does. Objects and custom values decoded into
+// an interface{} are IncompleteObjects unless their class is stdClass, one of
+// the built-in classes or one OnUnknownClass returns a type for.
+type IncompleteObject struct {
+	Class string
+	// Properties are the properties of an O: object in order, names mangled
+	// for protected and private properties.
+	Properties []IncompleteProperty
+	// Custom is set for C: values, Data is their data.
+	Custom bool
+	Data   []byte
+}
+
+// IncompleteProperty is a property of an IncompleteObject, Key is a string
+// or, in the array of __serialize, an int64.
+type IncompleteProperty struct {
+	Key   interface{}
+	Value RawMessage
+}
+
+func (o IncompleteObject) MarshalPHP() ([]byte, error) {
+
+	class := orDefault(o.Class, "stdClass")
+	if o.Custom {
+		return appendContainer(nil, phpTypeCustom, class, len(o.Data), o.Data), nil
+	}
+
+	var body []byte
+	for _, p := range o.Properties {
+		switch key := p.Key.(type) {
+		case int64:
+			body = appendPHPInt(body, int(key))
+		case string:
+			body = appendPHPString(body, []byte(key))
+		default:
+			return nil, &UnsupportedTypeError{reflect.TypeOf(p.Key)}
+		}
+		if len(p.Value) == 0 {
+			body = append(body, phpNullValue...)
+			continue
+		}
+		body = append(body, p.Value...)
+	}
+	return appendContainer(nil, phpTypeObject, class, len(o.Properties), body), nil
+
+}
+
+func (o *IncompleteObject) UnmarshalPHP(data []byte) error {
+
+	class, c, err := parseObject(data, o)
+	if err != nil || c == nil {
+		return err
+	}
+	*o = IncompleteObject{Class: class}
+
+	if c.tag == phpTypeCustom {
+		o.Custom = true
+		o.Data = append([]byte(nil), c.payload(data)...)
+		return nil
+	}
+
+	for i, e := range c.entries {
+		value := append(RawMessage(nil), c.value(data, i)...)
+		if class == incompleteClass && e.key == incompleteClassName {
+			var name string
+			if Unmarshal(value, &name) == nil {
+				o.Class = name
+				continue
+			}
+		}
+		o.Properties = append(o.Properties, IncompleteProperty{e.key, value})
+	}
+	return nil
+
+}
+
+// Property returns the value of the property name, which is mangled for
+// protected and private properties.
+func (o *IncompleteObject) Property(name string) (RawMessage, bool) {
+
+	for _, p := range o.Properties {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return nil, false
+
+}
+
+// Decode decodes the object into v, once there is a Go type for its class.
+func (o IncompleteObject) Decode(v interface{}) error {
+
+	data, err := o.MarshalPHP()
+	if err != nil {
+		return err
+	}
+	return Unmarshal(data, v)
+
+}
+
+// unknownClass decodes the object or custom value of class into the type the
+// OnUnknownClass hook returns for it, it reports false when there is none.
+func (d *decodeState) unknownClass(class string, start int) (interface{}, bool) {
+
+	if d.onUnknownClass == nil {
+		return nil, false
+	}
+	t, err := d.onUnknownClass(class)
+	if err == nil && t == nil {
+		return nil, false
+	}
+
+	d.skip()
+	data := d.data[start:d.off]
+	if err != nil {
+		d.saveError(err)
+		var o IncompleteObject
+		if err = o.UnmarshalPHP(data); err != nil {
+			d.saveError(err)
+		}
+		return o, true
+	}
+
+	v := reflect.New(t)
+	var sub decodeState
+	sub.init(data)
+	sub.onUnknownClass = d.onUnknownClass
+	if err = sub.unmarshal(v.Interface()); err != nil {
+		d.saveError(err)
+	}
+	return v.Elem().Interface(), true
+
+}
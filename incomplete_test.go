@@ -0,0 +1,131 @@
+package phpserialize
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIncompleteObject(t *testing.T) {
+
+	testList := []string{
+		`O:3:"Foo":3:{s:1:"a";i:1;s:4:"` + "\x00*\x00" + `b";O:8:"stdClass":0:{}s:6:"` + "\x00Foo\x00" + `c";a:1:{s:1:"x";b:1;}}`,
+		`O:5:"Money":2:{i:0;i:100;i:1;s:3:"EUR";}`,
+		`C:3:"Foo":3:{abc}`,
+		`N;`,
+	}
+
+	for i, test := range testList {
+
+		var o IncompleteObject
+		if err := Unmarshal([]byte(test), &o); err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if test == phpNullValue {
+			continue
+		}
+		data, err := Marshal(o)
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(data) != test {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test, data)
+		}
+
+	}
+
+	var o IncompleteObject
+	incomplete := `O:22:"__PHP_Incomplete_Class":2:{s:27:"__PHP_Incomplete_Class_Name";s:3:"Job";s:4:"name";s:1:"a";}`
+	if err := Unmarshal([]byte(incomplete), &o); err != nil {
+		t.Fatal(err)
+	}
+	if name, ok := o.Property("name"); o.Class != "Job" || !ok || string(name) != `s:1:"a";` {
+		t.Fatalf("unexpected value %+v", o)
+	}
+	data, _ := Marshal(o)
+	if expect := `O:3:"Job":1:{s:4:"name";s:1:"a";}`; string(data) != expect {
+		t.Fatalf("expect:%s got %s", expect, data)
+	}
+	var job testVisibility
+	if err := o.Decode(&job); err != nil || job.Name != "a" {
+		t.Fatalf("unexpected value %+v %v", job, err)
+	}
+
+	var v []interface{}
+	if err := Unmarshal([]byte(`a:2:{i:0;C:3:"Foo":3:{abc}i:1;`+incomplete+`}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := v[0].(IncompleteObject); !ok || c.Class != "Foo" || string(c.Data) != "abc" {
+		t.Fatalf("unexpected value %#v", v[0])
+	}
+	if c, ok := v[1].(IncompleteObject); !ok || c.Class != "Job" {
+		t.Fatalf("unexpected value %#v", v[1])
+	}
+
+	// without a Go type the class is kept, stdClass objects are maps
+	test := `a:2:{i:0;O:3:"Job":1:{s:4:"name";s:1:"a";}i:1;O:8:"stdClass":1:{s:1:"x";i:1;}}`
+	if err := Unmarshal([]byte(test), &v); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v[1].(map[interface{}]interface{}); !ok {
+		t.Fatalf("unexpected value %#v", v[1])
+	}
+	if data, err := Marshal(v[0]); err != nil || string(data) != `O:3:"Job":1:{s:4:"name";s:1:"a";}` {
+		t.Fatalf("unexpected value %s %v", data, err)
+	}
+
+	// the placeholder class is not written back
+	var value interface{}
+	if err := Unmarshal([]byte(incomplete), &value); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := Marshal(value); err != nil || string(data) != `O:3:"Job":1:{s:4:"name";s:1:"a";}` {
+		t.Fatalf("unexpected value %s %v", data, err)
+	}
+
+}
+
+func TestDecoder_OnUnknownClass(t *testing.T) {
+
+	dec := NewDecoder(strings.NewReader(`a:3:{i:0;O:3:"Job":1:{s:4:"name";s:1:"a";}i:1;O:3:"Bar":1:{s:1:"x";i:1;}i:2;C:5:"test1":3:{abc}}`))
+	var asked []string
+	dec.OnUnknownClass(func(class string) (reflect.Type, error) {
+		asked = append(asked, class)
+		switch class {
+		case "Job":
+			return reflect.TypeOf(testVisibility{}), nil
+		case "test1":
+			return reflect.TypeOf(&custom{}), nil
+		}
+		return nil, nil
+	})
+
+	var v []interface{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(asked, []string{"Job", "Bar", "test1"}) {
+		t.Fatalf("unexpected classes %v", asked)
+	}
+	if job, ok := v[0].(testVisibility); !ok || job.Name != "a" {
+		t.Fatalf("unexpected value %#v", v[0])
+	}
+	if o, ok := v[1].(IncompleteObject); !ok || o.Class != "Bar" {
+		t.Fatalf("unexpected value %#v", v[1])
+	}
+	if c, ok := v[2].(*custom); !ok || c.data != "abc" {
+		t.Fatalf("unexpected value %#v", v[2])
+	}
+
+	errUnknown := errors.New("unknown class")
+	dec = NewDecoder(strings.NewReader(`O:3:"Bar":0:{}`))
+	dec.OnUnknownClass(func(class string) (reflect.Type, error) {
+		return nil, errUnknown
+	})
+	var value interface{}
+	if err := dec.Decode(&value); err != errUnknown {
+		t.Fatalf("expect %v got %v", errUnknown, err)
+	}
+
+}
@@ -0,0 +1,902 @@
+package phpserialize
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+type JSONObjects int
+
+const (
+	// JSONObjectInline writes objects as {"__class": "Foo", "prop": ...}
+	JSONObjectInline JSONObjects = iota
+	// JSONObjectNested writes objects as {"__class": "Foo", "__data": {"prop": ...}}
+	JSONObjectNested
+	// JSONObjectPlain writes only the properties of objects
+	JSONObjectPlain
+)
+
+type JSONStrings int
+
+const (
+	// JSONStringReplace replaces invalid UTF-8 with U+FFFD, like encoding/json
+	JSONStringReplace JSONStrings = iota
+	// JSONStringEscape writes every invalid byte as the code point of the
+	// same value, \u0080 to ÿ
+	JSONStringEscape
+	// JSONStringBase64 writes strings that are not UTF-8 as
+	// {"__base64": "..."}, array keys are escaped
+	JSONStringBase64
+)
+
+// JSONOptions controls how ToJSON writes what JSON has no equivalent for,
+// the zero value writes the default shapes. FromJSON reads the class, data
+// and base64 shapes back into objects, custom values and binary strings.
+type JSONOptions struct {
+	Objects JSONObjects
+	Strings JSONStrings
+
+	ClassKey  string // "__class" when empty
+	DataKey   string // "__data" when empty, also holds the data of custom values
+	Base64Key string // "__base64" when empty
+
+	// ArraysAsObjects writes every array as a JSON object. By default arrays
+	// with the keys 0..n-1 in order are written as lists, which needs an
+	// array to be held back until its last key has been read.
+	ArraysAsObjects bool
+	// MangledProperties keeps the "\u0000*\u0000" and "\u0000Class\u0000"
+	// prefixes of protected and private property names.
+	MangledProperties bool
+	// NonFiniteNull writes INF, -INF and NAN as null instead of the strings
+	// "INF", "-INF" and "NAN".
+	NonFiniteNull bool
+}
+
+func (o *JSONOptions) classKey() string {
+	return orDefault(o.ClassKey, "__class")
+}
+
+func (o *JSONOptions) dataKey() string {
+	return orDefault(o.DataKey, "__data")
+}
+
+func (o *JSONOptions) base64Key() string {
+	return orDefault(o.Base64Key, "__base64")
+}
+
+func orDefault(s, def string) string {
+
+	if s == "" {
+		return def
+	}
+	return s
+
+}
+
+// ToJSON converts the serialized values read from r to JSON with the
+// default options, one value per line.
+func ToJSON(w io.Writer, r io.Reader) error {
+	return (&JSONOptions{}).ToJSON(w, r)
+}
+
+// FromJSON converts the JSON values read from r to serialized values
+// following the rules of php's json_decode($json, true).
+func FromJSON(w io.Writer, r io.Reader) error {
+	return (&JSONOptions{}).FromJSON(w, r)
+}
+
+// ToJSON converts the serialized values read from r to JSON, one value per
+// line. Values are read and written token by token, references are written
+// as null.
+func (o *JSONOptions) ToJSON(w io.Writer, r io.Reader) error {
+
+	t := toJSONState{opts: o, w: w, in: phpReader{r: bufio.NewReader(r)}}
+	for {
+
+		c, err := t.in.r.Peek(1)
+		if err == io.EOF {
+			return nil
+		}
+		if err == nil && bytes.IndexByte([]byte(" \t\r\n"), c[0]) >= 0 {
+			t.in.r.ReadByte()
+			t.in.off++
+			continue
+		}
+		if err := t.value(); err != nil {
+			return err
+		}
+		t.buf = append(t.buf, '\n')
+		if err := t.flush(); err != nil {
+			return err
+		}
+
+	}
+
+}
+
+type phpReader struct {
+	r   *bufio.Reader
+	off int64
+}
+
+func (p *phpReader) readByte() (byte, error) {
+
+	c, err := p.r.ReadByte()
+	if err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if err == nil {
+		p.off++
+	}
+	return c, err
+
+}
+
+func (p *phpReader) error(c byte, context string) error {
+	return &SyntaxError{"invalid character " + quoteChar(c) + " " + context + ", offset: " + strconv.FormatInt(p.off-1, 10), p.off - 1}
+}
+
+func (p *phpReader) expect(want byte) error {
+
+	c, err := p.readByte()
+	if err != nil {
+		return err
+	}
+	if c != want {
+		return p.error(c, ", expect "+quoteChar(want))
+	}
+	return nil
+
+}
+
+// until reads a short value up to delim and drops the delim.
+func (p *phpReader) until(delim byte) ([]byte, error) {
+
+	var b []byte
+	for len(b) < 64 {
+		c, err := p.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if c == delim {
+			return b, nil
+		}
+		b = append(b, c)
+	}
+	return nil, p.error(b[len(b)-1], "in scalar value")
+
+}
+
+// length reads a value length followed by delim.
+func (p *phpReader) length(delim byte) (int, error) {
+
+	n, digits := 0, 0
+	for {
+		c, err := p.readByte()
+		if err != nil {
+			return 0, err
+		}
+		if c == delim && digits > 0 {
+			return n, nil
+		}
+		if c < '0' || c > '9' || digits >= 10 {
+			return 0, p.error(c, "in value length")
+		}
+		n = n*10 + int(c-'0')
+		digits++
+	}
+
+}
+
+// bytes reads n bytes, the buffer grows with the data actually read.
+func (p *phpReader) bytes(n int) ([]byte, error) {
+
+	var b bytes.Buffer
+	m, err := io.CopyN(&b, p.r, int64(n))
+	p.off += m
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return b.Bytes(), err
+
+}
+
+// quoted reads "data" of length n.
+func (p *phpReader) quoted(n int) ([]byte, error) {
+
+	if err := p.expect(phpDoubleQuote); err != nil {
+		return nil, err
+	}
+	b, err := p.bytes(n)
+	if err != nil {
+		return nil, err
+	}
+	return b, p.expect(phpDoubleQuote)
+
+}
+
+type jsonFrame struct {
+	start int
+	list  bool
+	next  int64
+	keys  []int // start and end of the written keys while list is set
+}
+
+type toJSONState struct {
+	opts   *JSONOptions
+	w      io.Writer
+	in     phpReader
+	buf    []byte
+	frames []jsonFrame
+	lists  int // frames that may still be a list
+}
+
+func (t *toJSONState) flush() error {
+
+	if t.lists > 0 {
+		return nil
+	}
+	_, err := t.w.Write(t.buf)
+	t.buf = t.buf[:0]
+	return err
+
+}
+
+func (t *toJSONState) value() error {
+
+	tag, err := t.in.readByte()
+	if err != nil {
+		return err
+	}
+	if phpValueType(tag) == phpTypeNull {
+		if err = t.in.expect(phpTerminator); err == nil {
+			t.buf = append(t.buf, "null"...)
+		}
+		return err
+	}
+	if err = t.in.expect(phpSeparator); err != nil {
+		return err
+	}
+
+	switch phpValueType(tag) {
+	case phpTypeBoolean:
+		b, err := t.in.until(phpTerminator)
+		if err != nil {
+			return err
+		}
+		switch string(b) {
+		case "0":
+			t.buf = append(t.buf, "false"...)
+		case "1":
+			t.buf = append(t.buf, "true"...)
+		default:
+			return t.in.error(phpTerminator, "in bool value")
+		}
+	case phpTypeInteger:
+		b, err := t.in.until(phpTerminator)
+		if err != nil {
+			return err
+		}
+		if _, err = strconv.ParseInt(string(b), 10, 64); err != nil {
+			return t.in.error(phpTerminator, "in int value")
+		}
+		t.buf = append(t.buf, b...)
+	case phpTypeFloat:
+		b, err := t.in.until(phpTerminator)
+		if err != nil {
+			return err
+		}
+		return t.float(b)
+	case phpTypeString:
+		n, err := t.in.length(phpSeparator)
+		if err != nil {
+			return err
+		}
+		s, err := t.in.quoted(n)
+		if err != nil {
+			return err
+		}
+		t.string(s, false)
+		return t.in.expect(phpTerminator)
+	case phpTypeArray:
+		n, err := t.in.length(phpSeparator)
+		if err != nil {
+			return err
+		}
+		if err = t.in.expect(phpLeftBraces); err != nil {
+			return err
+		}
+		return t.array(n)
+	case phpTypeObject, phpTypeCustom:
+		n, err := t.in.length(phpSeparator)
+		if err != nil {
+			return err
+		}
+		name, err := t.in.quoted(n)
+		if err != nil {
+			return err
+		}
+		if err = t.in.expect(phpSeparator); err != nil {
+			return err
+		}
+		if n, err = t.in.length(phpSeparator); err != nil {
+			return err
+		}
+		if err = t.in.expect(phpLeftBraces); err != nil {
+			return err
+		}
+		if phpValueType(tag) == phpTypeObject {
+			return t.object(name, n)
+		}
+		return t.custom(name, n)
+	case phpTypeReference, phpTypeReferenceObject:
+		if _, err := t.in.length(phpTerminator); err != nil {
+			return err
+		}
+		t.buf = append(t.buf, "null"...)
+	default:
+		return t.in.error(tag, "of php type identifier")
+	}
+	return nil
+
+}
+
+func (t *toJSONState) float(b []byte) error {
+
+	switch string(b) {
+	case "INF", "-INF", "NAN":
+		t.nonFinite(b)
+		return nil
+	}
+
+	// only the grammar the scanner accepts, strconv also knows inf, nan and hex
+	for _, c := range b {
+		if (c < '0' || c > '9') && c != '-' && c != '+' && c != '.' && c != 'E' && c != 'e' {
+			return t.in.error(phpTerminator, "in float value")
+		}
+	}
+	f, err := strconv.ParseFloat(string(b), 64)
+	if math.IsInf(f, 0) {
+		//overflow, php reads it as INF as well
+		if f < 0 {
+			t.nonFinite([]byte("-INF"))
+		} else {
+			t.nonFinite([]byte("INF"))
+		}
+		return nil
+	}
+	if err != nil {
+		return t.in.error(phpTerminator, "in float value")
+	}
+
+	// php writes floats like 1.0E+25, which is a JSON number as well
+	if number, err := json.Marshal(json.Number(b)); err == nil {
+		t.buf = append(t.buf, number...)
+	} else {
+		t.buf = strconv.AppendFloat(t.buf, f, 'g', -1, 64)
+	}
+	return nil
+
+}
+
+func (t *toJSONState) nonFinite(name []byte) {
+
+	if t.opts.NonFiniteNull {
+		t.buf = append(t.buf, "null"...)
+		return
+	}
+	t.buf = append(t.buf, '"')
+	t.buf = append(t.buf, name...)
+	t.buf = append(t.buf, '"')
+
+}
+
+func (t *toJSONState) string(s []byte, key bool) {
+
+	if t.opts.Strings == JSONStringBase64 && !key && !utf8.Valid(s) {
+		t.buf = append(t.buf, '{')
+		t.buf = appendJSONString(t.buf, []byte(t.opts.base64Key()), JSONStringReplace)
+		t.buf = append(t.buf, ':', '"')
+		t.buf = append(t.buf, base64.StdEncoding.EncodeToString(s)...)
+		t.buf = append(t.buf, '"', '}')
+		return
+	}
+	t.buf = appendJSONString(t.buf, s, t.opts.Strings)
+
+}
+
+func (t *toJSONState) open(list bool) {
+
+	t.frames = append(t.frames, jsonFrame{start: len(t.buf), list: list})
+	if list {
+		t.lists++
+	}
+	t.buf = append(t.buf, '{')
+
+}
+
+func (t *toJSONState) notList(f *jsonFrame) {
+
+	if f.list {
+		f.list, f.keys = false, nil
+		t.lists--
+	}
+
+}
+
+func (t *toJSONState) close() error {
+
+	f := &t.frames[len(t.frames)-1]
+	if f.list {
+
+		// drop the keys, the array is a list
+		out := f.start + 1
+		from := f.start + 1
+		for i := 0; i < len(f.keys); i += 2 {
+			out += copy(t.buf[out:], t.buf[from:f.keys[i]])
+			from = f.keys[i+1]
+		}
+		out += copy(t.buf[out:], t.buf[from:])
+		t.buf = t.buf[:out]
+		t.buf[f.start] = '['
+		t.buf = append(t.buf, ']')
+		t.lists--
+
+	} else {
+		t.buf = append(t.buf, '}')
+	}
+
+	t.frames = t.frames[:len(t.frames)-1]
+	if len(t.buf) > 1<<15 {
+		return t.flush()
+	}
+	return nil
+
+}
+
+// key reads an array key or property name and writes it as an object key.
+func (t *toJSONState) key(first bool, property bool) error {
+
+	f := &t.frames[len(t.frames)-1]
+	if !first {
+		t.buf = append(t.buf, ',')
+	}
+	start := len(t.buf)
+
+	tag, err := t.in.readByte()
+	if err != nil {
+		return err
+	}
+	if err = t.in.expect(phpSeparator); err != nil {
+		return err
+	}
+
+	switch phpValueType(tag) {
+	case phpTypeInteger:
+		b, err := t.in.until(phpTerminator)
+		if err != nil {
+			return err
+		}
+		n, err := strconv.ParseInt(string(b), 10, 64)
+		if err != nil {
+			return t.in.error(phpTerminator, "in int value")
+		}
+		if f.list && n != f.next {
+			t.notList(f)
+		}
+		f.next++
+		t.buf = append(t.buf, '"')
+		t.buf = append(t.buf, b...)
+		t.buf = append(t.buf, '"')
+	case phpTypeString:
+		t.notList(f)
+		n, err := t.in.length(phpSeparator)
+		if err != nil {
+			return err
+		}
+		s, err := t.in.quoted(n)
+		if err != nil {
+			return err
+		}
+		if err = t.in.expect(phpTerminator); err != nil {
+			return err
+		}
+		if property && !t.opts.MangledProperties {
+			name, _ := unmangleProperty(string(s))
+			s = []byte(name)
+		}
+		t.string(s, true)
+	default:
+		return t.in.error(tag, "in array key")
+	}
+
+	t.buf = append(t.buf, ':')
+	if f.list {
+		f.keys = append(f.keys, start, len(t.buf))
+	}
+	return nil
+
+}
+
+func (t *toJSONState) entries(n int, property bool, first bool) error {
+
+	for i := 0; i < n; i++ {
+		if err := t.key(first && i == 0, property); err != nil {
+			return err
+		}
+		if err := t.value(); err != nil {
+			return err
+		}
+	}
+	return t.in.expect(phpRightBraces)
+
+}
+
+func (t *toJSONState) array(n int) error {
+
+	if n == 0 {
+		if t.opts.ArraysAsObjects {
+			t.buf = append(t.buf, "{}"...)
+		} else {
+			t.buf = append(t.buf, "[]"...)
+		}
+		return t.in.expect(phpRightBraces)
+	}
+
+	t.open(!t.opts.ArraysAsObjects)
+	if err := t.entries(n, false, true); err != nil {
+		return err
+	}
+	return t.close()
+
+}
+
+// classHeader writes the class name of an object or custom value and opens
+// the object holding its data in the nested shape.
+func (t *toJSONState) classHeader(name []byte, dataKey bool) bool {
+
+	if t.opts.Objects == JSONObjectPlain {
+		return true
+	}
+	t.buf = appendJSONString(t.buf, []byte(t.opts.classKey()), JSONStringReplace)
+	t.buf = append(t.buf, ':')
+	t.string(name, true)
+	if dataKey {
+		t.buf = append(t.buf, ',')
+		t.buf = appendJSONString(t.buf, []byte(t.opts.dataKey()), JSONStringReplace)
+		t.buf = append(t.buf, ':')
+	}
+	return false
+
+}
+
+func (t *toJSONState) object(name []byte, n int) error {
+
+	t.open(false)
+	nested := t.opts.Objects == JSONObjectNested
+	first := t.classHeader(name, nested)
+	if nested {
+		t.buf = append(t.buf, '{')
+		first = true
+	}
+
+	if err := t.entries(n, true, first); err != nil {
+		return err
+	}
+	if nested {
+		t.buf = append(t.buf, '}')
+	}
+	return t.close()
+
+}
+
+func (t *toJSONState) custom(name []byte, n int) error {
+
+	data, err := t.in.bytes(n)
+	if err != nil {
+		return err
+	}
+	if err = t.in.expect(phpRightBraces); err != nil {
+		return err
+	}
+
+	if t.opts.Objects == JSONObjectPlain {
+		t.string(data, false)
+		return nil
+	}
+	t.buf = append(t.buf, '{')
+	t.classHeader(name, true)
+	t.string(data, false)
+	t.buf = append(t.buf, '}')
+	return nil
+
+}
+
+const hex = "0123456789abcdef"
+
+func appendJSONString(buf []byte, s []byte, mode JSONStrings) []byte {
+
+	buf = append(buf, '"')
+	for i := 0; i < len(s); {
+
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				buf = append(buf, '\\', c)
+			case c == '\n':
+				buf = append(buf, '\\', 'n')
+			case c == '\r':
+				buf = append(buf, '\\', 'r')
+			case c == '\t':
+				buf = append(buf, '\\', 't')
+			case c < 0x20:
+				buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				buf = append(buf, c)
+			}
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRune(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1 && mode == JSONStringEscape:
+			buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		case r == utf8.RuneError && size == 1:
+			buf = append(buf, `\ufffd`...)
+		case r == '\u2028' || r == '\u2029':
+			buf = append(buf, '\\', 'u', '2', '0', '2', hex[r&0xf])
+		default:
+			buf = append(buf, s[i:i+size]...)
+		}
+		i += size
+
+	}
+	return append(buf, '"')
+
+}
+
+// FromJSON converts the JSON values read from r to serialized values, which
+// are written one after the other. Objects become arrays, with numeric keys
+// as integers, unless they have the class key of o first.
+func (o *JSONOptions) FromJSON(w io.Writer, r io.Reader) error {
+
+	f := fromJSONState{opts: o, dec: json.NewDecoder(r)}
+	f.dec.UseNumber()
+
+	var buf []byte
+	for {
+
+		tok, err := f.dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if buf, err = f.value(buf[:0], tok); err != nil {
+			return err
+		}
+		if _, err = w.Write(buf); err != nil {
+			return err
+		}
+
+	}
+
+}
+
+type fromJSONState struct {
+	opts *JSONOptions
+	dec  *json.Decoder
+}
+
+func (f *fromJSONState) value(buf []byte, tok json.Token) ([]byte, error) {
+
+	switch v := tok.(type) {
+	case nil:
+		return append(buf, phpNullValue...), nil
+	case bool:
+		if v {
+			return append(buf, "b:1;"...), nil
+		}
+		return append(buf, "b:0;"...), nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			buf = append(buf, byte(phpTypeInteger), phpSeparator)
+			buf = strconv.AppendInt(buf, n, 10)
+			return append(buf, phpTerminator), nil
+		}
+		// like json_decode, integers out of range become floats
+		n, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		b, err := Marshal(n)
+		return append(buf, b...), err
+	case string:
+		return appendPHPString(buf, []byte(v)), nil
+	case json.Delim:
+		if v == '[' {
+			return f.list(buf)
+		}
+		return f.object(buf)
+	}
+	return nil, &SyntaxError{"unexpected JSON token", f.dec.InputOffset()}
+
+}
+
+func appendPHPString(buf []byte, s []byte) []byte {
+
+	buf = append(buf, byte(phpTypeString), phpSeparator)
+	buf = strconv.AppendInt(buf, int64(len(s)), 10)
+	buf = append(buf, phpSeparator, phpDoubleQuote)
+	buf = append(buf, s...)
+	return append(buf, phpDoubleQuote, phpTerminator)
+
+}
+
+func appendContainer(buf []byte, tag phpValueType, name string, n int, body []byte) []byte {
+
+	buf = append(buf, byte(tag), phpSeparator)
+	if tag != phpTypeArray {
+		buf = strconv.AppendInt(buf, int64(len(name)), 10)
+		buf = append(buf, phpSeparator, phpDoubleQuote)
+		buf = append(buf, name...)
+		buf = append(buf, phpDoubleQuote, phpSeparator)
+	}
+	buf = strconv.AppendInt(buf, int64(n), 10)
+	buf = append(buf, phpSeparator, phpLeftBraces)
+	buf = append(buf, body...)
+	return append(buf, phpRightBraces)
+
+}
+
+func (f *fromJSONState) list(buf []byte) ([]byte, error) {
+
+	var body []byte
+	n := 0
+	for f.dec.More() {
+
+		tok, err := f.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, byte(phpTypeInteger), phpSeparator)
+		body = strconv.AppendInt(body, int64(n), 10)
+		body = append(body, phpTerminator)
+		if body, err = f.value(body, tok); err != nil {
+			return nil, err
+		}
+		n++
+
+	}
+	if _, err := f.dec.Token(); err != nil { //skip ]
+		return nil, err
+	}
+	return appendContainer(buf, phpTypeArray, "", n, body), nil
+
+}
+
+// member reads the key and the first token of the value of an object member.
+func (f *fromJSONState) member() (string, json.Token, error) {
+
+	tok, err := f.dec.Token()
+	if err != nil {
+		return "", nil, err
+	}
+	key, _ := tok.(string)
+	tok, err = f.dec.Token()
+	return key, tok, err
+
+}
+
+func (f *fromJSONState) entry(body []byte, key string, tok json.Token, property bool) ([]byte, error) {
+
+	if n, ok := phpIntKey(key); ok && !property {
+		body = append(body, byte(phpTypeInteger), phpSeparator)
+		body = strconv.AppendInt(body, n, 10)
+		body = append(body, phpTerminator)
+	} else {
+		body = appendPHPString(body, []byte(key))
+	}
+	return f.value(body, tok)
+
+}
+
+// members reads the rest of the members of an object and its closing '}'.
+func (f *fromJSONState) members(body []byte, n int, property bool) ([]byte, int, error) {
+
+	for f.dec.More() {
+		key, tok, err := f.member()
+		if err != nil {
+			return nil, 0, err
+		}
+		if body, err = f.entry(body, key, tok, property); err != nil {
+			return nil, 0, err
+		}
+		n++
+	}
+	_, err := f.dec.Token() //skip }
+	return body, n, err
+
+}
+
+func (f *fromJSONState) object(buf []byte) ([]byte, error) {
+
+	var body []byte
+	var err error
+	n := 0
+	class, object := "", false
+
+	if f.dec.More() {
+
+		key, tok, err := f.member()
+		if err != nil {
+			return nil, err
+		}
+		s, isString := tok.(string)
+
+		switch {
+		case key == f.opts.base64Key() && isString && !f.dec.More():
+			data, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, err
+			}
+			if _, err = f.dec.Token(); err != nil { //skip }
+				return nil, err
+			}
+			return appendPHPString(buf, data), nil
+		case key == f.opts.classKey() && isString:
+			class, object = s, true
+		default:
+			if body, err = f.entry(body, key, tok, false); err != nil {
+				return nil, err
+			}
+			n++
+		}
+
+	}
+
+	if object && f.dec.More() {
+
+		key, tok, err := f.member()
+		if err != nil {
+			return nil, err
+		}
+		s, isString := tok.(string)
+
+		switch {
+		case key == f.opts.dataKey() && isString && !f.dec.More():
+			if _, err = f.dec.Token(); err != nil { //skip }
+				return nil, err
+			}
+			return appendContainer(buf, phpTypeCustom, class, len(s), []byte(s)), nil
+		case key == f.opts.dataKey() && tok == json.Delim('{'):
+			if body, n, err = f.members(body, n, true); err != nil {
+				return nil, err
+			}
+		default:
+			if body, err = f.entry(body, key, tok, true); err != nil {
+				return nil, err
+			}
+			n++
+		}
+
+	}
+
+	if body, n, err = f.members(body, n, object); err != nil {
+		return nil, err
+	}
+	if object {
+		return appendContainer(buf, phpTypeObject, class, n, body), nil
+	}
+	return appendContainer(buf, phpTypeArray, "", n, body), nil
+
+}
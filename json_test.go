@@ -0,0 +1,159 @@
+package phpserialize
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+
+	testList := []struct {
+		opts   JSONOptions
+		data   string
+		expect string
+	}{
+		{JSONOptions{}, `N;b:1;i:-5;d:0.5;s:3:"a"b";`, "null\ntrue\n-5\n0.5\n\"a\\\"b\"\n"},
+		{JSONOptions{}, `a:0:{}`, "[]\n"},
+		{JSONOptions{}, `a:2:{i:0;s:1:"a";i:1;a:2:{i:0;i:1;i:1;i:2;}}`, "[\"a\",[1,2]]\n"},
+		{JSONOptions{}, `a:2:{i:1;s:1:"a";i:0;a:1:{i:0;i:1;}}`, "{\"1\":\"a\",\"0\":[1]}\n"},
+		{JSONOptions{}, `a:2:{i:0;a:1:{s:1:"k";i:1;}s:1:"x";a:1:{i:0;b:0;}}`, "{\"0\":{\"k\":1},\"x\":[false]}\n"},
+		{JSONOptions{ArraysAsObjects: true}, `a:1:{i:0;i:1;}`, "{\"0\":1}\n"},
+		{JSONOptions{}, `O:3:"Foo":2:{s:1:"a";i:1;s:4:"` + "\x00*\x00b" + `";a:0:{}}`, "{\"__class\":\"Foo\",\"a\":1,\"b\":[]}\n"},
+		{JSONOptions{MangledProperties: true}, `O:3:"Foo":1:{s:6:"` + "\x00Foo\x00c" + `";N;}`, "{\"__class\":\"Foo\",\"\\u0000Foo\\u0000c\":null}\n"},
+		{JSONOptions{Objects: JSONObjectNested, ClassKey: "class", DataKey: "data"}, `O:3:"Foo":1:{s:1:"a";i:1;}`, "{\"class\":\"Foo\",\"data\":{\"a\":1}}\n"},
+		{JSONOptions{Objects: JSONObjectPlain}, `O:3:"Foo":1:{s:1:"a";i:1;}`, "{\"a\":1}\n"},
+		{JSONOptions{}, `C:3:"Bar":4:{data}`, "{\"__class\":\"Bar\",\"__data\":\"data\"}\n"},
+		{JSONOptions{}, "s:2:\"\xff\n\";", "\"\\ufffd\\n\"\n"},
+		{JSONOptions{Strings: JSONStringEscape}, "s:1:\"\xe9\";", "\"\\u00e9\"\n"},
+		{JSONOptions{Strings: JSONStringBase64}, "s:1:\"\xe9\";", "{\"__base64\":\"6Q==\"}\n"},
+		{JSONOptions{}, `d:INF;d:-INF;d:NAN;d:1.0E+25;`, "\"INF\"\n\"-INF\"\n\"NAN\"\n1.0E+25\n"},
+		{JSONOptions{NonFiniteNull: true}, "d:NAN;\n", "null\n"},
+		{JSONOptions{}, `d:1e999;d:-1e999;d:.5;`, "\"INF\"\n\"-INF\"\n0.5\n"},
+		{JSONOptions{NonFiniteNull: true}, `d:1e999;`, "null\n"},
+	}
+
+	for i, test := range testList {
+
+		var out bytes.Buffer
+		if err := test.opts.ToJSON(&out, strings.NewReader(test.data)); err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if out.String() != test.expect {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.expect, out.String())
+		}
+
+	}
+
+	for _, data := range []string{`a:1:{i:0;}`, `s:5:"abc";`, `i:x;`, `X:1;`, `a:1:{d:1;i:1;}`, `d:inf;`, `d:+Inf;`, `d:infinity;`, `d:nan;`, `d:0x1p3;`} {
+		if err := ToJSON(&bytes.Buffer{}, strings.NewReader(data)); err == nil {
+			t.Fatalf("expect an error for %s", data)
+		}
+	}
+
+}
+
+func TestToJSON_Large(t *testing.T) {
+
+	// flushed while writing, the list stays a list
+	var data bytes.Buffer
+	data.WriteString("a:3:{i:0;a:5000:{")
+	for i := 0; i < 5000; i++ {
+		data.WriteString(`i:` + string(rune('0'+i%10)) + `;s:10:"0123456789";`)
+	}
+	data.WriteString(`}i:1;i:1;i:2;a:1:{i:0;i:1;}}`)
+
+	var out bytes.Buffer
+	if err := ToJSON(&out, &data); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), `[{"0":"0123456789","1"`) || !strings.HasSuffix(out.String(), `"},1,[1]]`+"\n") {
+		t.Fatalf("unexpected json %.40s...%s", out.String(), out.String()[out.Len()-40:])
+	}
+
+}
+
+func TestFromJSON(t *testing.T) {
+
+	testList := []struct {
+		json   string
+		expect string
+	}{
+		{`null true 5 0.5 "a"`, `N;b:1;i:5;d:0.5;s:1:"a";`},
+		{`["a", [1]]`, `a:2:{i:0;s:1:"a";i:1;a:1:{i:0;i:1;}}`},
+		{`{"1": "a", "x": {}, "01": []}`, `a:3:{i:1;s:1:"a";s:1:"x";a:0:{}s:2:"01";a:0:{}}`},
+		{`{"__class": "Foo", "a": 1, "1": 2}`, `O:3:"Foo":2:{s:1:"a";i:1;s:1:"1";i:2;}`},
+		{`{"__class": "Foo", "__data": {"a": 1}}`, `O:3:"Foo":1:{s:1:"a";i:1;}`},
+		{`{"__class": "Bar", "__data": "data"}`, `C:3:"Bar":4:{data}`},
+		{`{"__base64": "6Q=="}`, "s:1:\"\xe9\";"},
+		{`{"__base64": "6Q==", "b": 1}`, `a:2:{s:8:"__base64";s:4:"6Q==";s:1:"b";i:1;}`},
+	}
+
+	for i, test := range testList {
+
+		var out bytes.Buffer
+		if err := FromJSON(&out, strings.NewReader(test.json)); err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if out.String() != test.expect {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.expect, out.String())
+		}
+
+	}
+
+	// 20 digits don't fit an int64
+	var out bytes.Buffer
+	if err := FromJSON(&out, strings.NewReader(`12345678901234567890`)); err != nil {
+		t.Fatal(err)
+	}
+	if expect, _ := Marshal(float64(12345678901234567890)); out.String() != string(expect) {
+		t.Fatalf("expect:%s got %s", expect, out.String())
+	}
+
+	if err := FromJSON(&bytes.Buffer{}, strings.NewReader(`{"a": }`)); err == nil {
+		t.Fatal("expect an error for invalid json")
+	}
+
+}
+
+func TestJSON_RoundTrip(t *testing.T) {
+
+	data := `a:2:{s:4:"list";a:2:{i:0;d:1.5;i:1;N;}s:3:"obj";O:3:"Foo":1:{s:1:"a";C:3:"Bar":2:{xy}}}`
+
+	var js, out bytes.Buffer
+	if err := ToJSON(&js, strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := FromJSON(&out, &js); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != data {
+		t.Fatalf("expect:%s got %s", data, out.String())
+	}
+
+}
+
+func TestMarshal_NonFinite(t *testing.T) {
+
+	for _, f := range []float64{math.Inf(1), math.Inf(-1), math.NaN()} {
+
+		data, err := Marshal(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out float64
+		if err = Unmarshal(data, &out); err != nil {
+			t.Fatal(err)
+		}
+		if math.IsNaN(f) != math.IsNaN(out) || (!math.IsNaN(f) && f != out) {
+			t.Fatalf("expect %v got %v from %s", f, out, data)
+		}
+
+	}
+
+	if data, _ := Marshal(float32(math.Inf(-1))); string(data) != "d:-INF;" {
+		t.Fatalf("expect d:-INF; got %s", data)
+	}
+
+}
@@ -0,0 +1,308 @@
+// Package laravel reads and writes values encrypted by Laravel's Encrypter,
+// as used for its cookies and encrypted sessions.
+package laravel
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/zengxinqian/phpserialize"
+)
+
+type Cipher string
+
+const (
+	AES128CBC Cipher = "aes-128-cbc"
+	AES256CBC Cipher = "aes-256-cbc"
+	AES128GCM Cipher = "aes-128-gcm"
+	AES256GCM Cipher = "aes-256-gcm"
+)
+
+var (
+	ErrInvalidKey     = errors.New("laravel: invalid key length for the cipher")
+	ErrInvalidPayload = errors.New("laravel: the payload is invalid")
+	ErrInvalidMAC     = errors.New("laravel: the MAC is invalid")
+)
+
+// Encrypter is Illuminate\Encryption\Encrypter.
+type Encrypter struct {
+	key    []byte
+	cipher Cipher
+}
+
+// NewEncrypter takes the key as APP_KEY holds it, raw or "base64:" encoded.
+func NewEncrypter(key string, c Cipher) (*Encrypter, error) {
+
+	k := []byte(key)
+	if strings.HasPrefix(key, "base64:") {
+		var err error
+		if k, err = base64.StdEncoding.DecodeString(key[len("base64:"):]); err != nil {
+			return nil, err
+		}
+	}
+
+	size := 32
+	if c == AES128CBC || c == AES128GCM {
+		size = 16
+	}
+	if len(k) != size || (c != AES128CBC && c != AES256CBC && c != AES128GCM && c != AES256GCM) {
+		return nil, ErrInvalidKey
+	}
+	return &Encrypter{key: k, cipher: c}, nil
+
+}
+
+func (e *Encrypter) aead() bool {
+	return e.cipher == AES128GCM || e.cipher == AES256GCM
+}
+
+type payload struct {
+	IV    string `json:"iv"`
+	Value string `json:"value"`
+	MAC   string `json:"mac"`
+	Tag   string `json:"tag"`
+}
+
+// Encrypt serializes v and encrypts it like encrypt($value) does.
+func (e *Encrypter) Encrypt(v interface{}) (string, error) {
+
+	data, err := phpserialize.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return e.EncryptString(data)
+
+}
+
+// EncryptString encrypts data like encryptString($value) does.
+func (e *Encrypter) EncryptString(data []byte) (string, error) {
+
+	block, err := aes.NewCipher(e.key)
+	if err != nil {
+		return "", err
+	}
+
+	var p payload
+	if e.aead() {
+
+		gcm, err := cipher.NewGCMWithNonceSize(block, 12)
+		if err != nil {
+			return "", err
+		}
+		iv := make([]byte, 12)
+		if _, err = rand.Read(iv); err != nil {
+			return "", err
+		}
+		sealed := gcm.Seal(nil, iv, data, nil)
+		tagStart := len(sealed) - gcm.Overhead()
+		p.IV = base64.StdEncoding.EncodeToString(iv)
+		p.Value = base64.StdEncoding.EncodeToString(sealed[:tagStart])
+		p.Tag = base64.StdEncoding.EncodeToString(sealed[tagStart:])
+
+	} else {
+
+		iv := make([]byte, aes.BlockSize)
+		if _, err = rand.Read(iv); err != nil {
+			return "", err
+		}
+		padded := pkcs7Pad(data, aes.BlockSize)
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(padded, padded)
+		p.IV = base64.StdEncoding.EncodeToString(iv)
+		p.Value = base64.StdEncoding.EncodeToString(padded)
+		p.MAC = e.mac(p.IV, p.Value)
+
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+
+}
+
+// Decrypt decrypts value and unserializes it into v, like decrypt($payload).
+func (e *Encrypter) Decrypt(value string, v interface{}) error {
+
+	data, err := e.DecryptString(value)
+	if err != nil {
+		return err
+	}
+	return phpserialize.Unmarshal(data, v)
+
+}
+
+// DecryptString verifies and decrypts value like decryptString($payload).
+func (e *Encrypter) DecryptString(value string) ([]byte, error) {
+
+	p, err := decodePayload(value)
+	if err != nil {
+		return nil, err
+	}
+
+	iv, err := base64.StdEncoding.DecodeString(p.IV)
+	if err != nil {
+		return nil, ErrInvalidPayload
+	}
+	data, err := base64.StdEncoding.DecodeString(p.Value)
+	if err != nil {
+		return nil, ErrInvalidPayload
+	}
+
+	block, err := aes.NewCipher(e.key)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.aead() {
+
+		tag, err := base64.StdEncoding.DecodeString(p.Tag)
+		if err != nil || len(tag) != 16 || len(iv) != 12 {
+			return nil, ErrInvalidPayload
+		}
+		gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+		if err != nil {
+			return nil, err
+		}
+		plain, err := gcm.Open(nil, iv, append(data, tag...), nil)
+		if err != nil {
+			return nil, ErrInvalidMAC
+		}
+		return plain, nil
+
+	}
+
+	if len(iv) != aes.BlockSize || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, ErrInvalidPayload
+	}
+	if !hmac.Equal([]byte(e.mac(p.IV, p.Value)), []byte(p.MAC)) {
+		return nil, ErrInvalidMAC
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+	return pkcs7Unpad(plain, aes.BlockSize)
+
+}
+
+func decodePayload(value string) (*payload, error) {
+
+	b, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidPayload
+	}
+	p := &payload{}
+	if err = json.Unmarshal(b, p); err != nil || p.IV == "" || p.Value == "" {
+		return nil, ErrInvalidPayload
+	}
+	return p, nil
+
+}
+
+// mac is hash_hmac('sha256', $iv.$value, $key) in hex.
+func (e *Encrypter) mac(iv, value string) string {
+
+	h := hmac.New(sha256.New, e.key)
+	h.Write([]byte(iv + value))
+	return hex.EncodeToString(h.Sum(nil))
+
+}
+
+func pkcs7Pad(data []byte, size int) []byte {
+
+	n := size - len(data)%size
+	padded := make([]byte, len(data)+n)
+	copy(padded, data)
+	for i := len(data); i < len(padded); i++ {
+		padded[i] = byte(n)
+	}
+	return padded
+
+}
+
+func pkcs7Unpad(data []byte, size int) ([]byte, error) {
+
+	n := int(data[len(data)-1])
+	if n == 0 || n > size || n > len(data) {
+		return nil, ErrInvalidPayload
+	}
+	for _, c := range data[len(data)-n:] {
+		if int(c) != n {
+			return nil, ErrInvalidPayload
+		}
+	}
+	return data[:len(data)-n], nil
+
+}
+
+// CookiePrefix is the prefix Laravel 8.x and later put in front of the
+// value of an encrypted cookie, hash_hmac('sha1', $name.'v2', $key).'|'.
+func (e *Encrypter) CookiePrefix(name string) string {
+
+	h := hmac.New(sha1.New, e.key)
+	h.Write([]byte(name + "v2"))
+	return hex.EncodeToString(h.Sum(nil)) + "|"
+
+}
+
+// DecryptCookie decrypts the cookie name the way EncryptCookies does. The
+// cookie-name prefix of newer Laravel versions is checked and removed, and
+// values serialized by older versions are unserialized.
+func (e *Encrypter) DecryptCookie(name, value string) ([]byte, error) {
+
+	data, err := e.DecryptString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := e.CookiePrefix(name)
+	if strings.HasPrefix(string(data), prefix) {
+		data = data[len(prefix):]
+	} else if len(data) >= len(prefix) && data[len(prefix)-1] == '|' {
+		return nil, ErrInvalidMAC
+	}
+
+	var s string
+	if len(data) > 0 && data[0] == 's' && phpserialize.Unmarshal(data, &s) == nil {
+		return []byte(s), nil
+	}
+	return data, nil
+
+}
+
+// EncryptCookie encrypts value for the cookie name like EncryptCookies does
+// in Laravel 8.x and later.
+func (e *Encrypter) EncryptCookie(name string, value []byte) (string, error) {
+	return e.EncryptString(append([]byte(e.CookiePrefix(name)), value...))
+}
+
+// DecryptSession decodes the payload of an encrypted Laravel session into v.
+// The session attributes are serialized and then encrypted with
+// serialization again.
+func (e *Encrypter) DecryptSession(value string, v interface{}) error {
+
+	var data string
+	if err := e.Decrypt(value, &data); err != nil {
+		return err
+	}
+	return phpserialize.Unmarshal([]byte(data), v)
+
+}
+
+func (e *Encrypter) EncryptSession(v interface{}) (string, error) {
+
+	data, err := phpserialize.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return e.Encrypt(string(data))
+
+}
@@ -0,0 +1,151 @@
+package laravel
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+const testKey = "base64:AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+
+// laravelPayload builds a CBC payload the way Laravel does, with a fixed iv.
+func laravelPayload(key []byte, plain string) string {
+
+	iv := []byte("0123456789abcdef")
+	block, _ := aes.NewCipher(key)
+	data := pkcs7Pad([]byte(plain), aes.BlockSize)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+
+	ivs := base64.StdEncoding.EncodeToString(iv)
+	value := base64.StdEncoding.EncodeToString(data)
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(ivs + value))
+	json := `{"iv":"` + ivs + `","value":"` + value + `","mac":"` + hex.EncodeToString(h.Sum(nil)) + `","tag":""}`
+	return base64.StdEncoding.EncodeToString([]byte(json))
+
+}
+
+func TestEncrypter(t *testing.T) {
+
+	for _, c := range []Cipher{AES128CBC, AES256CBC, AES128GCM, AES256GCM} {
+
+		key := testKey
+		if c == AES128CBC || c == AES128GCM {
+			key = "base64:" + base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))
+		}
+		e, err := NewEncrypter(key, c)
+		if err != nil {
+			t.Fatalf("%s: %v", c, err)
+		}
+
+		payload, err := e.Encrypt(map[string]int{"a": 1})
+		if err != nil {
+			t.Fatalf("%s: %v", c, err)
+		}
+		var m map[string]int
+		if err = e.Decrypt(payload, &m); err != nil || m["a"] != 1 {
+			t.Fatalf("%s: unexpected result %v %v", c, m, err)
+		}
+
+		// flip a bit of the encrypted value
+		b, _ := base64.StdEncoding.DecodeString(payload)
+		i := strings.Index(string(b), `"value":"`) + len(`"value":"`)
+		if b[i] == 'A' {
+			b[i] = 'B'
+		} else {
+			b[i] = 'A'
+		}
+		if _, err = e.DecryptString(base64.StdEncoding.EncodeToString(b)); err != ErrInvalidMAC {
+			t.Fatalf("%s: expect ErrInvalidMAC got %v", c, err)
+		}
+
+	}
+
+	if _, err := NewEncrypter("short", AES256CBC); err != ErrInvalidKey {
+		t.Fatalf("expect ErrInvalidKey got %v", err)
+	}
+
+}
+
+func TestEncrypter_Laravel(t *testing.T) {
+
+	e, _ := NewEncrypter(testKey, AES256CBC)
+	key, _ := base64.StdEncoding.DecodeString(testKey[len("base64:"):])
+
+	var s string
+	if err := e.Decrypt(laravelPayload(key, `s:5:"hello";`), &s); err != nil || s != "hello" {
+		t.Fatalf("unexpected result %q %v", s, err)
+	}
+
+	if _, err := e.DecryptString("not a payload"); err != ErrInvalidPayload {
+		t.Fatalf("expect ErrInvalidPayload got %v", err)
+	}
+
+}
+
+func TestEncrypter_Cookie(t *testing.T) {
+
+	e, _ := NewEncrypter(testKey, AES256CBC)
+	key, _ := base64.StdEncoding.DecodeString(testKey[len("base64:"):])
+
+	prefix := e.CookiePrefix("laravel_session")
+	if len(prefix) != 41 || prefix[40] != '|' {
+		t.Fatalf("unexpected prefix %s", prefix)
+	}
+
+	testList := []struct {
+		plain  string
+		expect string
+	}{
+		{prefix + "sessionid", "sessionid"},
+		{`s:9:"sessionid";`, "sessionid"},
+		{prefix + `s:9:"sessionid";`, "sessionid"},
+	}
+
+	for i, test := range testList {
+		value, err := e.DecryptCookie("laravel_session", laravelPayload(key, test.plain))
+		if err != nil || string(value) != test.expect {
+			t.Fatalf("Test fail at index %d, expect:%s got %s %v", i, test.expect, value, err)
+		}
+	}
+
+	// a cookie encrypted for another name
+	other := e.CookiePrefix("XSRF-TOKEN")
+	if _, err := e.DecryptCookie("laravel_session", laravelPayload(key, other+"token")); err != ErrInvalidMAC {
+		t.Fatalf("expect ErrInvalidMAC got %v", err)
+	}
+
+	payload, err := e.EncryptCookie("laravel_session", []byte("sessionid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := e.DecryptCookie("laravel_session", payload); err != nil || string(value) != "sessionid" {
+		t.Fatalf("unexpected cookie %s %v", value, err)
+	}
+
+}
+
+func TestEncrypter_Session(t *testing.T) {
+
+	e, _ := NewEncrypter(testKey, AES256GCM)
+
+	payload, err := e.EncryptSession(map[string]string{"_token": "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := e.DecryptString(payload)
+	if string(data) != `s:29:"a:1:{s:6:"_token";s:3:"abc";}";` {
+		t.Fatalf("unexpected session data %s", data)
+	}
+
+	var m map[string]string
+	if err = e.DecryptSession(payload, &m); err != nil || m["_token"] != "abc" {
+		t.Fatalf("unexpected session %v %v", m, err)
+	}
+
+}
@@ -0,0 +1,236 @@
+package laravel
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/zengxinqian/phpserialize"
+)
+
+// CallQueuedHandler is the job of every queued command.
+const CallQueuedHandler = "Illuminate\\Queue\\CallQueuedHandler@call"
+
+var ErrNotCommand = errors.New("laravel: the payload has no serialized command")
+
+// Payload is the JSON a Laravel queue stores for a job, the same for the
+// redis, database and sqs drivers. ID and Attempts are only used by redis.
+type Payload struct {
+	UUID          string      `json:"uuid"`
+	DisplayName   string      `json:"displayName"`
+	Job           string      `json:"job"`
+	MaxTries      *int        `json:"maxTries"`
+	MaxExceptions *int        `json:"maxExceptions"`
+	FailOnTimeout bool        `json:"failOnTimeout"`
+	Backoff       interface{} `json:"backoff"`
+	Timeout       *int        `json:"timeout"`
+	RetryUntil    *int64      `json:"retryUntil"`
+	Data          PayloadData `json:"data"`
+	ID            string      `json:"id,omitempty"`
+	Attempts      int         `json:"attempts,omitempty"`
+
+	// Extra holds the fields there is no field for, like tags and pushedAt,
+	// written back after the others.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// jobPayload has the fields of Payload without its JSON methods.
+type jobPayload Payload
+
+func (p *Payload) UnmarshalJSON(data []byte) error {
+
+	if err := json.Unmarshal(data, (*jobPayload)(p)); err != nil {
+		return err
+	}
+	extra, err := extraFields(data, reflect.TypeOf(jobPayload{}))
+	if err != nil {
+		return err
+	}
+	p.Extra = extra
+	return nil
+
+}
+
+func (p Payload) MarshalJSON() ([]byte, error) {
+
+	data, err := json.Marshal(jobPayload(p))
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Extra) == 0 {
+		return data, nil
+	}
+
+	names := make([]string, 0, len(p.Extra))
+	for name := range p.Extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	b.Write(data[:len(data)-1])
+	for _, name := range names {
+		key, _ := json.Marshal(name)
+		b.WriteByte(',')
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(p.Extra[name])
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+
+}
+
+// extraFields returns the fields of the JSON object data that the struct
+// type t has no field for.
+func extraFields(data []byte, t reflect.Type) (map[string]json.RawMessage, error) {
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		for key := range fields {
+			if strings.EqualFold(key, name) {
+				delete(fields, key)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+
+}
+
+type PayloadData struct {
+	CommandName string `json:"commandName"`
+	Command     string `json:"command"`
+}
+
+// NewPayload serializes command into a payload run by CallQueuedHandler.
+func NewPayload(command phpserialize.PHPClass) (*Payload, error) {
+
+	data, err := phpserialize.Marshal(command)
+	if err != nil {
+		return nil, err
+	}
+
+	uuid, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	name := command.GetPHPClassName()
+	return &Payload{
+		UUID:        uuid,
+		DisplayName: name,
+		Job:         CallQueuedHandler,
+		Data:        PayloadData{CommandName: name, Command: string(data)},
+	}, nil
+
+}
+
+// NewEncryptedPayload is NewPayload for jobs implementing ShouldBeEncrypted.
+func NewEncryptedPayload(command phpserialize.PHPClass, e *Encrypter) (*Payload, error) {
+
+	p, err := NewPayload(command)
+	if err != nil {
+		return nil, err
+	}
+	if p.Data.Command, err = e.EncryptString([]byte(p.Data.Command)); err != nil {
+		return nil, err
+	}
+	return p, nil
+
+}
+
+func ParsePayload(data []byte) (*Payload, error) {
+
+	p := &Payload{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+
+}
+
+func (p *Payload) Marshal() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// DecodeCommand unserializes the command of the job into v.
+func (p *Payload) DecodeCommand(v interface{}) error {
+
+	if p.Data.Command == "" {
+		return ErrNotCommand
+	}
+	return phpserialize.Unmarshal([]byte(p.Data.Command), v)
+
+}
+
+// DecodeEncryptedCommand decrypts the command of a ShouldBeEncrypted job
+// and unserializes it into v. Commands that aren't encrypted are decoded as
+// they are.
+func (p *Payload) DecodeEncryptedCommand(e *Encrypter, v interface{}) error {
+
+	if p.Data.Command == "" {
+		return ErrNotCommand
+	}
+	if len(p.Data.Command) > 1 && p.Data.Command[0] == 'O' && p.Data.Command[1] == ':' {
+		return p.DecodeCommand(v)
+	}
+	data, err := e.DecryptString(p.Data.Command)
+	if err != nil {
+		return err
+	}
+	return phpserialize.Unmarshal(data, v)
+
+}
+
+// ModelIdentifier is what SerializesModels leaves of an Eloquent model or
+// collection in a job, the model is fetched again when the job runs.
+type ModelIdentifier struct {
+	Class           string                    `php:"class"`
+	ID              interface{}               `php:"id"`
+	Relations       phpserialize.List[string] `php:"relations"`
+	Connection      *string                   `php:"connection"`
+	CollectionClass *string                   `php:"collectionClass"`
+}
+
+func (ModelIdentifier) GetPHPClassName() string {
+	return "Illuminate\\Contracts\\Database\\ModelIdentifier"
+}
+
+// NewModelIdentifier identifies the model class with key id on the
+// connection, an empty connection is written as null.
+func NewModelIdentifier(class string, id interface{}, connection string) ModelIdentifier {
+
+	m := ModelIdentifier{Class: class, ID: id}
+	if connection != "" {
+		m.Connection = &connection
+	}
+	return m
+
+}
+
+// newUUID returns a random version 4 uuid like Str::uuid.
+func newUUID() (string, error) {
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	s := hex.EncodeToString(b)
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:], nil
+
+}
@@ -0,0 +1,108 @@
+package laravel
+
+import (
+	"strings"
+	"testing"
+)
+
+type processPodcast struct {
+	Podcast    ModelIdentifier `php:"podcast,protected"`
+	Secret     string          `php:"secret,private"`
+	Connection *string         `php:"connection"`
+	Queue      *string         `php:"queue"`
+}
+
+func (processPodcast) GetPHPClassName() string {
+	return "App\\Jobs\\ProcessPodcast"
+}
+
+const testPayload = `{"uuid":"5a8c7d4e-1f2b-4c3d-9e8f-0a1b2c3d4e5f","displayName":"App\\Jobs\\ProcessPodcast","job":"Illuminate\\Queue\\CallQueuedHandler@call","maxTries":3,"maxExceptions":null,"failOnTimeout":false,"backoff":"1,5","timeout":null,"retryUntil":null,"data":{"commandName":"App\\Jobs\\ProcessPodcast","command":"O:23:\"App\\Jobs\\ProcessPodcast\":4:{s:10:\"\u0000*\u0000podcast\";O:45:\"Illuminate\\Contracts\\Database\\ModelIdentifier\":5:{s:5:\"class\";s:14:\"App\\Models\\Pod\";s:2:\"id\";i:7;s:9:\"relations\";a:1:{i:0;s:6:\"author\";}s:10:\"connection\";s:5:\"mysql\";s:15:\"collectionClass\";N;}s:31:\"\u0000App\\Jobs\\ProcessPodcast\u0000secret\";s:3:\"xyz\";s:10:\"connection\";N;s:5:\"queue\";s:7:\"podcast\";}"},"id":"abc","attempts":1}`
+
+func TestPayload(t *testing.T) {
+
+	p, err := ParsePayload([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.DisplayName != "App\\Jobs\\ProcessPodcast" || *p.MaxTries != 3 || p.Attempts != 1 || p.Backoff != "1,5" {
+		t.Fatalf("unexpected payload %+v", p)
+	}
+
+	var job processPodcast
+	if err = p.DecodeCommand(&job); err != nil {
+		t.Fatal(err)
+	}
+	m := job.Podcast
+	if m.Class != "App\\Models\\Pod" || m.ID != int64(7) || len(m.Relations) != 1 || *m.Connection != "mysql" || m.CollectionClass != nil {
+		t.Fatalf("unexpected model %+v", m)
+	}
+	if job.Secret != "xyz" || job.Connection != nil || *job.Queue != "podcast" {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	// the command serializes back to the same bytes
+	p2, err := NewPayload(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p2.Data.Command != p.Data.Command {
+		t.Fatalf("expect:%q got %q", p.Data.Command, p2.Data.Command)
+	}
+	if p2.Job != CallQueuedHandler || p2.Data.CommandName != job.GetPHPClassName() || len(p2.UUID) != 36 {
+		t.Fatalf("unexpected payload %+v", p2)
+	}
+
+	data, err := p2.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"maxTries":null`) || !strings.Contains(string(data), `\u0000*\u0000podcast`) {
+		t.Fatalf("unexpected json %s", data)
+	}
+
+}
+
+func TestPayload_Encrypted(t *testing.T) {
+
+	e, _ := NewEncrypter(testKey, AES256CBC)
+	job := processPodcast{Podcast: NewModelIdentifier("App\\Models\\Pod", 7, "")}
+
+	p, err := NewEncryptedPayload(job, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(p.Data.Command, "O:") {
+		t.Fatalf("expect an encrypted command got %s", p.Data.Command)
+	}
+
+	var decoded processPodcast
+	if err = p.DecodeEncryptedCommand(e, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Podcast.ID != int64(7) || decoded.Podcast.Connection != nil {
+		t.Fatalf("unexpected job %+v", decoded)
+	}
+
+}
+
+func TestPayload_Extra(t *testing.T) {
+
+	data := strings.Replace(testPayload, `"id":"abc"`, `"tags":["podcast"],"pushedAt":"1700000000.1234","id":"abc"`, 1)
+	p, err := ParsePayload([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Extra) != 2 || string(p.Extra["tags"]) != `["podcast"]` {
+		t.Fatalf("unexpected extra fields %v", p.Extra)
+	}
+
+	result, err := p.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := strings.Replace(testPayload, `"attempts":1}`, `"attempts":1,"pushedAt":"1700000000.1234","tags":["podcast"]}`, 1)
+	if string(result) != expect {
+		t.Fatalf("expect:%s got %s", expect, result)
+	}
+
+}
@@ -0,0 +1,828 @@
+package phpserialize
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// UnmarshalVarExport parses php source holding a literal value, like
+// var_export output or a config file doing return [...];, and stores it in
+// v the way Unmarshal stores the serialized value.
+//
+// Arrays, strings, numbers, true, false, null, the PHP_* constants,
+// __set_state calls, (object) and (array) casts, concatenation and basic
+// arithmetic are understood. Anything else, like function calls or
+// variables, is a syntax error.
+func UnmarshalVarExport(data []byte, v interface{}) error {
+
+	serialized, err := VarExportToSerialized(data)
+	if err != nil {
+		return err
+	}
+	return Unmarshal(serialized, v)
+
+}
+
+// VarExportToSerialized converts php literal source to a serialized value.
+func VarExportToSerialized(data []byte) ([]byte, error) {
+
+	p := literalParser{data: data}
+	l, err := p.program()
+	if err != nil {
+		return nil, err
+	}
+	return l.appendSerialized(nil), nil
+
+}
+
+type literal struct {
+	tag     phpValueType
+	b       bool
+	i       int64
+	f       float64
+	s       string
+	class   string // objects only
+	entries []literalEntry
+}
+
+type literalEntry struct {
+	key   literal // an integer or a string
+	value *literal
+}
+
+var literalConstants = map[string]literal{
+	"PHP_INT_MAX":         {tag: phpTypeInteger, i: math.MaxInt64},
+	"PHP_INT_MIN":         {tag: phpTypeInteger, i: math.MinInt64},
+	"PHP_INT_SIZE":        {tag: phpTypeInteger, i: 8},
+	"PHP_FLOAT_EPSILON":   {tag: phpTypeFloat, f: 2.220446049250313e-16},
+	"PHP_FLOAT_MAX":       {tag: phpTypeFloat, f: math.MaxFloat64},
+	"PHP_FLOAT_MIN":       {tag: phpTypeFloat, f: 2.2250738585072014e-308},
+	"PHP_FLOAT_DIG":       {tag: phpTypeInteger, i: 15},
+	"INF":                 {tag: phpTypeFloat, f: math.Inf(1)},
+	"NAN":                 {tag: phpTypeFloat, f: math.NaN()},
+	"M_PI":                {tag: phpTypeFloat, f: math.Pi},
+	"M_E":                 {tag: phpTypeFloat, f: math.E},
+	"PHP_EOL":             {tag: phpTypeString, s: "\n"},
+	"DIRECTORY_SEPARATOR": {tag: phpTypeString, s: "/"},
+	"PATH_SEPARATOR":      {tag: phpTypeString, s: ":"},
+}
+
+type literalParser struct {
+	data []byte
+	off  int
+}
+
+func (p *literalParser) error(msg string) error {
+
+	if p.off >= len(p.data) {
+		return &SyntaxError{"unexpected end of input", int64(len(p.data))}
+	}
+	return &SyntaxError{msg + ", offset: " + strconv.Itoa(p.off), int64(p.off)}
+
+}
+
+func (p *literalParser) skipSpace() {
+
+	for p.off < len(p.data) {
+		switch c := p.data[p.off]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f':
+			p.off++
+		case c == '#' || bytes.HasPrefix(p.data[p.off:], []byte("//")):
+			for p.off < len(p.data) && p.data[p.off] != '\n' {
+				p.off++
+			}
+		case bytes.HasPrefix(p.data[p.off:], []byte("/*")):
+			end := bytes.Index(p.data[p.off+2:], []byte("*/"))
+			if end < 0 {
+				p.off = len(p.data)
+				return
+			}
+			p.off += end + 4
+		default:
+			return
+		}
+	}
+
+}
+
+// consume moves over s when it comes next.
+func (p *literalParser) consume(s string) bool {
+
+	p.skipSpace()
+	if bytes.HasPrefix(p.data[p.off:], []byte(s)) {
+		p.off += len(s)
+		return true
+	}
+	return false
+
+}
+
+func (p *literalParser) expect(s string) error {
+
+	if !p.consume(s) {
+		return p.error("expect " + strconv.Quote(s))
+	}
+	return nil
+
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// keyword moves over the case insensitive word when it comes next.
+func (p *literalParser) keyword(word string) bool {
+
+	p.skipSpace()
+	end := p.off + len(word)
+	if end > len(p.data) || !strings.EqualFold(string(p.data[p.off:end]), word) {
+		return false
+	}
+	if end < len(p.data) && isIdentByte(p.data[end]) {
+		return false
+	}
+	p.off = end
+	return true
+
+}
+
+// name reads a possibly qualified name, without a leading backslash.
+func (p *literalParser) name() string {
+
+	p.skipSpace()
+	if p.off < len(p.data) && p.data[p.off] == '\\' {
+		p.off++
+	}
+	start := p.off
+	for p.off < len(p.data) && (isIdentByte(p.data[p.off]) || p.data[p.off] == '\\') {
+		p.off++
+	}
+	return string(p.data[start:p.off])
+
+}
+
+func (p *literalParser) program() (*literal, error) {
+
+	p.skipSpace()
+	p.consume("<?php")
+
+	if p.keyword("declare") {
+		for p.off < len(p.data) && p.data[p.off] != phpTerminator {
+			p.off++
+		}
+		if err := p.expect(";"); err != nil {
+			return nil, err
+		}
+	}
+
+	statement := p.keyword("return")
+	l, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.consume(";") && statement && !p.consume("?>") {
+		return nil, p.error("expect ';'")
+	}
+	p.consume("?>")
+	p.skipSpace()
+	if p.off != len(p.data) {
+		return nil, p.error("invalid character " + quoteChar(p.data[p.off]) + " after value")
+	}
+	return l, nil
+
+}
+
+// expr reads concatenations of sums of products, the precedence php 8 uses.
+func (p *literalParser) expr() (*literal, error) {
+
+	l, err := p.sum()
+	for err == nil && p.consume(".") {
+		var r *literal
+		if r, err = p.sum(); err == nil {
+			l, err = p.concat(l, r)
+		}
+	}
+	return l, err
+
+}
+
+func (p *literalParser) sum() (*literal, error) {
+
+	l, err := p.product()
+	for err == nil {
+		var op byte
+		switch {
+		case p.consume("+"):
+			op = '+'
+		case p.consume("-"):
+			op = '-'
+		default:
+			return l, nil
+		}
+		var r *literal
+		if r, err = p.product(); err == nil {
+			l, err = p.arithmetic(op, l, r)
+		}
+	}
+	return nil, err
+
+}
+
+func (p *literalParser) product() (*literal, error) {
+
+	l, err := p.unary()
+	for err == nil {
+		var op byte
+		switch {
+		case p.consume("*"):
+			op = '*'
+		case p.consume("/"):
+			op = '/'
+		case p.consume("%"):
+			op = '%'
+		default:
+			return l, nil
+		}
+		var r *literal
+		if r, err = p.unary(); err == nil {
+			l, err = p.arithmetic(op, l, r)
+		}
+	}
+	return nil, err
+
+}
+
+func (p *literalParser) unary() (*literal, error) {
+
+	switch {
+	case p.consume("-"):
+		l, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return p.arithmetic('-', &literal{tag: phpTypeInteger}, l)
+	case p.consume("+"):
+		l, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return p.arithmetic('+', &literal{tag: phpTypeInteger}, l)
+	case p.consume("("):
+		for _, cast := range []string{"object", "array"} {
+			start := p.off
+			if !p.keyword(cast) || !p.consume(")") {
+				p.off = start
+				continue
+			}
+			l, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			if cast == "object" {
+				return castObject(l), nil
+			}
+			return castArray(l), nil
+		}
+		l, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return l, p.expect(")")
+	}
+	return p.primary()
+
+}
+
+func (p *literalParser) primary() (*literal, error) {
+
+	p.skipSpace()
+	if p.off >= len(p.data) {
+		return nil, p.error("")
+	}
+
+	switch c := p.data[p.off]; {
+	case c == '[':
+		p.off++
+		return p.array(']')
+	case c == '\'':
+		return p.singleQuoted()
+	case c == '"':
+		return p.doubleQuoted()
+	case c >= '0' && c <= '9', c == '.' && p.off+1 < len(p.data) && p.data[p.off+1] >= '0' && p.data[p.off+1] <= '9':
+		return p.number()
+	}
+
+	if p.keyword("array") {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		return p.array(')')
+	}
+	for _, word := range []string{"true", "false", "null"} {
+		if p.keyword(word) {
+			if word == "null" {
+				return &literal{tag: phpTypeNull}, nil
+			}
+			return &literal{tag: phpTypeBoolean, b: word == "true"}, nil
+		}
+	}
+
+	start := p.off
+	name := p.name()
+	if name == "" && p.off >= len(p.data) {
+		return nil, p.error("")
+	}
+	if name == "" {
+		return nil, p.error("invalid character " + quoteChar(p.data[p.off]) + " looking for beginning of value")
+	}
+
+	if p.consume("::") {
+		switch {
+		case p.keyword("class"):
+			return &literal{tag: phpTypeString, s: name}, nil
+		case p.keyword("__set_state"):
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			l, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if l.tag != phpTypeArray {
+				return nil, p.error("__set_state expects an array")
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			l = castObject(l)
+			l.class = name
+			return l, nil
+		}
+		p.off = start
+		return nil, p.error("unsupported class constant or method call")
+	}
+
+	if l, ok := literalConstants[name]; ok {
+		return &l, nil
+	}
+	p.off = start
+	return nil, p.error("unsupported constant or function " + strconv.Quote(name))
+
+}
+
+func (p *literalParser) array(end byte) (*literal, error) {
+
+	a := &literal{tag: phpTypeArray}
+	next := int64(0)
+	for {
+
+		if p.consume(string(end)) {
+			return a, nil
+		}
+
+		value, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		var key literal
+		if p.consume("=>") {
+			if key, err = p.arrayKey(value); err != nil {
+				return nil, err
+			}
+			if value, err = p.expr(); err != nil {
+				return nil, err
+			}
+		} else {
+			key = literal{tag: phpTypeInteger, i: next}
+		}
+		if key.tag == phpTypeInteger && key.i >= next && key.i < math.MaxInt64 {
+			next = key.i + 1
+		}
+		a.set(key, value)
+
+		if !p.consume(",") {
+			if err = p.expect(string(end)); err != nil {
+				return nil, err
+			}
+			return a, nil
+		}
+
+	}
+
+}
+
+// arrayKey converts k to the integer or string key php uses for it.
+func (p *literalParser) arrayKey(k *literal) (literal, error) {
+
+	switch k.tag {
+	case phpTypeInteger:
+		return *k, nil
+	case phpTypeString:
+		if n, ok := phpIntKey(k.s); ok {
+			return literal{tag: phpTypeInteger, i: n}, nil
+		}
+		return *k, nil
+	case phpTypeBoolean:
+		if k.b {
+			return literal{tag: phpTypeInteger, i: 1}, nil
+		}
+		return literal{tag: phpTypeInteger}, nil
+	case phpTypeFloat:
+		return literal{tag: phpTypeInteger, i: int64(k.f)}, nil
+	case phpTypeNull:
+		return literal{tag: phpTypeString}, nil
+	}
+	return literal{}, p.error("illegal offset type")
+
+}
+
+// set adds an entry, a key that is already there keeps its position.
+func (l *literal) set(key literal, value *literal) {
+
+	for i := range l.entries {
+		if e := &l.entries[i]; e.key.tag == key.tag && e.key.i == key.i && e.key.s == key.s {
+			e.value = value
+			return
+		}
+	}
+	l.entries = append(l.entries, literalEntry{key, value})
+
+}
+
+func castObject(l *literal) *literal {
+
+	o := &literal{tag: phpTypeObject, class: "stdClass"}
+	switch l.tag {
+	case phpTypeObject:
+		return l
+	case phpTypeArray:
+		for _, e := range l.entries {
+			if e.key.tag == phpTypeInteger {
+				e.key = literal{tag: phpTypeString, s: strconv.FormatInt(e.key.i, 10)}
+			}
+			o.entries = append(o.entries, e)
+		}
+	case phpTypeNull:
+	default:
+		o.entries = []literalEntry{{literal{tag: phpTypeString, s: "scalar"}, l}}
+	}
+	return o
+
+}
+
+func castArray(l *literal) *literal {
+
+	a := &literal{tag: phpTypeArray}
+	switch l.tag {
+	case phpTypeArray:
+		return l
+	case phpTypeObject:
+		for _, e := range l.entries {
+			if n, ok := phpIntKey(e.key.s); ok && e.key.tag == phpTypeString {
+				e.key = literal{tag: phpTypeInteger, i: n}
+			}
+			a.set(e.key, e.value)
+		}
+	case phpTypeNull:
+	default:
+		a.entries = []literalEntry{{literal{tag: phpTypeInteger}, l}}
+	}
+	return a
+
+}
+
+func (p *literalParser) concat(l, r *literal) (*literal, error) {
+
+	ls, ok1 := l.stringValue()
+	rs, ok2 := r.stringValue()
+	if !ok1 || !ok2 {
+		return nil, p.error("unsupported operand for '.'")
+	}
+	return &literal{tag: phpTypeString, s: ls + rs}, nil
+
+}
+
+func (l *literal) stringValue() (string, bool) {
+
+	switch l.tag {
+	case phpTypeString:
+		return l.s, true
+	case phpTypeInteger:
+		return strconv.FormatInt(l.i, 10), true
+	case phpTypeBoolean:
+		if l.b {
+			return "1", true
+		}
+		return "", true
+	case phpTypeNull:
+		return "", true
+	}
+	return "", false
+
+}
+
+func (l *literal) number() (int64, float64, bool, bool) {
+
+	switch l.tag {
+	case phpTypeInteger:
+		return l.i, float64(l.i), true, true
+	case phpTypeFloat:
+		return 0, l.f, false, true
+	case phpTypeBoolean:
+		if l.b {
+			return 1, 1, true, true
+		}
+		return 0, 0, true, true
+	case phpTypeNull:
+		return 0, 0, true, true
+	}
+	return 0, 0, false, false
+
+}
+
+// arithmetic applies op like php does, integers that overflow become floats.
+func (p *literalParser) arithmetic(op byte, l, r *literal) (*literal, error) {
+
+	li, lf, lint, ok1 := l.number()
+	ri, rf, rint, ok2 := r.number()
+	if !ok1 || !ok2 {
+		return nil, p.error("unsupported operand for " + quoteChar(op))
+	}
+
+	if lint && rint {
+		switch op {
+		case '+':
+			if s := li + ri; (s > li) == (ri > 0) {
+				return &literal{tag: phpTypeInteger, i: s}, nil
+			}
+		case '-':
+			if s := li - ri; (s < li) == (ri > 0) {
+				return &literal{tag: phpTypeInteger, i: s}, nil
+			}
+		case '*':
+			if li == 0 || ri == 0 {
+				return &literal{tag: phpTypeInteger}, nil
+			}
+			if s := li * ri; s/ri == li && !(li == -1 && ri == math.MinInt64) && !(ri == -1 && li == math.MinInt64) {
+				return &literal{tag: phpTypeInteger, i: s}, nil
+			}
+		case '/':
+			if ri != 0 && li%ri == 0 && !(li == math.MinInt64 && ri == -1) {
+				return &literal{tag: phpTypeInteger, i: li / ri}, nil
+			}
+		case '%':
+			if ri == 0 {
+				return nil, p.error("modulo by zero")
+			}
+			if ri == -1 {
+				return &literal{tag: phpTypeInteger}, nil
+			}
+			return &literal{tag: phpTypeInteger, i: li % ri}, nil
+		}
+	}
+
+	switch op {
+	case '+':
+		return &literal{tag: phpTypeFloat, f: lf + rf}, nil
+	case '-':
+		return &literal{tag: phpTypeFloat, f: lf - rf}, nil
+	case '*':
+		return &literal{tag: phpTypeFloat, f: lf * rf}, nil
+	case '/':
+		if rf == 0 {
+			return nil, p.error("division by zero")
+		}
+		return &literal{tag: phpTypeFloat, f: lf / rf}, nil
+	}
+	return nil, p.error("unsupported operand for '%'")
+
+}
+
+func (p *literalParser) number() (*literal, error) {
+
+	start := p.off
+	isFloat := false
+	base := 10
+	digits := start
+
+	if p.off+1 < len(p.data) && p.data[p.off] == '0' {
+		switch p.data[p.off+1] {
+		case 'x', 'X':
+			base = 16
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		}
+		if base != 10 {
+			p.off += 2
+			digits = p.off
+		}
+	}
+
+	for p.off < len(p.data) {
+		c := p.data[p.off]
+		switch {
+		case c >= '0' && c <= '9', c == '_':
+		case base == 16 && ((c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')):
+		case base == 10 && c == '.' && !isFloat && p.off+1 < len(p.data) && p.data[p.off+1] >= '0' && p.data[p.off+1] <= '9':
+			isFloat = true
+		case base == 10 && c == '.' && !isFloat && p.off > start:
+			// 1. is a float too
+			isFloat = true
+		case base == 10 && (c == 'e' || c == 'E'):
+			isFloat = true
+			if p.off+1 < len(p.data) && (p.data[p.off+1] == '+' || p.data[p.off+1] == '-') {
+				p.off++
+			}
+		default:
+			goto done
+		}
+		p.off++
+	}
+
+done:
+	text := strings.ReplaceAll(string(p.data[digits:p.off]), "_", "")
+	if text == "" {
+		return nil, p.error("invalid number")
+	}
+	if isFloat {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			p.off = start
+			return nil, p.error("invalid number")
+		}
+		return &literal{tag: phpTypeFloat, f: f}, nil
+	}
+
+	if base == 10 && len(text) > 1 && text[0] == '0' {
+		base = 8
+	}
+	u, err := strconv.ParseUint(text, base, 64)
+	if err == nil && u <= math.MaxInt64 {
+		return &literal{tag: phpTypeInteger, i: int64(u)}, nil
+	}
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err != strconv.ErrRange {
+		p.off = start
+		return nil, p.error("invalid number")
+	}
+
+	// php turns integers out of range into floats
+	f := 0.0
+	for i := 0; i < len(text); i++ {
+		f = f*float64(base) + float64(hexValue(text[i]))
+	}
+	return &literal{tag: phpTypeFloat, f: f}, nil
+
+}
+
+func (p *literalParser) singleQuoted() (*literal, error) {
+
+	p.off++
+	var b []byte
+	for p.off < len(p.data) {
+		c := p.data[p.off]
+		switch {
+		case c == '\'':
+			p.off++
+			return &literal{tag: phpTypeString, s: string(b)}, nil
+		case c == '\\' && p.off+1 < len(p.data) && (p.data[p.off+1] == '\'' || p.data[p.off+1] == '\\'):
+			b = append(b, p.data[p.off+1])
+			p.off += 2
+		default:
+			b = append(b, c)
+			p.off++
+		}
+	}
+	return nil, p.error("")
+
+}
+
+func (p *literalParser) doubleQuoted() (*literal, error) {
+
+	p.off++
+	var b []byte
+	for p.off < len(p.data) {
+
+		c := p.data[p.off]
+		switch {
+		case c == '"':
+			p.off++
+			return &literal{tag: phpTypeString, s: string(b)}, nil
+		case c == '$' && p.off+1 < len(p.data) && (isIdentByte(p.data[p.off+1]) || p.data[p.off+1] == '{') && !(p.data[p.off+1] >= '0' && p.data[p.off+1] <= '9'):
+			return nil, p.error("variables in strings are not supported")
+		case c != '\\' || p.off+1 >= len(p.data):
+			b = append(b, c)
+			p.off++
+			continue
+		}
+
+		p.off++
+		c = p.data[p.off]
+		p.off++
+		switch c {
+		case 'n':
+			b = append(b, '\n')
+		case 't':
+			b = append(b, '\t')
+		case 'r':
+			b = append(b, '\r')
+		case 'v':
+			b = append(b, '\v')
+		case 'e':
+			b = append(b, 0x1b)
+		case 'f':
+			b = append(b, '\f')
+		case '\\', '$', '"':
+			b = append(b, c)
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			n := int(c - '0')
+			for i := 0; i < 2 && p.off < len(p.data) && p.data[p.off] >= '0' && p.data[p.off] <= '7'; i++ {
+				n = n*8 + int(p.data[p.off]-'0')
+				p.off++
+			}
+			b = append(b, byte(n))
+		case 'x':
+			n, i := 0, 0
+			for ; i < 2 && p.off < len(p.data) && isHex(p.data[p.off]); i++ {
+				n = n*16 + hexValue(p.data[p.off])
+				p.off++
+			}
+			if i == 0 {
+				b = append(b, '\\', 'x')
+			} else {
+				b = append(b, byte(n))
+			}
+		case 'u':
+			if p.off >= len(p.data) || p.data[p.off] != '{' {
+				b = append(b, '\\', 'u')
+				break
+			}
+			end := bytes.IndexByte(p.data[p.off:], '}')
+			if end < 2 {
+				return nil, p.error("invalid unicode escape")
+			}
+			r, err := strconv.ParseUint(string(p.data[p.off+1:p.off+end]), 16, 32)
+			if err != nil || r > utf8.MaxRune {
+				return nil, p.error("invalid unicode escape")
+			}
+			b = utf8.AppendRune(b, rune(r))
+			p.off += end + 1
+		default:
+			b = append(b, '\\', c)
+		}
+
+	}
+	return nil, p.error("")
+
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexValue(c byte) int {
+
+	switch {
+	case c >= 'a':
+		return int(c-'a') + 10
+	case c >= 'A':
+		return int(c-'A') + 10
+	}
+	return int(c - '0')
+
+}
+
+func (l *literal) appendSerialized(b []byte) []byte {
+
+	switch l.tag {
+	case phpTypeNull:
+		return append(b, phpNullValue...)
+	case phpTypeBoolean:
+		if l.b {
+			return append(b, "b:1;"...)
+		}
+		return append(b, "b:0;"...)
+	case phpTypeInteger:
+		b = append(b, byte(phpTypeInteger), phpSeparator)
+		b = strconv.AppendInt(b, l.i, 10)
+		return append(b, phpTerminator)
+	case phpTypeFloat:
+		data, _ := Marshal(l.f)
+		return append(b, data...)
+	case phpTypeString:
+		return appendPHPString(b, []byte(l.s))
+	}
+
+	var body []byte
+	for _, e := range l.entries {
+		body = e.key.appendSerialized(body)
+		body = e.value.appendSerialized(body)
+	}
+	return appendContainer(b, l.tag, l.class, len(l.entries), body)
+
+}
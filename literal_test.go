@@ -0,0 +1,147 @@
+package phpserialize
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestVarExportToSerialized(t *testing.T) {
+
+	defer func(precision int) { SerializePrecision = precision }(SerializePrecision)
+	SerializePrecision = -1
+
+	testList := []struct {
+		source string
+		expect string
+	}{
+		{`NULL`, `N;`},
+		{`<?php return TRUE;`, `b:1;`},
+		{"<?php\n\ndeclare(strict_types=1);\n\n// config\nreturn -5;\n", `i:-5;`},
+		{`0x1F + 0b11 + 0o17 + 017 + 1_000`, `i:1064;`},
+		{`-9223372036854775807-1`, `i:-9223372036854775808;`},
+		{`PHP_INT_MAX + 1`, `d:9.223372036854776E+18;`},
+		{`60 * 60 * 24`, `i:86400;`},
+		{`7 / 2`, `d:3.5;`},
+		{`1.5e3`, `d:1500;`},
+		{`'it\'s \n \\'`, `s:9:"it's \n \";`},
+		{`"tab\there \x41\101\u{e9} \$x"`, "s:16:\"tab\there AAé $x\";"},
+		{`'a' . "\0" . 'b'`, "s:3:\"a\x00b\";"},
+		{`'v' . 2 . PHP_EOL`, "s:3:\"v2\n\";"},
+		{`[]`, `a:0:{}`},
+		{`array (
+  0 => 'a',
+  'k' => 
+  array (
+    0 => 1,
+  ),
+)`, `a:2:{i:0;s:1:"a";s:1:"k";a:1:{i:0;i:1;}}`},
+		{`['a', 5 => 'b', 'c', '7' => 'd', 'e', true => 'f', 'a' => 1, /* dup */ 0 => 'g',]`,
+			`a:7:{i:0;s:1:"g";i:5;s:1:"b";i:6;s:1:"c";i:7;s:1:"d";i:8;s:1:"e";i:1;s:1:"f";s:1:"a";i:1;}`},
+		{`\Foo\Bar::__set_state(array(
+   'a' => 1,
+))`, `O:7:"Foo\Bar":1:{s:1:"a";i:1;}`},
+		{`(object) array(
+   'a' => 1,
+   0 => 2,
+)`, `O:8:"stdClass":2:{s:1:"a";i:1;s:1:"0";i:2;}`},
+		{`(array) (object) ['1' => 2]`, `a:1:{i:1;i:2;}`},
+		{`(array('x'))`, `a:1:{i:0;s:1:"x";}`},
+		{`Foo::class`, `s:3:"Foo";`},
+	}
+
+	for i, test := range testList {
+
+		data, err := VarExportToSerialized([]byte(test.source))
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(data) != test.expect {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.expect, data)
+		}
+
+	}
+
+	for _, source := range []string{`[1, 2`, `env('APP_KEY')`, `"hello $name"`, `$x`, `return [1]`, `[1] [2]`, `'open`, `[[1] => 2]`, `1 % 0`, `Foo::BAR`} {
+		if _, err := VarExportToSerialized([]byte(source)); err == nil {
+			t.Fatalf("expect an error for %s", source)
+		}
+	}
+
+}
+
+func TestVarExportToSerialized_Truncated(t *testing.T) {
+
+	for _, source := range []string{`\`, `'abc\`, `"abc\`, `[1 => `} {
+		_, err := VarExportToSerialized([]byte(source))
+		if err == nil || err.Error() != "unexpected end of input" {
+			t.Fatalf("expect unexpected end of input for %s got %v", source, err)
+		}
+		var v interface{}
+		if err = UnmarshalVarExport([]byte(source), &v); err == nil {
+			t.Fatalf("expect an error for %s", source)
+		}
+	}
+
+	// every prefix fails cleanly
+	source := `<?php return ['a' => "x\n", \Foo\Bar::__set_state(array('b' => 0x1F, 'c' => -1.5e3)), (object) [true, null]];`
+	for i := 0; i < len(source); i++ {
+		if _, err := VarExportToSerialized([]byte(source[:i])); err == nil {
+			t.Fatalf("expect an error for %s", source[:i])
+		}
+	}
+
+}
+
+func TestUnmarshalVarExport(t *testing.T) {
+
+	type Redis struct {
+		Host string `php:"host"`
+		Port int    `php:"port"`
+	}
+	type Config struct {
+		Default string           `php:"default"`
+		Stores  map[string]Redis `php:"stores"`
+		Ratio   float64          `php:"ratio"`
+		Tags    []string         `php:"tags"`
+	}
+
+	source := `<?php return array (
+  'default' => 'redis',
+  'stores' => 
+  array (
+    'redis' => 
+    array (
+      'host' => '127.0.0.1',
+      'port' => 6379,
+    ),
+  ),
+  'ratio' => INF,
+  'tags' => ['a', 'b'],
+);
+`
+	var c Config
+	if err := UnmarshalVarExport([]byte(source), &c); err != nil {
+		t.Fatal(err)
+	}
+	expect := Config{"redis", map[string]Redis{"redis": {"127.0.0.1", 6379}}, math.Inf(1), []string{"a", "b"}}
+	if !reflect.DeepEqual(c, expect) {
+		t.Fatalf("expect:%v got %v", expect, c)
+	}
+
+	// var_export output reads back into the same value
+	queue, count := "default", 3
+	in := testVisibility{Name: "a", Queue: &queue, Secret: "it's\x00", Count: &count}
+	data, err := MarshalVarExport(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out testVisibility
+	if err = UnmarshalVarExport(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("expect:%v got %v", in, out)
+	}
+
+}
@@ -0,0 +1,150 @@
+package phpserialize
+
+import "errors"
+
+var errCorruptLZF = errors.New("php serialize: corrupt lzf or fastlz data")
+
+const (
+	lzfMaxLiteral  = 32
+	lzfMaxOffset   = 8191
+	lzfMaxLength   = 264
+	lzfHashBits    = 13
+	fastlzL2Offset = 8191
+)
+
+// lzfCompress compresses data in the LZF format, which is also FastLZ level
+// 1 as it always starts with a literal run.
+func lzfCompress(data []byte) []byte {
+
+	out := make([]byte, 0, len(data)+len(data)/32+1)
+	var table [1 << lzfHashBits]int
+
+	literal := 0 // start of the pending literal run
+	flush := func(end int) {
+		for literal < end {
+			n := end - literal
+			if n > lzfMaxLiteral {
+				n = lzfMaxLiteral
+			}
+			out = append(out, byte(n-1))
+			out = append(out, data[literal:literal+n]...)
+			literal += n
+		}
+	}
+
+	for i := 0; i+2 < len(data); {
+
+		h := (uint32(data[i])<<16 | uint32(data[i+1])<<8 | uint32(data[i+2])) * 2654435761 >> (32 - lzfHashBits)
+		ref := table[h] - 1
+		table[h] = i + 1
+
+		distance := i - ref - 1
+		if ref < 0 || distance > lzfMaxOffset || data[ref] != data[i] || data[ref+1] != data[i+1] || data[ref+2] != data[i+2] {
+			i++
+			continue
+		}
+
+		n := 3
+		for i+n < len(data) && n < lzfMaxLength && data[ref+n] == data[i+n] {
+			n++
+		}
+
+		flush(i)
+		length := n - 2
+		if length < 7 {
+			out = append(out, byte(length<<5|distance>>8))
+		} else {
+			out = append(out, byte(7<<5|distance>>8), byte(length-7))
+		}
+		out = append(out, byte(distance))
+
+		i += n
+		literal = i
+
+	}
+
+	flush(len(data))
+	return out
+
+}
+
+// lzfDecompress decompresses LZF data, and FastLZ data when fastlz is set.
+func lzfDecompress(data []byte, fastlz bool) ([]byte, error) {
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	level2 := false
+	if fastlz {
+		switch data[0] >> 5 {
+		case 0:
+		case 1:
+			level2 = true
+		default:
+			return nil, errCorruptLZF
+		}
+	}
+
+	out := make([]byte, 0, len(data)*2)
+	for i := 0; i < len(data); {
+
+		ctrl := int(data[i])
+		if i == 0 && fastlz {
+			ctrl &= 31
+		}
+		i++
+
+		if ctrl < 32 {
+			n := ctrl + 1
+			if i+n > len(data) {
+				return nil, errCorruptLZF
+			}
+			out = append(out, data[i:i+n]...)
+			i += n
+			continue
+		}
+
+		length := ctrl >> 5
+		distance := (ctrl & 31) << 8
+		if length == 7 {
+			for {
+				if i >= len(data) {
+					return nil, errCorruptLZF
+				}
+				code := int(data[i])
+				i++
+				length += code
+				if !level2 || code != 255 {
+					break
+				}
+			}
+		}
+		if i >= len(data) {
+			return nil, errCorruptLZF
+		}
+		code := int(data[i])
+		i++
+		distance += code
+
+		if level2 && code == 255 && distance == 31<<8|255 {
+			if i+2 > len(data) {
+				return nil, errCorruptLZF
+			}
+			distance = (int(data[i])<<8 | int(data[i+1])) + fastlzL2Offset
+			i += 2
+		}
+
+		ref := len(out) - distance - 1
+		if ref < 0 {
+			return nil, errCorruptLZF
+		}
+		for n := length + 2; n > 0; n-- {
+			out = append(out, out[ref])
+			ref++
+		}
+
+	}
+	return out, nil
+
+}
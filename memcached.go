@@ -0,0 +1,256 @@
+package phpserialize
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// Flags of the php memcached extension, the type of the value is in the low
+// 4 bits.
+const (
+	MemcachedString     uint32 = 0
+	MemcachedLong       uint32 = 1
+	MemcachedDouble     uint32 = 2
+	MemcachedBool       uint32 = 3
+	MemcachedSerialized uint32 = 4
+	MemcachedIgbinary   uint32 = 5
+	MemcachedJSON       uint32 = 6
+	MemcachedMsgpack    uint32 = 7
+
+	MemcachedCompressed  uint32 = 0x10
+	MemcachedZlib        uint32 = 0x20
+	MemcachedFastLZ      uint32 = 0x40
+	memcachedTypeMask    uint32 = 0xf
+	memcachedUserFlags   uint32 = 0xffff0000
+	memcachedUserFlagsAt        = 16
+)
+
+type CacheSerializer int
+
+const (
+	CacheSerializerPHP CacheSerializer = iota
+	CacheSerializerJSON
+	CacheSerializerIgbinary
+	// CacheSerializerNone stores values as strings, only for phpredis
+	CacheSerializerNone
+)
+
+type Compression int
+
+const (
+	CompressionNone Compression = iota
+	CompressionFastLZ
+	CompressionZlib
+	CompressionLZF
+)
+
+var ErrUnsupportedFormat = errors.New("php serialize: unsupported cache value format")
+
+// MemcachedCodec reads and writes values the way the php memcached extension
+// stores them. The zero value serializes with php serialize and does not
+// compress.
+type MemcachedCodec struct {
+	Serializer  CacheSerializer
+	Compression Compression // CompressionFastLZ or CompressionZlib
+	// values are compressed from CompressionThreshold bytes on and kept
+	// compressed when that makes them CompressionFactor times smaller, the
+	// php defaults are 2000 and 1.3
+	CompressionThreshold int
+	CompressionFactor    float64
+	// UserFlags are stored in the high 16 bits of the flags
+	UserFlags uint16
+}
+
+// Decode decodes the value stored with flags into v.
+func (c *MemcachedCodec) Decode(flags uint32, data []byte, v interface{}) error {
+
+	if flags&MemcachedCompressed != 0 {
+		if len(data) < 4 {
+			return errCorruptLZF
+		}
+		size := binary.LittleEndian.Uint32(data)
+		var err error
+		if flags&MemcachedFastLZ != 0 {
+			data, err = lzfDecompress(data[4:], true)
+		} else {
+			data, err = zlibDecompress(data[4:])
+		}
+		if err != nil {
+			return err
+		}
+		if uint32(len(data)) != size {
+			return errCorruptLZF
+		}
+	}
+
+	switch flags & memcachedTypeMask {
+	case MemcachedString:
+		return Unmarshal(stringValue(data), v)
+	case MemcachedLong:
+		if _, err := strconv.ParseInt(string(data), 10, 64); err != nil {
+			return &SyntaxError{"invalid memcached long value " + strconv.Quote(string(data)), 0}
+		}
+		return Unmarshal(scalarValue(phpTypeInteger, data), v)
+	case MemcachedDouble:
+		if _, err := strconv.ParseFloat(string(data), 64); err != nil {
+			return &SyntaxError{"invalid memcached double value " + strconv.Quote(string(data)), 0}
+		}
+		return Unmarshal(scalarValue(phpTypeFloat, data), v)
+	case MemcachedBool:
+		if len(data) > 0 && data[0] == '1' {
+			return Unmarshal([]byte("b:1;"), v)
+		}
+		return Unmarshal([]byte("b:0;"), v)
+	case MemcachedSerialized:
+		return Unmarshal(data, v)
+	case MemcachedIgbinary:
+		return UnmarshalIgbinary(data, v)
+	case MemcachedJSON:
+		return json.Unmarshal(data, v)
+	}
+	return ErrUnsupportedFormat
+
+}
+
+// Encode returns the flags and data the php memcached extension would store
+// for v.
+func (c *MemcachedCodec) Encode(v interface{}) (uint32, []byte, error) {
+
+	flags, data, err := c.encodeValue(v)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	threshold := c.CompressionThreshold
+	if threshold == 0 {
+		threshold = 2000
+	}
+	factor := c.CompressionFactor
+	if factor == 0 {
+		factor = 1.3
+	}
+
+	if c.Compression != CompressionNone && len(data) >= threshold {
+
+		var compressed []byte
+		compression := MemcachedZlib
+		switch c.Compression {
+		case CompressionFastLZ:
+			compressed, compression = lzfCompress(data), MemcachedFastLZ
+		case CompressionZlib:
+			compressed = zlibCompress(data)
+		default:
+			return 0, nil, ErrUnsupportedFormat
+		}
+
+		if float64(len(data)) > float64(len(compressed))*factor {
+			buf := make([]byte, 4, 4+len(compressed))
+			binary.LittleEndian.PutUint32(buf, uint32(len(data)))
+			data = append(buf, compressed...)
+			flags |= MemcachedCompressed | compression
+		}
+
+	}
+
+	return flags | uint32(c.UserFlags)<<memcachedUserFlagsAt, data, nil
+
+}
+
+func (c *MemcachedCodec) encodeValue(v interface{}) (uint32, []byte, error) {
+
+	rv := reflect.ValueOf(v)
+	for rv.IsValid() && (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if rv.IsValid() {
+		switch rv.Kind() {
+		case reflect.String:
+			return MemcachedString, []byte(rv.String()), nil
+		case reflect.Slice:
+			if rv.Type().Elem().Kind() == reflect.Uint8 {
+				return MemcachedString, rv.Bytes(), nil
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return MemcachedLong, strconv.AppendInt(nil, rv.Int(), 10), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return MemcachedLong, strconv.AppendUint(nil, rv.Uint(), 10), nil
+		case reflect.Float32, reflect.Float64:
+			data, err := Marshal(rv.Interface())
+			if err != nil {
+				return 0, nil, err
+			}
+			return MemcachedDouble, data[2 : len(data)-1], nil
+		case reflect.Bool:
+			if rv.Bool() {
+				return MemcachedBool, []byte("1"), nil
+			}
+			return MemcachedBool, []byte{}, nil
+		}
+	}
+
+	switch c.Serializer {
+	case CacheSerializerPHP:
+		data, err := Marshal(v)
+		return MemcachedSerialized, data, err
+	case CacheSerializerIgbinary:
+		data, err := MarshalIgbinary(v)
+		return MemcachedIgbinary, data, err
+	case CacheSerializerJSON:
+		data, err := json.Marshal(v)
+		return MemcachedJSON, data, err
+	}
+	return 0, nil, ErrUnsupportedFormat
+
+}
+
+// MemcachedUserFlags returns the user flags stored in the high 16 bits.
+func MemcachedUserFlags(flags uint32) uint16 {
+	return uint16((flags & memcachedUserFlags) >> memcachedUserFlagsAt)
+}
+
+func stringValue(data []byte) []byte {
+
+	e := newEncodeState()
+	defer encodeStatePool.Put(e)
+
+	stringEncoderRaw(e, string(data))
+	return append([]byte(nil), e.Bytes()...)
+
+}
+
+func scalarValue(tag phpValueType, data []byte) []byte {
+
+	b := make([]byte, 0, len(data)+3)
+	b = append(b, byte(tag), phpSeparator)
+	b = append(b, data...)
+	return append(b, phpTerminator)
+
+}
+
+func zlibCompress(data []byte) []byte {
+
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+
+}
+
+func zlibDecompress(data []byte) ([]byte, error) {
+
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+
+}
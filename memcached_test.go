@@ -0,0 +1,128 @@
+package phpserialize
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestLZF(t *testing.T) {
+
+	testList := []struct {
+		data   string
+		fastlz bool
+		expect string
+	}{
+		{"\x02abc\x80\x02", false, "abcabcabc"},
+		{"\x22abc\x80\x02", true, "abcabcabc"},
+		{"\x00a\xe0\x05\x00", false, "aaaaaaaaaaaaaaa"},
+	}
+
+	for i, test := range testList {
+		result, err := lzfDecompress([]byte(test.data), test.fastlz)
+		if err != nil || string(result) != test.expect {
+			t.Fatalf("Test fail at index %d, expect:%s got %s %v", i, test.expect, result, err)
+		}
+	}
+
+	random := make([]byte, 5000)
+	rand.New(rand.NewSource(1)).Read(random)
+	for i, data := range [][]byte{nil, []byte("ab"), []byte(strings.Repeat("php serialize ", 1000)), random} {
+		for _, fastlz := range []bool{false, true} {
+			result, err := lzfDecompress(lzfCompress(data), fastlz)
+			if err != nil || !bytes.Equal(result, data) {
+				t.Fatalf("Test fail at index %d, round trip failed: %v", i, err)
+			}
+		}
+	}
+
+	if _, err := lzfDecompress([]byte("\x05ab"), false); err == nil {
+		t.Fatal("expect an error for a short literal run")
+	}
+
+}
+
+func TestMemcachedCodec(t *testing.T) {
+
+	testList := []struct {
+		value interface{}
+		flags uint32
+		data  string
+	}{
+		{"hello", MemcachedString, "hello"},
+		{42, MemcachedLong, "42"},
+		{1.5, MemcachedDouble, "1.5"},
+		{true, MemcachedBool, "1"},
+		{false, MemcachedBool, ""},
+		{map[string]int{"a": 1}, MemcachedSerialized, `a:1:{s:1:"a";i:1;}`},
+		{nil, MemcachedSerialized, "N;"},
+	}
+
+	var c MemcachedCodec
+	for i, test := range testList {
+
+		flags, data, err := c.Encode(test.value)
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if flags != test.flags || string(data) != test.data {
+			t.Fatalf("Test fail at index %d, expect:%d %q got %d %q", i, test.flags, test.data, flags, data)
+		}
+
+		var v interface{}
+		if err = c.Decode(flags, data, &v); err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+
+	}
+
+	var n int
+	if err := c.Decode(MemcachedLong, []byte("-7"), &n); err != nil || n != -7 {
+		t.Fatalf("expect -7 got %d %v", n, err)
+	}
+	var s string
+	if err := c.Decode(MemcachedString, []byte(`a"b`), &s); err != nil || s != `a"b` {
+		t.Fatalf("expect a\"b got %s %v", s, err)
+	}
+	if err := c.Decode(MemcachedIgbinary, []byte("\x00\x00\x00\x02\x11\x02ok"), &s); err != nil || s != "ok" {
+		t.Fatalf("expect ok got %s %v", s, err)
+	}
+	if err := c.Decode(MemcachedMsgpack, []byte{0x90}, &s); err != ErrUnsupportedFormat {
+		t.Fatalf("expect ErrUnsupportedFormat got %v", err)
+	}
+
+	flags, data, err := (&MemcachedCodec{Serializer: CacheSerializerIgbinary}).Encode([]int{1})
+	if err != nil || flags != MemcachedIgbinary || string(data) != "\x00\x00\x00\x02\x14\x01\x06\x00\x06\x01" {
+		t.Fatalf("expect igbinary array got %d %q %v", flags, data, err)
+	}
+
+}
+
+func TestMemcachedCodec_Compression(t *testing.T) {
+
+	value := strings.Repeat("cache ", 1000)
+	for _, compression := range []Compression{CompressionFastLZ, CompressionZlib} {
+
+		c := MemcachedCodec{Compression: compression, UserFlags: 3}
+		flags, data, err := c.Encode(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if flags&MemcachedCompressed == 0 || len(data) >= len(value) || MemcachedUserFlags(flags) != 3 {
+			t.Fatalf("expect a compressed value, flags %x, %d bytes", flags, len(data))
+		}
+
+		var s string
+		if err = c.Decode(flags, data, &s); err != nil || s != value {
+			t.Fatalf("round trip failed: %v", err)
+		}
+
+		// small values are not compressed
+		if flags, _, _ = c.Encode("small"); flags&MemcachedCompressed != 0 {
+			t.Fatalf("expect small values to stay uncompressed, flags %x", flags)
+		}
+
+	}
+
+}
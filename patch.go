@@ -0,0 +1,286 @@
+package phpserialize
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+type patchOp int
+
+const (
+	patchSet patchOp = iota
+	patchDelete
+	patchAppend
+)
+
+// Set replaces the value at path with the serialized form of value, or adds
+// it when the last key of path is missing. Only the bytes of the value and
+// the counts and lengths of the enclosing arrays, objects and custom values
+// change, the rest of data stays as it is.
+func Set(data []byte, path []interface{}, value interface{}) ([]byte, error) {
+
+	b, err := Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return patch(data, path, patchSet, b)
+
+}
+
+func Delete(data []byte, path []interface{}) ([]byte, error) {
+	return patch(data, path, patchDelete, nil)
+}
+
+// Append adds value to the array at path under the next free integer key.
+func Append(data []byte, path []interface{}, value interface{}) ([]byte, error) {
+
+	b, err := Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return patch(data, path, patchAppend, b)
+
+}
+
+func patch(data []byte, path []interface{}, op patchOp, value []byte) ([]byte, error) {
+
+	n, err := valueLength(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := patchValue(data[:n], path, path, op, value)
+	if err != nil {
+		return nil, err
+	}
+	return append(result, data[n:]...), nil
+
+}
+
+func patchValue(v []byte, path, fullPath []interface{}, op patchOp, value []byte) ([]byte, error) {
+
+	depth := len(fullPath) - len(path)
+
+	if len(path) == 0 && op == patchSet {
+		return value, nil
+	}
+
+	c, err := parseContainer(v)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, &PathError{Path: pathTo(fullPath, depth)}
+	}
+
+	// the data of custom values is patched when it is a serialized value
+	if c.tag == phpTypeCustom {
+
+		data := v[c.bodyStart : len(v)-1]
+		if n, err := valueLength(data); err != nil || n != len(data) {
+			return nil, &PathError{Path: pathTo(fullPath, depth)}
+		}
+
+		newData, err := patchValue(data, path, fullPath, op, value)
+		if err != nil {
+			return nil, err
+		}
+		return c.splice(v, len(newData), c.bodyStart, len(v)-1, newData), nil
+
+	}
+
+	if len(path) == 0 {
+		if op == patchAppend && c.tag == phpTypeArray {
+			return appendEntry(v, c, value), nil
+		}
+		return nil, &PathError{Path: fullPath}
+	}
+
+	object := c.tag == phpTypeObject
+	for _, entry := range c.entries {
+
+		if !matchKey(entry.key, path[0], object) {
+			continue
+		}
+
+		if len(path) == 1 && op == patchDelete {
+			return c.splice(v, len(c.entries)-1, entry.start, entry.end, nil), nil
+		}
+
+		newValue, err := patchValue(v[entry.valueStart:entry.end], path[1:], fullPath, op, value)
+		if err != nil {
+			return nil, err
+		}
+		return c.splice(v, len(c.entries), entry.valueStart, entry.end, newValue), nil
+
+	}
+
+	if len(path) == 1 && op == patchSet {
+
+		e := newEncodeState()
+		defer encodeStatePool.Put(e)
+
+		if object {
+			stringEncoderRaw(e, keyString(path[0]))
+		} else {
+			writeArrayKey(e, reflectKey(path[0]))
+		}
+		e.Write(value)
+
+		end := len(v) - 1
+		return c.splice(v, len(c.entries)+1, end, end, e.Bytes()), nil
+
+	}
+
+	return nil, &PathError{Path: pathTo(fullPath, depth)}
+
+}
+
+// pathTo returns the path up to the key at depth, all of it when the path
+// ends before, like at a scalar value.
+func pathTo(path []interface{}, depth int) []interface{} {
+
+	if depth+1 > len(path) {
+		return path
+	}
+	return path[:depth+1]
+
+}
+
+func appendEntry(v []byte, c *rawContainer, value []byte) []byte {
+
+	next, found := int64(0), false
+	for _, entry := range c.entries {
+		if n, ok := entry.key.(int64); ok && (!found || n >= next) {
+			next, found = n+1, true
+		}
+	}
+
+	e := newEncodeState()
+	defer encodeStatePool.Put(e)
+
+	e.writeTag(phpTypeInteger)
+	e.Write(strconv.AppendInt(e.scratch[:0], next, 10))
+	e.WriteByte(phpTerminator)
+	e.Write(value)
+
+	end := len(v) - 1
+	return c.splice(v, len(c.entries)+1, end, end, e.Bytes())
+
+}
+
+type rawEntry struct {
+	key        interface{}
+	start      int
+	valueStart int
+	end        int
+}
+
+// rawContainer holds the offsets of an array, object or custom value. For
+// custom values count is the length of the data and there are no entries.
+type rawContainer struct {
+	tag        phpValueType
+	countStart int
+	countEnd   int
+	bodyStart  int
+	entries    []rawEntry
+}
+
+func parseContainer(v []byte) (*rawContainer, error) {
+
+	var d decodeState
+	d.init(v)
+	d.scan.reset()
+
+	c := &rawContainer{}
+	switch d.scanNext() {
+	case scanBeginArray, scanBeginObject:
+
+		c.tag = phpValueType(v[0])
+		d.scanUntil(scanEndKeyValueLength)
+		if d.parserState == scanError {
+			return nil, d.scan.err
+		}
+		c.countEnd = d.readIndex()
+		c.countStart = bytes.LastIndexByte(v[:c.countEnd], phpSeparator) + 1
+		arrayLength := d.scan.lastLength() / 2
+
+		if d.scanNext() == scanError { //skip {
+			return nil, d.scan.err
+		}
+		c.bodyStart = d.off
+
+		for index := 0; index < arrayLength; index++ {
+
+			start := d.off
+			key, err := d.rawKey()
+			if err != nil {
+				return nil, err
+			}
+			valueStart := d.off
+			if err = d.jump(); err != nil {
+				return nil, err
+			}
+			c.entries = append(c.entries, rawEntry{key, start, valueStart, d.off})
+
+		}
+
+	case scanBeginCustom:
+
+		c.tag = phpTypeCustom
+		if _, err := d.customHeader(); err != nil {
+			return nil, err
+		}
+		c.bodyStart = d.off
+		c.countEnd = d.readIndex() - 1
+		c.countStart = bytes.LastIndexByte(v[:c.countEnd], phpSeparator) + 1
+
+	case scanError:
+		return nil, d.scan.err
+
+	default:
+		return nil, nil
+	}
+
+	return c, nil
+
+}
+
+// splice replaces v[start:end] with data and writes count into the header.
+func (c *rawContainer) splice(v []byte, count int, start, end int, data []byte) []byte {
+
+	result := make([]byte, 0, len(v)+len(data)+8)
+	result = append(result, v[:c.countStart]...)
+	result = strconv.AppendInt(result, int64(count), 10)
+	result = append(result, v[c.countEnd:start]...)
+	result = append(result, data...)
+	result = append(result, v[end:]...)
+	return result
+
+}
+
+func keyString(key interface{}) string {
+
+	switch k := normalizeArrayKey(key).(type) {
+	case int64:
+		return strconv.FormatInt(k, 10)
+	case string:
+		return k
+	}
+	return fmt.Sprint(key)
+
+}
+
+func reflectKey(key interface{}) reflect.Value {
+
+	switch k := normalizeArrayKey(key).(type) {
+	case int64:
+		return reflect.ValueOf(k)
+	case string:
+		return reflect.ValueOf(k)
+	}
+	return reflect.ValueOf(fmt.Sprint(key))
+
+}
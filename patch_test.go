@@ -0,0 +1,126 @@
+package phpserialize
+
+import (
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+
+	data := "a:3:{s:1:\"f\";d:0.1000000000000000055511151231257827;s:4:\"user\";O:4:\"User\":2:{s:8:\"\u0000User\u0000id\";i:9;s:4:\"tags\";a:2:{i:3;s:1:\"a\";i:4;s:1:\"b\";}}" +
+		"s:6:\"custom\";C:3:\"Box\":18:{a:1:{i:0;s:1:\"x\";}}}"
+
+	type testEntry struct {
+		Op     patchOp
+		Path   []interface{}
+		Value  interface{}
+		Result string
+	}
+
+	testEntries := []testEntry{
+		{
+			Op:     patchSet,
+			Path:   []interface{}{"user", "id"},
+			Value:  "abc",
+			Result: "a:3:{s:1:\"f\";d:0.1000000000000000055511151231257827;s:4:\"user\";O:4:\"User\":2:{s:8:\"\u0000User\u0000id\";s:3:\"abc\";s:4:\"tags\";a:2:{i:3;s:1:\"a\";i:4;s:1:\"b\";}}s:6:\"custom\";C:3:\"Box\":18:{a:1:{i:0;s:1:\"x\";}}}",
+		},
+		{
+			Op:     patchSet,
+			Path:   []interface{}{"user", "name"},
+			Value:  "bob",
+			Result: "a:3:{s:1:\"f\";d:0.1000000000000000055511151231257827;s:4:\"user\";O:4:\"User\":3:{s:8:\"\u0000User\u0000id\";i:9;s:4:\"tags\";a:2:{i:3;s:1:\"a\";i:4;s:1:\"b\";}s:4:\"name\";s:3:\"bob\";}s:6:\"custom\";C:3:\"Box\":18:{a:1:{i:0;s:1:\"x\";}}}",
+		},
+		{
+			Op:     patchDelete,
+			Path:   []interface{}{"user", "tags", 3},
+			Result: "a:3:{s:1:\"f\";d:0.1000000000000000055511151231257827;s:4:\"user\";O:4:\"User\":2:{s:8:\"\u0000User\u0000id\";i:9;s:4:\"tags\";a:1:{i:4;s:1:\"b\";}}s:6:\"custom\";C:3:\"Box\":18:{a:1:{i:0;s:1:\"x\";}}}",
+		},
+		{
+			Op:     patchAppend,
+			Path:   []interface{}{"user", "tags"},
+			Value:  "c",
+			Result: "a:3:{s:1:\"f\";d:0.1000000000000000055511151231257827;s:4:\"user\";O:4:\"User\":2:{s:8:\"\u0000User\u0000id\";i:9;s:4:\"tags\";a:3:{i:3;s:1:\"a\";i:4;s:1:\"b\";i:5;s:1:\"c\";}}s:6:\"custom\";C:3:\"Box\":18:{a:1:{i:0;s:1:\"x\";}}}",
+		},
+		{
+			Op:     patchAppend,
+			Path:   []interface{}{"custom"},
+			Value:  10,
+			Result: "a:3:{s:1:\"f\";d:0.1000000000000000055511151231257827;s:4:\"user\";O:4:\"User\":2:{s:8:\"\u0000User\u0000id\";i:9;s:4:\"tags\";a:2:{i:3;s:1:\"a\";i:4;s:1:\"b\";}}s:6:\"custom\";C:3:\"Box\":27:{a:2:{i:0;s:1:\"x\";i:1;i:10;}}}",
+		},
+		{
+			Op:     patchDelete,
+			Path:   []interface{}{"f"},
+			Result: "a:2:{s:4:\"user\";O:4:\"User\":2:{s:8:\"\u0000User\u0000id\";i:9;s:4:\"tags\";a:2:{i:3;s:1:\"a\";i:4;s:1:\"b\";}}s:6:\"custom\";C:3:\"Box\":18:{a:1:{i:0;s:1:\"x\";}}}",
+		},
+	}
+
+	for index, entry := range testEntries {
+
+		var result []byte
+		var err error
+		switch entry.Op {
+		case patchSet:
+			result, err = Set([]byte(data), entry.Path, entry.Value)
+		case patchDelete:
+			result, err = Delete([]byte(data), entry.Path)
+		case patchAppend:
+			result, err = Append([]byte(data), entry.Path, entry.Value)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(result) != entry.Result {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", index, entry.Result, result)
+		}
+		if !Valid(result) {
+			t.Fatalf("Test fail at index %d, invalid result %s", index, result)
+		}
+
+	}
+
+	_, err := Set([]byte(data), []interface{}{"missing", "key"}, 1)
+	if _, ok := err.(*PathError); !ok {
+		t.Fatalf("expect PathError, got %v", err)
+	}
+
+	raw, err := GetRaw([]byte(data), "custom", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := Decode[string](raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x != "x" {
+		t.Fatalf("unexpected value %s", x)
+	}
+
+}
+
+func TestPatch_Scalar(t *testing.T) {
+
+	data := []byte(`a:1:{s:1:"a";i:1;}`)
+
+	if result, err := Set([]byte("i:1;"), nil, 2); err != nil || string(result) != "i:2;" {
+		t.Fatalf("expect:i:2; got %s %v", result, err)
+	}
+
+	testList := []func() ([]byte, error){
+		func() ([]byte, error) { return Set([]byte("i:1;"), []interface{}{"a"}, 2) },
+		func() ([]byte, error) { return Delete([]byte("i:1;"), nil) },
+		func() ([]byte, error) { return Append([]byte("i:1;"), nil, 2) },
+		func() ([]byte, error) { return Set(data, []interface{}{"a", "b"}, 2) },
+		func() ([]byte, error) { return Delete(data, []interface{}{"a", "b"}) },
+		func() ([]byte, error) { return Append(data, []interface{}{"a"}, 2) },
+		func() ([]byte, error) { return Delete(data, nil) },
+	}
+
+	for i, test := range testList {
+
+		_, err := test()
+		if _, ok := err.(*PathError); !ok {
+			t.Fatalf("Test fail at index %d, expect PathError, got %v", i, err)
+		}
+
+	}
+
+}
@@ -0,0 +1,250 @@
+package phpserialize
+
+import (
+	"strconv"
+)
+
+// GetRaw returns the raw bytes of the value at path in the serialized data,
+// without decoding it. Values in front of the wanted one are jumped over.
+// Keys are array keys or property names, which match private and protected
+// properties by their plain name too. The result is a slice of data.
+func GetRaw(data []byte, path ...interface{}) (RawMessage, error) {
+	return getRaw(data, path)
+}
+
+func getRaw(data []byte, path []interface{}) (RawMessage, error) {
+
+	var d decodeState
+	d.init(data)
+	d.scan.reset()
+
+	for i, key := range path {
+		found, err := d.enterKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, &PathError{Path: path[:i+1]}
+		}
+	}
+
+	start := d.off
+	if err := d.jump(); err != nil {
+		return nil, err
+	}
+	return d.data[start:d.off], nil
+
+}
+
+// enterKey reads the header of the next array or object and moves to the
+// value stored under key, jumping over all the values in front of it.
+func (d *decodeState) enterKey(key interface{}) (bool, error) {
+
+	object := false
+	switch d.scanNext() {
+	case scanBeginArray:
+	case scanBeginObject:
+		object = true
+	case scanBeginCustom:
+		return d.enterCustom(key)
+	case scanError:
+		return false, d.scan.err
+	default:
+		return false, nil
+	}
+
+	d.scanUntil(scanEndKeyValueLength)
+	if d.parserState == scanError {
+		return false, d.scan.err
+	}
+	arrayLength := d.scan.lastLength() / 2
+
+	if d.scanNext() == scanError { //skip {
+		return false, d.scan.err
+	}
+
+	for index := 0; index < arrayLength; index++ {
+
+		k, err := d.rawKey()
+		if err != nil {
+			return false, err
+		}
+		if matchKey(k, key, object) {
+			return true, nil
+		}
+		if err = d.jump(); err != nil {
+			return false, err
+		}
+
+	}
+	return false, nil
+
+}
+
+// enterCustom continues the lookup in the data of a custom value, as long as
+// the data is a serialized value itself.
+func (d *decodeState) enterCustom(key interface{}) (bool, error) {
+
+	length, err := d.customHeader()
+	if err != nil {
+		return false, err
+	}
+	if d.off+length > len(d.data) {
+		return false, &SyntaxError{"unexpected end of php serialize data", int64(len(d.data))}
+	}
+
+	data := d.data[d.off : d.off+length]
+	if n, err := valueLength(data); err != nil || n != len(data) {
+		return false, nil
+	}
+
+	d.init(data)
+	d.scan.reset()
+	return d.enterKey(key)
+
+}
+
+// customHeader moves over the class name of a custom value and returns the
+// length of its data, which starts at d.off.
+func (d *decodeState) customHeader() (int, error) {
+
+	d.scanUntil(scanEndValueLength)
+	if d.parserState == scanError {
+		return 0, d.scan.err
+	}
+	if d.scanNext() == scanError { //skip "
+		return 0, d.scan.err
+	}
+	d.off += d.scan.takeLastLength()
+
+	d.scanUntil(scanEndValueLength)
+	if d.parserState == scanError {
+		return 0, d.scan.err
+	}
+	if d.scanNext() == scanError { //skip {
+		return 0, d.scan.err
+	}
+	return d.scan.lastLength(), nil
+
+}
+
+// valueLength returns the length of the first serialized value in data.
+func valueLength(data []byte) (int, error) {
+
+	var d decodeState
+	d.init(data)
+	d.scan.reset()
+
+	if err := d.jump(); err != nil {
+		return 0, err
+	}
+	if d.off > len(data) {
+		return 0, &SyntaxError{"unexpected end of php serialize data", int64(len(data))}
+	}
+	return d.off, nil
+
+}
+
+func (d *decodeState) rawKey() (interface{}, error) {
+
+	switch d.scanNext() {
+	case scanBeginScalarValue:
+	case scanError:
+		return nil, d.scan.err
+	default:
+		return nil, &SyntaxError{"array key is not a scalar value", int64(d.readIndex())}
+	}
+
+	tag := phpValueType(d.data[d.readIndex()])
+	data := d.dataBetween(scanInScalarValue, scanEndScalarValue)
+	if d.parserState == scanError {
+		return nil, d.scan.err
+	}
+
+	switch tag {
+	case phpTypeInteger:
+		n, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return nil, &SyntaxError{"invalid integer array key", int64(d.readIndex())}
+		}
+		return n, nil
+	case phpTypeString:
+		if d.scanNext() == scanError { //skip ;
+			return nil, d.scan.err
+		}
+		return string(data), nil
+	}
+	return nil, &SyntaxError{"array key is not an integer or string", int64(d.readIndex())}
+
+}
+
+// jump skips the next value like skip does, but moves over strings, class
+// names and custom data by their declared length instead of scanning them.
+func (d *decodeState) jump() error {
+
+	depth := d.scan.parserDepth()
+	for {
+
+		switch d.scanNext() {
+		case scanError:
+			return d.scan.err
+		case scanEndValueLength:
+			if d.scanNext() == scanError { //skip " or {
+				return d.scan.err
+			}
+			d.off += d.scan.takeLastLength()
+			if d.off > len(d.data) {
+				return &SyntaxError{"unexpected end of php serialize data", int64(len(d.data))}
+			}
+		}
+
+		if d.scan.parserDepth() <= depth {
+			return nil
+		}
+
+	}
+
+}
+
+func matchKey(k interface{}, key interface{}, object bool) bool {
+
+	want := normalizeArrayKey(key)
+	if k == want {
+		return true
+	}
+
+	s, ok := k.(string)
+	if !object || !ok {
+		return false
+	}
+
+	var name string
+	switch w := want.(type) {
+	case string:
+		name = w
+	case int64:
+		name = strconv.FormatInt(w, 10)
+	default:
+		return false
+	}
+
+	property, _ := unmangleProperty(s)
+	return s == name || property == name
+
+}
+
+// unmangleProperty splits an object property name into the property and the
+// class it belongs to, "*" for protected and "" for public properties.
+func unmangleProperty(key string) (string, string) {
+
+	if len(key) < 3 || key[0] != 0 {
+		return key, ""
+	}
+	for i := 1; i < len(key); i++ {
+		if key[i] == 0 {
+			return key[i+1:], key[1:i]
+		}
+	}
+	return key, ""
+
+}
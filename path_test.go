@@ -0,0 +1,86 @@
+package phpserialize
+
+import (
+	"testing"
+)
+
+func TestGet_Raw(t *testing.T) {
+
+	data := []byte("a:3:{s:4:\"skip\";a:1:{i:0;s:9:\"}\"a:1:{};\";}s:15:\"_sf2_attributes\";a:2:{s:7:\"user_id\";i:42;i:7;s:3:\"odd\";}" +
+		"s:4:\"user\";O:4:\"User\":2:{s:8:\"\u0000User\u0000id\";i:9;s:7:\"\u0000*\u0000name\";s:3:\"bob\";}}")
+
+	type testEntry struct {
+		Path   []interface{}
+		Result string
+	}
+
+	testEntries := []testEntry{
+		{
+			Path:   []interface{}{"_sf2_attributes", "user_id"},
+			Result: "i:42;",
+		},
+		{
+			Path:   []interface{}{"_sf2_attributes", "7"},
+			Result: "s:3:\"odd\";",
+		},
+		{
+			Path:   []interface{}{"_sf2_attributes", 7},
+			Result: "s:3:\"odd\";",
+		},
+		{
+			Path:   []interface{}{"user", "id"},
+			Result: "i:9;",
+		},
+		{
+			Path:   []interface{}{"user", "name"},
+			Result: "s:3:\"bob\";",
+		},
+		{
+			Path:   []interface{}{"user", "\u0000*\u0000name"},
+			Result: "s:3:\"bob\";",
+		},
+		{
+			Path:   []interface{}{"skip"},
+			Result: "a:1:{i:0;s:9:\"}\"a:1:{};\";}",
+		},
+		{
+			Path:   nil,
+			Result: string(data),
+		},
+	}
+
+	for index, entry := range testEntries {
+
+		result, err := GetRaw(data, entry.Path...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(result) != entry.Result {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", index, entry.Result, result)
+		}
+
+	}
+
+	raw, err := GetRaw(data, "_sf2_attributes", "user_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := Decode[int](raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 42 {
+		t.Fatalf("unexpected id %d", id)
+	}
+
+	_, err = GetRaw(data, "_sf2_attributes", "user_id", "x")
+	if _, ok := err.(*PathError); !ok {
+		t.Fatalf("expect PathError, got %v", err)
+	}
+
+	_, err = GetRaw(data[:40], "user")
+	if _, ok := err.(*SyntaxError); !ok {
+		t.Fatalf("expect SyntaxError, got %v", err)
+	}
+
+}
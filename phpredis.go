@@ -0,0 +1,109 @@
+package phpserialize
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+)
+
+// RedisCodec reads and writes values the way phpredis stores them with its
+// OPT_SERIALIZER and OPT_COMPRESSION options. phpredis does not mark how a
+// value is stored, so the options have to match the ones used in php. Note
+// that phpredis uses no serializer by default, CacheSerializerNone.
+type RedisCodec struct {
+	Serializer  CacheSerializer
+	Compression Compression // CompressionNone or CompressionLZF
+}
+
+func (c *RedisCodec) Decode(data []byte, v interface{}) error {
+
+	switch c.Compression {
+	case CompressionNone:
+	case CompressionLZF:
+		// like phpredis, data that doesn't decompress is used as it is
+		if d, err := lzfDecompress(data, false); err == nil {
+			data = d
+		}
+	default:
+		return ErrUnsupportedFormat
+	}
+
+	switch c.Serializer {
+	case CacheSerializerNone:
+		return Unmarshal(stringValue(data), v)
+	case CacheSerializerPHP:
+		return Unmarshal(data, v)
+	case CacheSerializerIgbinary:
+		return UnmarshalIgbinary(data, v)
+	case CacheSerializerJSON:
+		return json.Unmarshal(data, v)
+	}
+	return ErrUnsupportedFormat
+
+}
+
+func (c *RedisCodec) Encode(v interface{}) ([]byte, error) {
+
+	var data []byte
+	var err error
+
+	switch c.Serializer {
+	case CacheSerializerNone:
+		data, err = phpString(v)
+	case CacheSerializerPHP:
+		data, err = Marshal(v)
+	case CacheSerializerIgbinary:
+		data, err = MarshalIgbinary(v)
+	case CacheSerializerJSON:
+		data, err = json.Marshal(v)
+	default:
+		err = ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.Compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionLZF:
+		return lzfCompress(data), nil
+	}
+	return nil, ErrUnsupportedFormat
+
+}
+
+// phpString converts a scalar to a string like php does, other values are
+// unsupported.
+func phpString(v interface{}) ([]byte, error) {
+
+	rv := reflect.ValueOf(v)
+	for rv.IsValid() && (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || ((rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && rv.IsNil()) {
+		return []byte{}, nil
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return []byte(rv.String()), nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Bytes(), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(nil, rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.AppendUint(nil, rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return appendPrecisionFloat(nil, rv.Float()), nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return []byte("1"), nil
+		}
+		return []byte{}, nil
+	}
+	return nil, &UnsupportedTypeError{rv.Type()}
+
+}
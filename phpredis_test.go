@@ -0,0 +1,49 @@
+package phpserialize
+
+import "testing"
+
+func TestRedisCodec(t *testing.T) {
+
+	testList := []struct {
+		codec RedisCodec
+		value interface{}
+		data  string
+	}{
+		{RedisCodec{Serializer: CacheSerializerNone}, "hello", "hello"},
+		{RedisCodec{Serializer: CacheSerializerNone}, 12, "12"},
+		{RedisCodec{Serializer: CacheSerializerPHP}, "hello", `s:5:"hello";`},
+		{RedisCodec{Serializer: CacheSerializerPHP}, []int{1}, `a:1:{i:0;i:1;}`},
+		{RedisCodec{Serializer: CacheSerializerJSON}, []int{1}, `[1]`},
+		{RedisCodec{Serializer: CacheSerializerIgbinary}, "hello", "\x00\x00\x00\x02\x11\x05hello"},
+		{RedisCodec{Serializer: CacheSerializerPHP, Compression: CompressionLZF}, "hello", "\x0bs:5:\"hello\";"},
+	}
+
+	for i, test := range testList {
+
+		data, err := test.codec.Encode(test.value)
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(data) != test.data {
+			t.Fatalf("Test fail at index %d, expect:%q got %q", i, test.data, data)
+		}
+
+		var v interface{}
+		if err = test.codec.Decode(data, &v); err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+
+	}
+
+	// uncompressed data is read as it is, like phpredis does
+	var s string
+	c := RedisCodec{Serializer: CacheSerializerPHP, Compression: CompressionLZF}
+	if err := c.Decode([]byte(`s:2:"ok";`), &s); err != nil || s != "ok" {
+		t.Fatalf("expect ok got %s %v", s, err)
+	}
+
+	if _, err := (&RedisCodec{Serializer: CacheSerializerNone}).Encode(map[string]int{}); err == nil {
+		t.Fatal("expect an error for an array without a serializer")
+	}
+
+}
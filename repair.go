@@ -0,0 +1,359 @@
+package phpserialize
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+// Fix describes a declared length that Repair corrected.
+type Fix struct {
+	Offset   int64 // offset of the value in the input
+	Declared int
+	Actual   int
+}
+
+func (f Fix) String() string {
+	return "offset " + strconv.FormatInt(f.Offset, 10) + ": length " + strconv.Itoa(f.Declared) + " corrected to " + strconv.Itoa(f.Actual)
+}
+
+var errRepairTooComplex = errors.New("php serialize: data is too ambiguous to repair")
+
+const repairMaxCandidates = 16
+
+// Repair rewrites data with correct lengths for strings, class names and
+// custom data whose declared length no longer matches their content, as
+// left behind by a plain text search-replace or a charset conversion. The
+// real end of such a value is found by its closing delimiter and the
+// structure that follows it.
+func Repair(data []byte) ([]byte, []Fix, error) {
+
+	r := newRepairState(data)
+	err := r.value(0, func(off int) error {
+		if off != len(data) {
+			return r.syntaxError(off, "after top-level value")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return r.out, r.fixes, nil
+
+}
+
+// repairPrefix repairs the first value in data and returns it with the
+// number of input bytes it took.
+func repairPrefix(data []byte) ([]byte, int, []Fix, error) {
+
+	r := newRepairState(data)
+	n := 0
+	err := r.value(0, func(off int) error {
+		if !r.plausible(off) {
+			return r.syntaxError(off, "after top-level value")
+		}
+		n = off
+		return nil
+	})
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return r.out, n, r.fixes, nil
+
+}
+
+type repairState struct {
+	data   []byte
+	out    []byte
+	fixes  []Fix
+	budget int
+}
+
+type repairFunc func(off int) error
+
+func newRepairState(data []byte) *repairState {
+	return &repairState{data: data, out: make([]byte, 0, len(data)), budget: 4*len(data) + 1024}
+}
+
+func (r *repairState) syntaxError(off int, context string) error {
+
+	if off >= len(r.data) {
+		return &SyntaxError{"unexpected end of php serialize data", int64(len(r.data))}
+	}
+	return &SyntaxError{"invalid character " + quoteChar(r.data[off]) + " " + context + ", offset: " + strconv.Itoa(off), int64(off)}
+
+}
+
+// try runs f and drops everything f wrote when it fails.
+func (r *repairState) try(f func() error) error {
+
+	mark, fixes := len(r.out), len(r.fixes)
+	err := f()
+	if err != nil {
+		r.out = r.out[:mark]
+		r.fixes = r.fixes[:fixes]
+	}
+	return err
+
+}
+
+func (r *repairState) value(off int, k repairFunc) error {
+
+	r.budget--
+	if r.budget < 0 {
+		return errRepairTooComplex
+	}
+	if off+1 >= len(r.data) {
+		return r.syntaxError(len(r.data), "")
+	}
+
+	tag := phpValueType(r.data[off])
+	if tag == phpTypeNull {
+		if r.data[off+1] != phpTerminator {
+			return r.syntaxError(off+1, "in null value")
+		}
+		r.out = append(r.out, phpNullValue...)
+		return k(off + 2)
+	}
+
+	if r.data[off+1] != phpSeparator {
+		return r.syntaxError(off+1, ", expect ':'")
+	}
+
+	switch tag {
+	case phpTypeBoolean, phpTypeInteger, phpTypeFloat:
+		return r.scalar(off, tag, k)
+	case phpTypeString:
+		return r.quoted(off, off+2, phpDoubleQuote, "\";", r.plausible, func(start, end, next int) error {
+			r.out = append(r.out, "s:"...)
+			r.out = strconv.AppendInt(r.out, int64(end-start), 10)
+			r.out = append(r.out, ":\""...)
+			r.out = append(r.out, r.data[start:end]...)
+			r.out = append(r.out, "\";"...)
+			return k(next)
+		})
+	case phpTypeArray:
+		n, p, ok := r.number(off + 2)
+		if !ok || p >= len(r.data) || r.data[p] != phpLeftBraces {
+			return r.syntaxError(p, "in array length")
+		}
+		r.out = append(r.out, r.data[off:p+1]...)
+		return r.elements(p+1, n, k)
+	case phpTypeObject:
+		return r.quoted(off, off+2, phpDoubleQuote, "\":", r.isLength, func(start, end, next int) error {
+			n, p, ok := r.number(next)
+			if !ok || p >= len(r.data) || r.data[p] != phpLeftBraces {
+				return r.syntaxError(p, "in object length")
+			}
+			r.out = append(r.out, "O:"...)
+			r.out = strconv.AppendInt(r.out, int64(end-start), 10)
+			r.out = append(r.out, ":\""...)
+			r.out = append(r.out, r.data[start:end]...)
+			r.out = append(r.out, r.data[end:p+1]...)
+			return r.elements(p+1, n, k)
+		})
+	case phpTypeCustom:
+		return r.quoted(off, off+2, phpDoubleQuote, "\":", r.isLength, func(start, end, next int) error {
+			header := append([]byte("C:"), strconv.Itoa(end-start)...)
+			header = append(header, ":\""...)
+			header = append(header, r.data[start:end]...)
+			header = append(header, "\":"...)
+			return r.custom(off, next, header, k)
+		})
+	}
+	return r.syntaxError(off, "of php type identifier")
+
+}
+
+func (r *repairState) scalar(off int, tag phpValueType, k repairFunc) error {
+
+	p := off + 2
+	for p < len(r.data) && r.data[p] != phpTerminator {
+		c := r.data[p]
+		switch {
+		case c >= '0' && c <= '9', c == '-':
+		case tag != phpTypeBoolean && c == '+':
+		case tag == phpTypeFloat && bytes.IndexByte([]byte(".eEINFA"), c) >= 0:
+		default:
+			return r.syntaxError(p, "in scalar value")
+		}
+		p++
+	}
+	if p >= len(r.data) || p == off+2 {
+		return r.syntaxError(p, "in scalar value")
+	}
+	r.out = append(r.out, r.data[off:p+1]...)
+	return k(p + 1)
+
+}
+
+func (r *repairState) elements(off int, n int, k repairFunc) error {
+
+	if n == 0 {
+		if off >= len(r.data) || r.data[off] != phpRightBraces {
+			return r.syntaxError(off, "in array")
+		}
+		r.out = append(r.out, phpRightBraces)
+		return k(off + 1)
+	}
+
+	return r.value(off, func(off int) error {
+		return r.value(off, func(off int) error {
+			return r.elements(off, n-1, k)
+		})
+	})
+
+}
+
+// custom repairs the data of a custom value, which is tried as a serialized
+// value first and as opaque bytes ending at '}' after that.
+func (r *repairState) custom(off, lenOff int, header []byte, k repairFunc) error {
+
+	write := func(data []byte) {
+		r.out = append(r.out, header...)
+		r.out = strconv.AppendInt(r.out, int64(len(data)), 10)
+		r.out = append(r.out, phpSeparator, phpLeftBraces)
+		r.out = append(r.out, data...)
+		r.out = append(r.out, phpRightBraces)
+	}
+
+	declared, p, ok := r.number(lenOff)
+	if !ok || p >= len(r.data) || r.data[p] != phpLeftBraces {
+		return r.syntaxError(p, "in custom value length")
+	}
+	start := p + 1
+
+	if start+declared < len(r.data) && r.data[start+declared] == phpRightBraces && Valid(r.data[start:start+declared]) {
+		err := r.try(func() error {
+			write(r.data[start : start+declared])
+			return k(start + declared + 1)
+		})
+		if err == nil {
+			return nil
+		}
+	}
+
+	mark := len(r.out)
+	err := r.try(func() error {
+		return r.value(start, func(end int) error {
+			if end >= len(r.data) || r.data[end] != phpRightBraces {
+				return r.syntaxError(end, "after custom value")
+			}
+			data := append([]byte(nil), r.out[mark:]...)
+			r.out = r.out[:mark]
+			write(data)
+			if len(data) != declared {
+				r.fixes = append(r.fixes, Fix{Offset: int64(off), Declared: declared, Actual: len(data)})
+			}
+			return k(end + 1)
+		})
+	})
+	if err == nil || err == errRepairTooComplex {
+		return err
+	}
+
+	return r.quoted(off, lenOff, phpLeftBraces, "}", r.plausible, func(start, end, next int) error {
+		write(r.data[start:end])
+		return k(next)
+	})
+
+}
+
+// quoted handles "N:<open>data<end>" at lenOff for the value at off. Every
+// plausible end of data, the declared one first and then the closest ones,
+// is passed to f until f succeeds.
+func (r *repairState) quoted(off, lenOff int, open byte, end string, follow func(off int) bool, f func(start, end, next int) error) error {
+
+	declared, p, ok := r.number(lenOff)
+	if !ok || p >= len(r.data) || r.data[p] != open {
+		return r.syntaxError(p, "in value length")
+	}
+	start := p + 1
+
+	var err error
+	for _, stop := range r.candidates(start, declared, end, follow) {
+		err = r.try(func() error {
+			if stop-start != declared {
+				r.fixes = append(r.fixes, Fix{Offset: int64(off), Declared: declared, Actual: stop - start})
+			}
+			return f(start, stop, stop+len(end))
+		})
+		if err == nil || err == errRepairTooComplex {
+			return err
+		}
+	}
+	if err == nil {
+		err = r.syntaxError(start, "after value, no end of the value found")
+	}
+	return err
+
+}
+
+func (r *repairState) candidates(start, declared int, end string, follow func(off int) bool) []int {
+
+	var result []int
+
+	match := func(i int) bool {
+		return i >= start && bytes.HasPrefix(r.data[i:], []byte(end)) && follow(i+len(end))
+	}
+
+	want := start + declared
+	if want < len(r.data) && match(want) {
+		result = append(result, want)
+	}
+
+	for d := 1; len(result) < repairMaxCandidates; d++ {
+		below, above := want-d, want+d
+		if below < start && above >= len(r.data) {
+			break
+		}
+		if below >= start && below < len(r.data) && match(below) {
+			result = append(result, below)
+		}
+		if above < len(r.data) && match(above) {
+			result = append(result, above)
+		}
+	}
+	return result
+
+}
+
+// plausible reports whether something that may follow a value starts at off.
+func (r *repairState) plausible(off int) bool {
+
+	if off >= len(r.data) {
+		return true
+	}
+
+	switch phpValueType(r.data[off]) {
+	case phpTypeNull:
+		return off+1 < len(r.data) && r.data[off+1] == phpTerminator
+	case phpTypeBoolean, phpTypeInteger, phpTypeFloat, phpTypeString, phpTypeArray, phpTypeObject, phpTypeCustom:
+		return off+1 < len(r.data) && r.data[off+1] == phpSeparator
+	}
+	return r.data[off] == phpRightBraces
+
+}
+
+func (r *repairState) isLength(off int) bool {
+
+	_, _, ok := r.number(off)
+	return ok
+
+}
+
+// number reads digits followed by ':' and returns the number and the offset
+// after the ':'.
+func (r *repairState) number(off int) (int, int, bool) {
+
+	n, p := 0, off
+	for p < len(r.data) && r.data[p] >= '0' && r.data[p] <= '9' {
+		n = n*10 + int(r.data[p]-'0')
+		p++
+	}
+	if p == off || p >= len(r.data) || r.data[p] != phpSeparator {
+		return 0, p, false
+	}
+	return n, p + 1, true
+
+}
@@ -0,0 +1,105 @@
+package phpserialize
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+
+	testList := []struct {
+		data   string
+		expect string
+		fixes  []Fix
+	}{
+		{`s:5:"hello";`, `s:5:"hello";`, nil},
+		{`s:5:"héllo";`, `s:6:"héllo";`, []Fix{{0, 5, 6}}},
+		{`s:9:"a";`, `s:1:"a";`, []Fix{{0, 9, 1}}},
+		{
+			`a:2:{s:3:"url";s:18:"https://example.org";s:4:"home";s:18:"https://example.org/home";}`,
+			`a:2:{s:3:"url";s:19:"https://example.org";s:4:"home";s:24:"https://example.org/home";}`,
+			[]Fix{{15, 18, 19}, {53, 18, 24}},
+		},
+		{
+			`a:2:{i:0;s:3:"a";b";i:1;s:1:"c";}`,
+			`a:2:{i:0;s:4:"a";b";i:1;s:1:"c";}`,
+			[]Fix{{9, 3, 4}},
+		},
+		{
+			`s:30:"a:1:{s:3:"foo";s:4:"barz";}";`,
+			`s:27:"a:1:{s:3:"foo";s:4:"barz";}";`,
+			[]Fix{{0, 30, 27}},
+		},
+		{
+			`O:3:"Fooo":1:{s:4:"name";s:2:"Bob";}`,
+			`O:4:"Fooo":1:{s:4:"name";s:3:"Bob";}`,
+			[]Fix{{0, 3, 4}, {25, 2, 3}},
+		},
+		{
+			`C:11:"ArrayObject":20:{x:i:0;a:0:{};m:a:0:{}}`,
+			`C:11:"ArrayObject":21:{x:i:0;a:0:{};m:a:0:{}}`,
+			[]Fix{{0, 20, 21}},
+		},
+		{
+			`C:3:"Foo":18:{a:1:{i:0;s:1:"abc";}}`,
+			`C:3:"Foo":20:{a:1:{i:0;s:3:"abc";}}`,
+			[]Fix{{23, 1, 3}, {0, 18, 20}},
+		},
+	}
+
+	for i, test := range testList {
+
+		result, fixes, err := Repair([]byte(test.data))
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(result) != test.expect {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.expect, result)
+		}
+		if len(fixes) != len(test.fixes) {
+			t.Fatalf("Test fail at index %d, expect fixes:%v got %v", i, test.fixes, fixes)
+		}
+		for j := range fixes {
+			if fixes[j] != test.fixes[j] {
+				t.Fatalf("Test fail at index %d, expect fixes:%v got %v", i, test.fixes, fixes)
+			}
+		}
+		if !Valid(result) {
+			t.Fatalf("Test fail at index %d, result is not valid: %s", i, result)
+		}
+
+	}
+
+	for i, data := range []string{`s:5:"hello`, `a:2:{i:0;s:1:"a";}`, `x:1;`, `s:1:"a";i:0;`} {
+		if _, _, err := Repair([]byte(data)); err == nil {
+			t.Fatalf("Test fail at index %d, expect an error for %s", i, data)
+		}
+	}
+
+}
+
+func TestDecoder_Lenient(t *testing.T) {
+
+	dec := NewDecoder(strings.NewReader(`a:1:{s:4:"name";s:3:"José";}s:2:"ok";`))
+	dec.Lenient()
+
+	var m map[string]string
+	if err := dec.Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m["name"] != "José" {
+		t.Fatalf("expect José got %s", m["name"])
+	}
+	if fixes := dec.Fixes(); len(fixes) != 1 || fixes[0] != (Fix{16, 3, 5}) {
+		t.Fatalf("unexpected fixes %v", fixes)
+	}
+
+	var s string
+	if err := dec.Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s != "ok" || len(dec.Fixes()) != 0 {
+		t.Fatalf("expect ok without fixes got %s %v", s, dec.Fixes())
+	}
+
+}
@@ -0,0 +1,277 @@
+package phpserialize
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Replace replaces old with new in the string values of serialized data and
+// returns the result with the number of replacements. Serialized values in
+// strings are handled the same way, and all string, class name and custom
+// data lengths are recomputed. Array keys and property names are left alone.
+// Data that is not a serialized value is replaced as plain text.
+//
+// The data of custom values is replaced in when it is a single serialized
+// value or has the layout of the SPL classes, other custom data is left
+// untouched, see ReplaceReport.
+func Replace(data, old, new []byte) ([]byte, int) {
+
+	result, n, _ := ReplaceReport(data, old, new)
+	return result, n
+
+}
+
+// ReplaceReport is Replace that also returns the classes of the custom values
+// whose data was left untouched because its format is unknown.
+func ReplaceReport(data, old, new []byte) (result []byte, n int, skipped []string) {
+
+	if len(old) == 0 {
+		return data, 0, nil
+	}
+	r := replacer{old: old, new: new}
+	return r.replace(data), r.count, r.skipped
+
+}
+
+// isSerializedValue reports whether data is exactly one serialized value.
+func isSerializedValue(data []byte) bool {
+
+	n, err := valueLength(data)
+	return err == nil && n == len(data) && Valid(data)
+
+}
+
+type replacer struct {
+	old     []byte
+	new     []byte
+	count   int
+	skipped []string
+}
+
+func (r *replacer) replace(data []byte) []byte {
+
+	if isSerializedValue(data) {
+		out, _ := r.value(make([]byte, 0, len(data)), data, 0, true)
+		return out
+	}
+	n := bytes.Count(data, r.old)
+	if n == 0 {
+		return data
+	}
+	r.count += n
+	return bytes.ReplaceAll(data, r.old, r.new)
+
+}
+
+// value copies the valid serialized value at off to out, replacing in string
+// values when replace is set, and returns the offset after the value.
+func (r *replacer) value(out, data []byte, off int, replace bool) ([]byte, int) {
+
+	switch phpValueType(data[off]) {
+	case phpTypeString:
+		n, start := readLength(data, off+2)
+		s := data[start+1 : start+1+n]
+		if replace {
+			s = r.replace(s)
+		}
+		out = append(out, "s:"...)
+		out = strconv.AppendInt(out, int64(len(s)), 10)
+		out = append(out, ":\""...)
+		out = append(out, s...)
+		out = append(out, "\";"...)
+		return out, start + n + 3
+	case phpTypeArray:
+		n, start := readLength(data, off+2)
+		out = append(out, data[off:start+1]...)
+		return r.elements(out, data, start+1, n)
+	case phpTypeObject:
+		n, start := readLength(data, off+2)
+		n, start = readLength(data, start+n+3)
+		out = append(out, data[off:start+1]...)
+		return r.elements(out, data, start+1, n)
+	case phpTypeCustom:
+		n, start := readLength(data, off+2)
+		class := string(data[start+1 : start+1+n])
+		header := data[off : start+n+3]
+		n, start = readLength(data, start+n+3)
+		payload := data[start+1 : start+1+n]
+		if replace {
+			payload = r.custom(class, payload)
+		}
+		out = append(out, header...)
+		out = strconv.AppendInt(out, int64(len(payload)), 10)
+		out = append(out, phpSeparator, phpLeftBraces)
+		out = append(out, payload...)
+		out = append(out, phpRightBraces)
+		return out, start + n + 2
+	}
+
+	end := bytes.IndexByte(data[off:], phpTerminator) + off + 1
+	return append(out, data[off:end]...), end
+
+}
+
+func (r *replacer) elements(out, data []byte, off int, n int) ([]byte, int) {
+
+	for i := 0; i < n; i++ {
+		out, off = r.value(out, data, off, false) // key
+		out, off = r.value(out, data, off, true)
+	}
+	return append(out, phpRightBraces), off + 1
+
+}
+
+// custom replaces in the data of a custom value of class. Data that is not a
+// single serialized value nor SPL data is kept, its length may be part of the
+// format, and the class is reported as skipped.
+func (r *replacer) custom(class string, data []byte) []byte {
+
+	if isSerializedValue(data) {
+		return r.replace(data)
+	}
+
+	var layout func(s *splReplacer) error
+	switch class {
+	case "ArrayObject", "ArrayIterator", "RecursiveArrayIterator":
+		layout = (*splReplacer).arrayObject
+	case "SplObjectStorage":
+		layout = (*splReplacer).objectStorage
+	case "SplDoublyLinkedList", "SplQueue", "SplStack":
+		layout = (*splReplacer).doublyLinkedList
+	}
+
+	count, skipped := r.count, len(r.skipped)
+	if layout != nil {
+		s := splReplacer{splReader: splReader{data: data}, r: r}
+		if err := layout(&s); err == nil && s.off == len(data) {
+			return s.out
+		}
+	}
+	r.count, r.skipped = count, r.skipped[:skipped]
+	if bytes.Contains(data, r.old) {
+		r.skipped = append(r.skipped, class)
+	}
+	return data
+
+}
+
+// splReplacer copies the C: data of the SPL classes, replacing in the values
+// between the separators.
+type splReplacer struct {
+	splReader
+	r   *replacer
+	out []byte
+}
+
+func (s *splReplacer) expect(sep string) error {
+
+	if err := s.splReader.expect(sep); err != nil {
+		return err
+	}
+	s.out = append(s.out, sep...)
+	return nil
+
+}
+
+// value copies the next value, replacing in it, and returns it as it was read.
+func (s *splReplacer) value() ([]byte, error) {
+
+	n, err := valueLength(s.data[s.off:])
+	if err != nil {
+		return nil, err
+	}
+	v := s.data[s.off : s.off+n]
+	if !Valid(v) {
+		return nil, &SyntaxError{"php serialize: invalid value in SPL data, offset: " + strconv.Itoa(s.off), int64(s.off)}
+	}
+	s.out, _ = s.r.value(s.out, v, 0, true)
+	s.off += n
+	return v, nil
+
+}
+
+// x:i:flags;storage;m:members
+func (s *splReplacer) arrayObject() error {
+
+	if err := s.expect("x:"); err != nil {
+		return err
+	}
+	if _, err := s.value(); err != nil {
+		return err
+	}
+	if _, err := s.value(); err != nil {
+		return err
+	}
+	if err := s.expect(";m:"); err != nil {
+		return err
+	}
+	_, err := s.value()
+	return err
+
+}
+
+// x:i:n;object,info;...m:members
+func (s *splReplacer) objectStorage() error {
+
+	if err := s.expect("x:"); err != nil {
+		return err
+	}
+	v, err := s.value()
+	if err != nil {
+		return err
+	}
+	var n int
+	if err = Unmarshal(v, &n); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if _, err = s.value(); err != nil {
+			return err
+		}
+		if err = s.expect(","); err != nil {
+			return err
+		}
+		if _, err = s.value(); err != nil {
+			return err
+		}
+		if err = s.expect(";"); err != nil {
+			return err
+		}
+	}
+	if err = s.expect("m:"); err != nil {
+		return err
+	}
+	_, err = s.value()
+	return err
+
+}
+
+// i:flags;:value:value...
+func (s *splReplacer) doublyLinkedList() error {
+
+	if _, err := s.value(); err != nil {
+		return err
+	}
+	for s.off < len(s.data) {
+		if err := s.expect(":"); err != nil {
+			return err
+		}
+		if _, err := s.value(); err != nil {
+			return err
+		}
+	}
+	return nil
+
+}
+
+// readLength reads the digits at off and returns them with the offset of the
+// byte after the ':' that follows.
+func readLength(data []byte, off int) (int, int) {
+
+	n := 0
+	for ; data[off] != phpSeparator; off++ {
+		n = n*10 + int(data[off]-'0')
+	}
+	return n, off + 1
+
+}
@@ -0,0 +1,104 @@
+package phpserialize
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReplace(t *testing.T) {
+
+	testList := []struct {
+		data   string
+		expect string
+		count  int
+	}{
+		{`http://old.test/page`, `https://new.test/page`, 1},
+		{`s:15:"http://old.test";`, `s:16:"https://new.test";`, 1},
+		{
+			`a:2:{s:15:"http://old.test";s:15:"http://old.test";i:0;a:1:{i:0;s:20:"http://old.test/page";}}`,
+			`a:2:{s:15:"http://old.test";s:16:"https://new.test";i:0;a:1:{i:0;s:21:"https://new.test/page";}}`,
+			2,
+		},
+		{
+			`O:3:"Foo":1:{s:3:"url";s:33:"a:1:{i:0;s:15:"http://old.test";}";}`,
+			`O:3:"Foo":1:{s:3:"url";s:34:"a:1:{i:0;s:16:"https://new.test";}";}`,
+			1,
+		},
+		{
+			`C:3:"Foo":15:{http://old.test}`,
+			`C:3:"Foo":15:{http://old.test}`,
+			0,
+		},
+		{
+			`C:3:"Foo":33:{a:1:{i:0;s:15:"http://old.test";}}`,
+			`C:3:"Foo":34:{a:1:{i:0;s:16:"https://new.test";}}`,
+			1,
+		},
+		{`s:5:"other";`, `s:5:"other";`, 0},
+	}
+
+	for i, test := range testList {
+
+		result, count := Replace([]byte(test.data), []byte("http://old.test"), []byte("https://new.test"))
+		if string(result) != test.expect || count != test.count {
+			t.Fatalf("Test fail at index %d, expect:%s (%d) got %s (%d)", i, test.expect, test.count, result, count)
+		}
+
+	}
+
+}
+
+func TestReplace_Custom(t *testing.T) {
+
+	testList := []struct {
+		data    string
+		expect  string
+		count   int
+		skipped []string
+	}{
+		{
+			`C:11:"ArrayObject":48:{x:i:0;a:1:{i:0;s:15:"http://old.test";};m:a:0:{}}`,
+			`C:11:"ArrayObject":49:{x:i:0;a:1:{i:0;s:16:"https://new.test";};m:a:0:{}}`,
+			1, nil,
+		},
+		{
+			`C:16:"SplObjectStorage":58:{x:i:1;O:8:"stdClass":0:{},s:15:"http://old.test";;m:a:0:{}}`,
+			`C:16:"SplObjectStorage":59:{x:i:1;O:8:"stdClass":0:{},s:16:"https://new.test";;m:a:0:{}}`,
+			1, nil,
+		},
+		{
+			`C:8:"SplQueue":33:{i:4;:s:15:"http://old.test";:i:1;}`,
+			`C:8:"SplQueue":34:{i:4;:s:16:"https://new.test";:i:1;}`,
+			1, nil,
+		},
+		{
+			`a:2:{i:0;C:3:"Foo":15:{http://old.test}i:1;s:15:"http://old.test";}`,
+			`a:2:{i:0;C:3:"Foo":15:{http://old.test}i:1;s:16:"https://new.test";}`,
+			1, []string{"Foo"},
+		},
+		{
+			`C:11:"ArrayObject":29:{x:i:0;s:15:"http://old.test";}`,
+			`C:11:"ArrayObject":29:{x:i:0;s:15:"http://old.test";}`,
+			0, []string{"ArrayObject"},
+		},
+	}
+
+	for i, test := range testList {
+
+		result, count, skipped := ReplaceReport([]byte(test.data), []byte("http://old.test"), []byte("https://new.test"))
+		if string(result) != test.expect || count != test.count || !reflect.DeepEqual(skipped, test.skipped) {
+			t.Fatalf("Test fail at index %d, expect:%s (%d %v) got %s (%d %v)", i, test.expect, test.count, test.skipped, result, count, skipped)
+		}
+		if !Valid(result) {
+			t.Fatalf("Test fail at index %d, result is not valid: %s", i, result)
+		}
+
+	}
+
+	var a ArrayObject[[]string]
+	result, _ := Replace([]byte(testList[0].data), []byte("http://old.test"), []byte("https://new.test"))
+	if err := Unmarshal(result, &a); err != nil || len(a.Storage) != 1 || a.Storage[0] != "https://new.test" {
+		t.Fatalf("Test fail, expect:https://new.test got %v (%v)", a.Storage, err)
+	}
+
+}
@@ -127,6 +127,14 @@ func (s *scanner) popParser() {
 
 }
 
+// switchParser replaces the current parser with p.
+func (s *scanner) switchParser(p parser) {
+
+	s.parserStack[len(s.parserStack)-1] = p
+	s.currentParser = p
+
+}
+
 func (s *scanner) parserDepth() int {
 	return len(s.parserStack)
 }
@@ -168,6 +176,15 @@ func (s *scanner) lastLength() int {
 
 }
 
+func (s *scanner) takeLastLength() int {
+
+	n := len(s.lengthStack) - 1
+	length := s.lengthStack[n]
+	s.lengthStack[n] = 0
+	return length
+
+}
+
 func (s *scanner) decreaseLastLength() int {
 
 	n := len(s.lengthStack) - 1
@@ -189,7 +206,7 @@ func parseValue(s *scanner, c byte) int {
 		s.useParser(separatorParser, intValueParser)
 		return scanBeginScalarValue
 	case phpTypeFloat:
-		s.useParser(separatorParser, floatValueParser)
+		s.useParser(separatorParser, floatBeginParser)
 		return scanBeginScalarValue
 	case phpTypeString:
 		s.useParser(separatorParser, valueLengthParser, doubleQuoteParser, stringValueParser)
@@ -251,8 +268,15 @@ func nullValueParser(s *scanner, c byte) int {
 func boolValueParser(s *scanner, c byte) int {
 
 	if c == '1' || c == '0' {
+		s.switchParser(boolEndParser)
 		return scanInScalarValue
 	}
+	return s.error(c, "in bool value")
+
+}
+
+// boolEndParser reads the terminator after the single digit of a bool.
+func boolEndParser(s *scanner, c byte) int {
 
 	if c == phpTerminator {
 		return s.parserEnd(scanEndScalarValue)
@@ -274,9 +298,58 @@ func intValueParser(s *scanner, c byte) int {
 
 }
 
+// floatBeginParser reads the first byte of a float, INF, -INF and NAN are
+// read by floatNameParser.
+func floatBeginParser(s *scanner, c byte) int {
+
+	switch c {
+	case '-':
+		s.switchParser(floatSignParser)
+		return scanInScalarValue
+	case 'I':
+		s.switchParser(floatNameParser("NF"))
+		return scanInScalarValue
+	case 'N':
+		s.switchParser(floatNameParser("AN"))
+		return scanInScalarValue
+	}
+	s.switchParser(floatValueParser)
+	return floatValueParser(s, c)
+
+}
+
+func floatSignParser(s *scanner, c byte) int {
+
+	if c == 'I' {
+		s.switchParser(floatNameParser("NF"))
+		return scanInScalarValue
+	}
+	s.switchParser(floatValueParser)
+	return floatValueParser(s, c)
+
+}
+
+// floatNameParser reads the rest of INF or NAN, then the terminator.
+func floatNameParser(rest string) parser {
+
+	return func(s *scanner, c byte) int {
+
+		if rest == "" && c == phpTerminator {
+			return s.parserEnd(scanEndScalarValue)
+		}
+		if rest == "" || c != rest[0] {
+			return s.error(c, "in float value")
+		}
+		s.switchParser(floatNameParser(rest[1:]))
+		return scanInScalarValue
+
+	}
+
+}
+
 func floatValueParser(s *scanner, c byte) int {
 
-	if (c >= '0' && c <= '9') || c == '-' || c == '.' || c == 'E' || c == 'e' {
+	if (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.' || c == 'E' || c == 'e' {
 		return scanInScalarValue
 	}
 
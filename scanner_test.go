@@ -22,6 +22,10 @@ func TestValid(t *testing.T) {
 		"d:51999999999999996980101120;",
 		"d:8.529000000000000015048907821909675090775407218185526836754222629322086390857293736189603805541992188E-22;",
 		"d:8.9999999999999995265585574287341141808127531476202420890331268310546875E-9;",
+		"d:1.0E+25;",
+		"d:INF;",
+		"d:-INF;",
+		"d:NAN;",
 		"s:5:\"hallo\";",
 		"a:4:{i:0;i:1;i:1;i:2;i:2;i:3;i:3;i:4;}",
 		"a:4:{i:0;s:1:\"1\";i:1;s:1:\"2\";i:2;s:1:\"3\";i:3;s:1:\"4\";}",
@@ -43,6 +47,28 @@ func TestValid(t *testing.T) {
 
 }
 
+func TestValid_Invalid(t *testing.T) {
+
+	testData := []string{
+		"d:1N2;",
+		"d:NAF;",
+		"d:IN;",
+		"d:INFF;",
+		"d:-NAN;",
+		"d:1INF;",
+		"a:1:{i:0;d:NA;}",
+	}
+
+	for index, data := range testData {
+
+		if Valid([]byte(data)) {
+			t.Fatalf("Test fail at index %d, %s is valid", index, data)
+		}
+
+	}
+
+}
+
 func TestScanEnd(t *testing.T) {
 
 	data := []byte("O:10:\"testObject\":1:{s:1:\"a\";s:5:\"hallo\";}")
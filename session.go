@@ -0,0 +1,195 @@
+package phpserialize
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// SessionHandler is the session.serialize_handler used for $_SESSION data.
+type SessionHandler int
+
+const (
+	// SessionPHP stores each variable as name|value.
+	SessionPHP SessionHandler = iota
+	// SessionPHPBinary stores each variable as a length byte, the name and
+	// the value.
+	SessionPHPBinary
+	// SessionPHPSerialize stores $_SESSION as one serialized array.
+	SessionPHPSerialize
+)
+
+const (
+	phpSessionDelimiter = '|'
+	phpSessionUndefined = '!'
+	phpBinaryUndefined  = 0x80
+	phpBinaryMaxName    = 0x7f
+)
+
+func (h SessionHandler) String() string {
+
+	switch h {
+	case SessionPHP:
+		return "php"
+	case SessionPHPBinary:
+		return "php_binary"
+	case SessionPHPSerialize:
+		return "php_serialize"
+	}
+	return "SessionHandler(" + strconv.Itoa(int(h)) + ")"
+
+}
+
+type SessionError struct {
+	Handler SessionHandler
+	Name    string
+	msg     string
+}
+
+func (e *SessionError) Error() string {
+	return "php serialize: " + e.Handler.String() + " session variable " + strconv.Quote(e.Name) + " " + e.msg
+}
+
+// SessionDecode decodes session data written with handler into v, which is
+// filled like an array holding the session variables.
+func SessionDecode(data []byte, handler SessionHandler, v interface{}) error {
+
+	if handler == SessionPHPSerialize {
+		return Unmarshal(data, v)
+	}
+
+	array, err := sessionArray(data, handler)
+	if err != nil {
+		return err
+	}
+
+	var d decodeState
+	d.init(array)
+	return d.unmarshal(v)
+
+}
+
+// sessionArray turns php and php_binary session data into a serialized
+// array of the session variables.
+func sessionArray(data []byte, handler SessionHandler) ([]byte, error) {
+
+	e := newEncodeState()
+	defer encodeStatePool.Put(e)
+
+	count := 0
+	for p := 0; p < len(data); {
+
+		var name []byte
+		undefined := false
+		if handler == SessionPHPBinary {
+			n := int(data[p] &^ phpBinaryUndefined)
+			undefined = data[p]&phpBinaryUndefined != 0
+			if p+1+n > len(data) {
+				return nil, &SyntaxError{"unexpected end of php session data", int64(len(data))}
+			}
+			name = data[p+1 : p+1+n]
+			p += 1 + n
+		} else {
+			i := bytes.IndexByte(data[p:], phpSessionDelimiter)
+			if i < 0 {
+				return nil, &SyntaxError{"unexpected end of php session data", int64(len(data))}
+			}
+			name = data[p : p+i]
+			p += i + 1
+			if len(name) > 0 && name[0] == phpSessionUndefined {
+				name, undefined = name[1:], true
+			}
+		}
+		if undefined {
+			continue
+		}
+
+		n, err := valueLength(data[p:])
+		if err != nil {
+			return nil, err
+		}
+		if !Valid(data[p : p+n]) {
+			return nil, checkValid(data[p:p+n], &scanner{})
+		}
+
+		writeArrayKey(e, reflectKey(string(name)))
+		e.Write(data[p : p+n])
+		p += n
+		count++
+
+	}
+
+	array := make([]byte, 0, e.Len()+16)
+	array = append(array, "a:"...)
+	array = strconv.AppendInt(array, int64(count), 10)
+	array = append(array, phpSeparator, phpLeftBraces)
+	array = append(array, e.Bytes()...)
+	return append(array, phpRightBraces), nil
+
+}
+
+// SessionEncode encodes v, a map or struct of session variables, the way
+// handler writes $_SESSION.
+func SessionEncode(v interface{}, handler SessionHandler) ([]byte, error) {
+
+	data, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// a nil map or pointer is an empty session
+	if string(data) == phpNullValue {
+		data = []byte("a:0:{}")
+	}
+
+	c, err := parseContainer(data)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || c.tag == phpTypeCustom {
+		return nil, &SessionError{Handler: handler, msg: "is not an array: " + string(data)}
+	}
+
+	e := newEncodeState()
+	defer encodeStatePool.Put(e)
+
+	if handler == SessionPHPSerialize {
+		e.writeTagAndLength(phpTypeArray, len(c.entries))
+		e.WriteByte(phpLeftBraces)
+	}
+
+	for _, entry := range c.entries {
+
+		name, ok := entry.key.(string)
+		if _, numeric := phpIntKey(name); numeric {
+			ok = false
+		}
+		if !ok && handler != SessionPHPSerialize {
+			return nil, &SessionError{Handler: handler, Name: keyString(entry.key), msg: "has a numeric name"}
+		}
+
+		switch handler {
+		case SessionPHP:
+			if bytes.IndexByte([]byte(name), phpSessionDelimiter) >= 0 || bytes.IndexByte([]byte(name), phpSessionUndefined) >= 0 {
+				return nil, &SessionError{Handler: handler, Name: name, msg: "contains '|' or '!'"}
+			}
+			e.WriteString(name)
+			e.WriteByte(phpSessionDelimiter)
+		case SessionPHPBinary:
+			if len(name) > phpBinaryMaxName {
+				return nil, &SessionError{Handler: handler, Name: name, msg: "is longer than 127 bytes"}
+			}
+			e.WriteByte(byte(len(name)))
+			e.WriteString(name)
+		default:
+			e.Write(data[entry.start:entry.valueStart])
+		}
+		e.Write(data[entry.valueStart:entry.end])
+
+	}
+
+	if handler == SessionPHPSerialize {
+		e.WriteByte(phpRightBraces)
+	}
+	return append([]byte(nil), e.Bytes()...), nil
+
+}
@@ -0,0 +1,167 @@
+package session
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const filePrefix = "sess_"
+
+// FileStore is the php files save handler. Sessions live in
+// <Path>/<a>/<b>/sess_<id> where the Depth directories are the first
+// characters of the id. Like php, FileStore does not create these
+// directories. Sessions are locked like php locks them, with flock or
+// LockFileEx on Windows, Open fails on other platforms.
+type FileStore struct {
+	Path  string
+	Depth int
+	Mode  os.FileMode
+}
+
+// NewFileStore reads a session.save_path value, "[N;[MODE;]]/path".
+func NewFileStore(savePath string) (*FileStore, error) {
+
+	s := &FileStore{Mode: 0600}
+
+	parts := strings.Split(savePath, ";")
+	s.Path = parts[len(parts)-1]
+	if len(parts) > 3 || s.Path == "" {
+		return nil, &os.PathError{Op: "open", Path: savePath, Err: os.ErrInvalid}
+	}
+	if len(parts) > 1 {
+		depth, err := strconv.Atoi(parts[0])
+		if err != nil || depth < 0 {
+			return nil, &os.PathError{Op: "open", Path: savePath, Err: os.ErrInvalid}
+		}
+		s.Depth = depth
+	}
+	if len(parts) > 2 {
+		mode, err := strconv.ParseUint(parts[1], 8, 32)
+		if err != nil {
+			return nil, &os.PathError{Op: "open", Path: savePath, Err: os.ErrInvalid}
+		}
+		s.Mode = os.FileMode(mode)
+	}
+	return s, nil
+
+}
+
+func (s *FileStore) file(id string) string {
+
+	path := s.Path
+	for i := 0; i < s.Depth && i < len(id); i++ {
+		path = filepath.Join(path, id[i:i+1])
+	}
+	return filepath.Join(path, filePrefix+id)
+
+}
+
+func (s *FileStore) Open(ctx context.Context, id string) (Handle, error) {
+
+	if !ValidID(id) || len(id) < s.Depth {
+		return nil, ErrInvalidID
+	}
+	if errLockUnsupported != nil {
+		return nil, errLockUnsupported
+	}
+
+	mode := s.Mode
+	if mode == 0 {
+		mode = 0600
+	}
+	f, err := os.OpenFile(s.file(id), os.O_CREATE|os.O_RDWR, mode)
+	if err != nil {
+		return nil, err
+	}
+	if err = lockFile(ctx, f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &fileHandle{f: f}, nil
+
+}
+
+func (s *FileStore) GC(ctx context.Context, maxLifetime time.Duration) (int, error) {
+	return s.cleanup(ctx, s.Path, s.Depth, time.Now().Add(-maxLifetime))
+}
+
+func (s *FileStore) cleanup(ctx context.Context, dir string, depth int, before time.Time) (int, error) {
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+
+		name := filepath.Join(dir, entry.Name())
+		if depth > 0 {
+			if entry.IsDir() {
+				n, err := s.cleanup(ctx, name, depth-1, before)
+				removed += n
+				if err != nil {
+					return removed, err
+				}
+			}
+			continue
+		}
+
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), filePrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(before) {
+			continue
+		}
+		if os.Remove(name) == nil {
+			removed++
+		}
+
+	}
+	return removed, nil
+
+}
+
+type fileHandle struct {
+	f *os.File
+}
+
+func (h *fileHandle) Read() ([]byte, error) {
+
+	if _, err := h.f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(h.f)
+
+}
+
+func (h *fileHandle) Write(data []byte) error {
+
+	if err := h.f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := h.f.WriteAt(data, 0)
+	return err
+
+}
+
+func (h *fileHandle) Destroy() error {
+	return os.Remove(h.f.Name())
+}
+
+func (h *fileHandle) Close() error {
+
+	unlockFile(h.f)
+	return h.f.Close()
+
+}
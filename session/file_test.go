@@ -0,0 +1,93 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewFileStore(t *testing.T) {
+
+	testList := []struct {
+		savePath string
+		expect   FileStore
+	}{
+		{"/tmp/sessions", FileStore{Path: "/tmp/sessions", Mode: 0600}},
+		{"2;/tmp/sessions", FileStore{Path: "/tmp/sessions", Depth: 2, Mode: 0600}},
+		{"1;0640;/tmp/sessions", FileStore{Path: "/tmp/sessions", Depth: 1, Mode: 0640}},
+	}
+
+	for i, test := range testList {
+		s, err := NewFileStore(test.savePath)
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if *s != test.expect {
+			t.Fatalf("Test fail at index %d, expect:%+v got %+v", i, test.expect, *s)
+		}
+	}
+
+	for i, savePath := range []string{"", "x;/tmp", "1;9;/tmp", "1;2;3;/tmp"} {
+		if _, err := NewFileStore(savePath); err == nil {
+			t.Fatalf("Test fail at index %d, expect an error for %q", i, savePath)
+		}
+	}
+
+}
+
+func TestFileStore(t *testing.T) {
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0700)
+	s := &FileStore{Path: dir, Depth: 2}
+	ctx := context.Background()
+
+	if _, err := s.Open(ctx, "../etc"); err != ErrInvalidID {
+		t.Fatalf("expect ErrInvalidID got %v", err)
+	}
+
+	h, err := s.Open(ctx, "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := h.Read(); err != nil || len(data) != 0 {
+		t.Fatalf("expect an empty session got %q %v", data, err)
+	}
+	if err = h.Write([]byte(`a|i:1;b|i:2;`)); err != nil {
+		t.Fatal(err)
+	}
+	if err = h.Write([]byte(`a|i:1;`)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the session stays locked until it is closed
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := s.Open(timeout, "abc123"); err != context.DeadlineExceeded {
+		t.Fatalf("expect the session to be locked, got %v", err)
+	}
+	h.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "a", "b", "sess_abc123"))
+	if err != nil || string(data) != `a|i:1;` {
+		t.Fatalf("unexpected session file %q %v", data, err)
+	}
+
+	h, err = s.Open(ctx, "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := h.Read(); err != nil || string(data) != `a|i:1;` {
+		t.Fatalf("unexpected session %q %v", data, err)
+	}
+	h.Close()
+
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, "a", "b", "sess_abc123"), old, old)
+	if n, err := s.GC(ctx, time.Minute); err != nil || n != 1 {
+		t.Fatalf("expect 1 removed session got %d %v", n, err)
+	}
+
+}
@@ -0,0 +1,21 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package session
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+)
+
+// errLockUnsupported is returned by FileStore.Open where session files
+// can't be locked the way php locks them, like on aix and solaris.
+var errLockUnsupported = errors.New("session: file locking is not supported on " + runtime.GOOS)
+
+func lockFile(ctx context.Context, f *os.File) error {
+	return errLockUnsupported
+}
+
+func unlockFile(f *os.File) {}
@@ -0,0 +1,42 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package session
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"time"
+)
+
+var errLockUnsupported error
+
+// lockFile takes an exclusive flock on f, the lock php takes on session
+// files, and waits for it until ctx is done.
+func lockFile(ctx context.Context, f *os.File) error {
+
+	wait := time.Millisecond
+	for {
+
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err != syscall.EWOULDBLOCK && err != syscall.EINTR {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		if wait < 100*time.Millisecond {
+			wait *= 2
+		}
+
+	}
+
+}
+
+func unlockFile(f *os.File) {
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,66 @@
+//go:build windows
+// +build windows
+
+package session
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var errLockUnsupported error
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+
+	errorLockViolation syscall.Errno = 33
+
+	// php locks the whole file
+	lockRange = 0xffffffff
+)
+
+// lockFile takes an exclusive LockFileEx lock on f, the lock php's flock
+// takes on Windows, and waits for it until ctx is done.
+func lockFile(ctx context.Context, f *os.File) error {
+
+	wait := time.Millisecond
+	for {
+
+		var ol syscall.Overlapped
+		r, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, lockRange, lockRange, uintptr(unsafe.Pointer(&ol)))
+		if r != 0 {
+			return nil
+		}
+		if err != errorLockViolation {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		if wait < 100*time.Millisecond {
+			wait *= 2
+		}
+
+	}
+
+}
+
+func unlockFile(f *os.File) {
+
+	var ol syscall.Overlapped
+	procUnlockFileEx.Call(f.Fd(), 0, lockRange, lockRange, uintptr(unsafe.Pointer(&ol)))
+
+}
@@ -0,0 +1,303 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"net/http"
+
+	"github.com/zengxinqian/phpserialize"
+)
+
+const DefaultCookieName = "PHPSESSID"
+
+// Manager loads the session of each request into a T and writes it back to
+// the Store when the handler changed it. The session stays locked while the
+// handler runs, the same as in php.
+type Manager[T any] struct {
+	Store   Store
+	Handler phpserialize.SessionHandler
+	// Cookie is the template of the session cookie, its Name defaults to
+	// PHPSESSID.
+	Cookie http.Cookie
+	// ErrorHandler is called when the session can't be loaded or saved,
+	// the default replies with 500 Internal Server Error. The session is
+	// saved before the response is written, a handler changing it after
+	// that gets a save error reported once the response is sent, which the
+	// default ignores.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+func NewManager[T any](store Store, handler phpserialize.SessionHandler) *Manager[T] {
+	return &Manager[T]{Store: store, Handler: handler, Cookie: http.Cookie{Name: DefaultCookieName, Path: "/", HttpOnly: true}}
+}
+
+type contextKey struct{}
+
+type requestSession struct {
+	id        string
+	value     interface{}
+	destroyed bool
+}
+
+// Get returns the session of the request, nil when the request didn't go
+// through a Manager[T].
+func Get[T any](r *http.Request) *T {
+
+	s, _ := r.Context().Value(contextKey{}).(*requestSession)
+	if s == nil {
+		return nil
+	}
+	v, _ := s.value.(*T)
+	return v
+
+}
+
+// ID returns the session id of the request.
+func ID(r *http.Request) string {
+
+	if s, _ := r.Context().Value(contextKey{}).(*requestSession); s != nil {
+		return s.id
+	}
+	return ""
+
+}
+
+// Destroy removes the session of the request from the store and expires its
+// cookie, before the response is written or once the handler returns.
+func Destroy(r *http.Request) {
+
+	if s, _ := r.Context().Value(contextKey{}).(*requestSession); s != nil {
+		s.destroyed = true
+	}
+
+}
+
+func (m *Manager[T]) Middleware(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		sw := &sessionWriter{ResponseWriter: w}
+		if err := m.serve(sw, r, next); err != nil && !sw.failed {
+			m.fail(w, r, err, sw.wrote)
+		}
+
+	})
+
+}
+
+func (m *Manager[T]) fail(w http.ResponseWriter, r *http.Request, err error, wrote bool) {
+
+	if m.ErrorHandler != nil {
+		m.ErrorHandler(w, r, err)
+		return
+	}
+	if !wrote {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+
+}
+
+func (m *Manager[T]) serve(w *sessionWriter, r *http.Request, next http.Handler) error {
+
+	name := m.Cookie.Name
+	if name == "" {
+		name = DefaultCookieName
+	}
+
+	id := ""
+	if c, err := r.Cookie(name); err == nil && ValidID(c.Value) {
+		id = c.Value
+	}
+	if id == "" {
+		var err error
+		if id, err = NewID(); err != nil {
+			return err
+		}
+		cookie := m.Cookie
+		cookie.Name, cookie.Value = name, id
+		http.SetCookie(w, &cookie)
+	}
+
+	h, err := m.Store.Open(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+
+	data, err := h.Read()
+	if err != nil {
+		return err
+	}
+
+	// raw keeps the variables T doesn't declare, only the variables the
+	// handler changed are written back over it
+	raw := phpserialize.RawMessage("a:0:{}")
+	if len(data) > 0 {
+		if err = phpserialize.SessionDecode(data, m.Handler, &raw); err != nil {
+			return err
+		}
+	}
+	value := new(T)
+	if err = phpserialize.Unmarshal(raw, value); err != nil {
+		return err
+	}
+	loaded, err := phpserialize.SessionEncode(value, phpserialize.SessionPHPSerialize)
+	if err != nil {
+		return err
+	}
+
+	s := &requestSession{id: id, value: value}
+	destroyed := false
+	save := func() error {
+
+		if destroyed {
+			return nil
+		}
+		if s.destroyed {
+			destroyed = true
+			cookie := m.Cookie
+			cookie.Name, cookie.MaxAge = name, -1
+			http.SetCookie(w.ResponseWriter, &cookie)
+			return h.Destroy()
+		}
+		saved, err := phpserialize.SessionEncode(value, phpserialize.SessionPHPSerialize)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(saved, loaded) {
+			return nil
+		}
+		patched, err := patchSession(raw, loaded, saved)
+		if err != nil {
+			return err
+		}
+		data, err := phpserialize.SessionEncode(phpserialize.RawMessage(patched), m.Handler)
+		if err != nil {
+			return err
+		}
+		if err = h.Write(data); err != nil {
+			return err
+		}
+		raw, loaded = patched, saved
+		return nil
+
+	}
+
+	// the session is saved before the response is written so that a failed
+	// save can still be reported, and again at the end when the handler
+	// changed it after writing
+	w.save = func() error {
+		err := save()
+		if err != nil {
+			m.fail(w.ResponseWriter, r, err, false)
+		}
+		return err
+	}
+	next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, s)))
+	if w.failed {
+		return nil
+	}
+	return save()
+
+}
+
+// sessionWriter saves the session before the response header is written.
+// When the save fails the error is reported instead of the response of the
+// handler, which is discarded.
+type sessionWriter struct {
+	http.ResponseWriter
+	save   func() error
+	wrote  bool
+	failed bool
+}
+
+func (w *sessionWriter) writeHeader() bool {
+
+	if !w.wrote {
+		w.wrote = true
+		w.failed = w.save != nil && w.save() != nil
+	}
+	return !w.failed
+
+}
+
+func (w *sessionWriter) WriteHeader(code int) {
+
+	if w.writeHeader() {
+		w.ResponseWriter.WriteHeader(code)
+	}
+
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+
+	if !w.writeHeader() {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+
+}
+
+func (w *sessionWriter) Flush() {
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && w.writeHeader() {
+		f.Flush()
+	}
+
+}
+
+// Unwrap returns the wrapped ResponseWriter, for http.ResponseController.
+func (w *sessionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// patchSession applies to the session array raw the changes from the
+// variables loaded to saved, both encoded with SessionPHPSerialize.
+func patchSession(raw, loaded, saved []byte) ([]byte, error) {
+
+	var before, after phpserialize.Map[string, phpserialize.RawMessage]
+	if err := phpserialize.Unmarshal(loaded, &before); err != nil {
+		return nil, err
+	}
+	if err := phpserialize.Unmarshal(saved, &after); err != nil {
+		return nil, err
+	}
+
+	var err error
+	for _, name := range before.Keys() {
+		if _, ok := after.Get(name); !ok {
+			if raw, err = phpserialize.Delete(raw, []interface{}{name}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, name := range after.Keys() {
+		value, _ := after.Get(name)
+		if old, ok := before.Get(name); ok && bytes.Equal(old, value) {
+			continue
+		}
+		if raw, err = phpserialize.Set(raw, []interface{}{name}, value); err != nil {
+			return nil, err
+		}
+	}
+	return raw, nil
+
+}
+
+const idChars = "0123456789abcdefghijklmnopqrstuv"
+
+// NewID returns a random session id of 32 characters, like the php default
+// with session.sid_bits_per_character=5.
+func NewID() (string, error) {
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = idChars[b[i]&31]
+	}
+	return string(b), nil
+
+}
@@ -0,0 +1,148 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zengxinqian/phpserialize"
+)
+
+type testSession struct {
+	UserID int    `php:"user_id"`
+	Name   string `php:"name"`
+}
+
+func TestManager(t *testing.T) {
+
+	dir := t.TempDir()
+	m := NewManager[testSession](&FileStore{Path: dir}, phpserialize.SessionPHP)
+	os.WriteFile(filepath.Join(dir, "sess_abc"), []byte(`user_id|i:7;name|s:3:"bob";`), 0600)
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := Get[testSession](r)
+		switch r.URL.Path {
+		case "/login":
+			s.UserID, s.Name = 42, "alice"
+		case "/logout":
+			Destroy(r)
+		}
+		w.Write([]byte(s.Name))
+	}))
+
+	// reading a session does not write it
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "PHPSESSID", Value: "abc"})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Body.String() != "bob" {
+		t.Fatalf("expect bob got %s", w.Body.String())
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "sess_abc")); string(data) != `user_id|i:7;name|s:3:"bob";` {
+		t.Fatalf("unexpected session file %q", data)
+	}
+
+	// a new session gets a cookie and is written when it changed
+	r = httptest.NewRequest("GET", "/login", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "PHPSESSID" || !ValidID(cookies[0].Value) {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "sess_"+cookies[0].Value))
+	if string(data) != `user_id|i:42;name|s:5:"alice";` {
+		t.Fatalf("unexpected session file %q", data)
+	}
+
+	r = httptest.NewRequest("GET", "/logout", nil)
+	r.AddCookie(cookies[0])
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if _, err := os.Stat(filepath.Join(dir, "sess_"+cookies[0].Value)); !os.IsNotExist(err) {
+		t.Fatalf("expect the session to be destroyed, got %v", err)
+	}
+
+}
+
+func TestManager_UndeclaredVariables(t *testing.T) {
+
+	dir := t.TempDir()
+	m := NewManager[testSession](&FileStore{Path: dir}, phpserialize.SessionPHP)
+	os.WriteFile(filepath.Join(dir, "sess_abc"), []byte(`cart|a:1:{i:0;i:3;}user_id|i:7;name|s:3:"bob";csrf|s:2:"xy";`), 0600)
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Get[testSession](r).Name = "alice"
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "PHPSESSID", Value: "abc"})
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	data, _ := os.ReadFile(filepath.Join(dir, "sess_abc"))
+	if string(data) != `cart|a:1:{i:0;i:3;}user_id|i:7;name|s:5:"alice";csrf|s:2:"xy";` {
+		t.Fatalf("unexpected session file %q", data)
+	}
+
+}
+
+func TestManager_Destroy(t *testing.T) {
+
+	dir := t.TempDir()
+	m := NewManager[testSession](&FileStore{Path: dir}, phpserialize.SessionPHP)
+	os.WriteFile(filepath.Join(dir, "sess_abc"), []byte(`user_id|i:7;`), 0600)
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Destroy(r)
+		w.Write([]byte("bye"))
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "PHPSESSID", Value: "abc"})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "PHPSESSID" || cookies[0].MaxAge >= 0 {
+		t.Fatalf("expect an expired cookie, got %v", cookies)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sess_abc")); !os.IsNotExist(err) {
+		t.Fatalf("expect the session to be destroyed, got %v", err)
+	}
+
+}
+
+type failingStore struct{}
+
+func (failingStore) Open(ctx context.Context, id string) (Handle, error) {
+	return failingHandle{}, nil
+}
+
+func (failingStore) GC(ctx context.Context, maxLifetime time.Duration) (int, error) {
+	return 0, nil
+}
+
+type failingHandle struct{}
+
+func (failingHandle) Read() ([]byte, error)   { return nil, nil }
+func (failingHandle) Write(data []byte) error { return errors.New("disk full") }
+func (failingHandle) Destroy() error          { return nil }
+func (failingHandle) Close() error            { return nil }
+
+func TestManager_SaveError(t *testing.T) {
+
+	m := NewManager[testSession](failingStore{}, phpserialize.SessionPHP)
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Get[testSession](r).Name = "alice"
+		w.Write([]byte("ok"))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "Internal Server Error\n" {
+		t.Fatalf("expect 500 got %d %q", w.Code, w.Body.String())
+	}
+
+}
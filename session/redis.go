@@ -0,0 +1,284 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DefaultRedisPrefix = "PHPREDIS_SESSION:"
+
+var (
+	ErrLockTimeout = errors.New("session: timeout waiting for the session lock")
+	ErrLockLost    = errors.New("session: session lock is held by someone else")
+)
+
+// RedisClient is the part of a redis client RedisStore needs. Adapting
+// go-redis or redigo to it takes a few lines.
+type RedisClient interface {
+	// Get returns the value of key, found is false when key does not exist.
+	Get(ctx context.Context, key string) (value []byte, found bool, err error)
+	SetEX(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetNX sets key only if it does not exist and reports whether it did.
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Del(ctx context.Context, key string) error
+	// DelIfEqual deletes key when it holds value, usually done with a lua
+	// script.
+	DelIfEqual(ctx context.Context, key string, value []byte) error
+}
+
+// RedisStore is the phpredis session handler. Sessions are stored under
+// Prefix+id with a ttl of MaxLifetime, and with Locking enabled they are
+// locked with a Prefix+id+"_LOCK" key like redis.session.locking_enabled
+// does.
+type RedisStore struct {
+	Client      RedisClient
+	Prefix      string
+	MaxLifetime time.Duration // session.gc_maxlifetime
+	Locking     bool
+	LockExpire  time.Duration // redis.session.lock_expire
+	LockWait    time.Duration // redis.session.lock_wait_time
+	LockRetries int           // redis.session.lock_retries, -1 retries forever
+}
+
+// NewRedisStore returns a RedisStore with the php and phpredis defaults.
+func NewRedisStore(client RedisClient) *RedisStore {
+
+	return &RedisStore{
+		Client:      client,
+		Prefix:      DefaultRedisPrefix,
+		MaxLifetime: 1440 * time.Second,
+		LockExpire:  30 * time.Second,
+		LockWait:    20 * time.Millisecond,
+		LockRetries: 100,
+	}
+
+}
+
+func (s *RedisStore) Open(ctx context.Context, id string) (Handle, error) {
+
+	if !ValidID(id) {
+		return nil, ErrInvalidID
+	}
+
+	h := &redisHandle{store: s, ctx: ctx, key: s.Prefix + id}
+	if !s.Locking {
+		return h, nil
+	}
+
+	h.lockKey = h.key + "_LOCK"
+	h.secret = lockSecret()
+	for retry := 0; s.LockRetries < 0 || retry <= s.LockRetries; retry++ {
+
+		ok, err := s.Client.SetNX(ctx, h.lockKey, h.secret, s.LockExpire)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return h, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(s.LockWait):
+		}
+
+	}
+	return nil, ErrLockTimeout
+
+}
+
+// GC does nothing, redis expires the sessions by their ttl.
+func (s *RedisStore) GC(ctx context.Context, maxLifetime time.Duration) (int, error) {
+	return 0, nil
+}
+
+var lockCount uint64
+
+// lockSecret is the value of the lock key, "<hostname>|<pid>" like phpredis
+// with a counter added since a Go process serves many requests at once.
+func lockSecret() []byte {
+
+	host, _ := os.Hostname()
+	n := atomic.AddUint64(&lockCount, 1)
+	return []byte(host + "|" + strconv.Itoa(os.Getpid()) + "|" + strconv.FormatUint(n, 10))
+
+}
+
+type redisHandle struct {
+	store   *RedisStore
+	ctx     context.Context
+	key     string
+	lockKey string
+	secret  []byte
+}
+
+func (h *redisHandle) Read() ([]byte, error) {
+
+	data, _, err := h.store.Client.Get(h.ctx, h.key)
+	return data, err
+
+}
+
+func (h *redisHandle) Write(data []byte) error {
+
+	if err := h.checkLock(); err != nil {
+		return err
+	}
+	return h.store.Client.SetEX(h.ctx, h.key, data, h.store.MaxLifetime)
+
+}
+
+func (h *redisHandle) Destroy() error {
+
+	if err := h.checkLock(); err != nil {
+		return err
+	}
+	return h.store.Client.Del(h.ctx, h.key)
+
+}
+
+func (h *redisHandle) Close() error {
+
+	if h.lockKey == "" {
+		return nil
+	}
+	return h.store.Client.DelIfEqual(h.ctx, h.lockKey, h.secret)
+
+}
+
+// checkLock refuses changes once the lock expired and someone else took it.
+func (h *redisHandle) checkLock() error {
+
+	if h.lockKey == "" {
+		return nil
+	}
+	secret, _, err := h.store.Client.Get(h.ctx, h.lockKey)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(secret, h.secret) {
+		return ErrLockLost
+	}
+	return nil
+
+}
+
+// MemoryRedis is an in-memory RedisClient for tests.
+type MemoryRedis struct {
+	mu   sync.Mutex
+	keys map[string]memoryEntry
+	now  func() time.Time
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+func NewMemoryRedis() *MemoryRedis {
+	return &MemoryRedis{keys: make(map[string]memoryEntry), now: time.Now}
+}
+
+func (m *MemoryRedis) get(key string) (memoryEntry, bool) {
+
+	e, ok := m.keys[key]
+	if ok && !e.expires.IsZero() && !m.now().Before(e.expires) {
+		delete(m.keys, key)
+		return e, false
+	}
+	return e, ok
+
+}
+
+func (m *MemoryRedis) set(key string, value []byte, ttl time.Duration) {
+
+	e := memoryEntry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.expires = m.now().Add(ttl)
+	}
+	m.keys[key] = e
+
+}
+
+func (m *MemoryRedis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.get(key)
+	if !ok {
+		return nil, false, nil
+	}
+	return append([]byte(nil), e.value...), true, nil
+
+}
+
+func (m *MemoryRedis) SetEX(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.set(key, value, ttl)
+	return nil
+
+}
+
+func (m *MemoryRedis) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.get(key); ok {
+		return false, nil
+	}
+	m.set(key, value, ttl)
+	return true, nil
+
+}
+
+func (m *MemoryRedis) Del(ctx context.Context, key string) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.keys, key)
+	return nil
+
+}
+
+func (m *MemoryRedis) DelIfEqual(ctx context.Context, key string, value []byte) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.get(key); ok && bytes.Equal(e.value, value) {
+		delete(m.keys, key)
+	}
+	return nil
+
+}
+
+// TTL returns the time key has left to live, -1 for keys without a ttl and
+// -2 for missing keys, as the redis TTL command does.
+func (m *MemoryRedis) TTL(key string) time.Duration {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.get(key)
+	switch {
+	case !ok:
+		return -2
+	case e.expires.IsZero():
+		return -1
+	}
+	return e.expires.Sub(m.now())
+
+}
@@ -0,0 +1,86 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zengxinqian/phpserialize"
+)
+
+func TestRedisStore(t *testing.T) {
+
+	client := NewMemoryRedis()
+	s := NewRedisStore(client)
+	s.Locking = true
+	s.LockWait = time.Millisecond
+	s.LockRetries = 5
+	ctx := context.Background()
+
+	h, err := s.Open(ctx, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := h.Read(); err != nil || len(data) != 0 {
+		t.Fatalf("expect an empty session got %q %v", data, err)
+	}
+	if err = h.Write([]byte(`a|i:1;`)); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := client.TTL("PHPREDIS_SESSION:abc"); ttl <= 1430*time.Second || ttl > 1440*time.Second {
+		t.Fatalf("unexpected ttl %v", ttl)
+	}
+	if ttl := client.TTL("PHPREDIS_SESSION:abc_LOCK"); ttl <= 0 || ttl > 30*time.Second {
+		t.Fatalf("unexpected lock ttl %v", ttl)
+	}
+
+	if _, err := s.Open(ctx, "abc"); err != ErrLockTimeout {
+		t.Fatalf("expect ErrLockTimeout got %v", err)
+	}
+	h.Close()
+	if ttl := client.TTL("PHPREDIS_SESSION:abc_LOCK"); ttl != -2 {
+		t.Fatalf("expect the lock to be released, ttl %v", ttl)
+	}
+
+	h, err = s.Open(ctx, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := h.Read(); err != nil || string(data) != `a|i:1;` {
+		t.Fatalf("unexpected session %q %v", data, err)
+	}
+
+	// a lock taken over after it expired can't be used to write
+	client.SetEX(ctx, "PHPREDIS_SESSION:abc_LOCK", []byte("other|1"), time.Second)
+	if err = h.Write([]byte(`a|i:2;`)); err != ErrLockLost {
+		t.Fatalf("expect ErrLockLost got %v", err)
+	}
+	h.Close()
+	if v, _, _ := client.Get(ctx, "PHPREDIS_SESSION:abc_LOCK"); string(v) != "other|1" {
+		t.Fatalf("expect the lock of someone else to stay, got %q", v)
+	}
+
+}
+
+func TestRedisStore_Manager(t *testing.T) {
+
+	client := NewMemoryRedis()
+	client.SetEX(context.Background(), "PHPREDIS_SESSION:abc", []byte(`a:2:{s:7:"user_id";i:7;s:4:"name";s:3:"bob";}`), time.Hour)
+
+	m := NewManager[testSession](NewRedisStore(client), phpserialize.SessionPHPSerialize)
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Get[testSession](r).UserID++
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "PHPSESSID", Value: "abc"})
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	data, _, _ := client.Get(context.Background(), "PHPREDIS_SESSION:abc")
+	if string(data) != `a:2:{s:7:"user_id";i:8;s:4:"name";s:3:"bob";}` {
+		t.Fatalf("unexpected session %q", data)
+	}
+
+}
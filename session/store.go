@@ -0,0 +1,48 @@
+// Package session shares $_SESSION data with php. Stores read and write the
+// raw session data the way the php save handlers do, Manager decodes it into
+// a Go value for each request.
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Store is a php session save handler.
+type Store interface {
+	// Open locks the session id until the Handle is closed, like
+	// session_start does.
+	Open(ctx context.Context, id string) (Handle, error)
+	// GC removes the sessions not written for maxLifetime and returns how
+	// many were removed.
+	GC(ctx context.Context, maxLifetime time.Duration) (int, error)
+}
+
+type Handle interface {
+	// Read returns the session data, empty for a new session.
+	Read() ([]byte, error)
+	Write(data []byte) error
+	Destroy() error
+	// Close releases the lock on the session.
+	Close() error
+}
+
+var ErrInvalidID = errors.New("session: invalid session id")
+
+// ValidID reports whether id only uses the characters php allows in session
+// ids, which also keeps it safe to use in file names and keys.
+func ValidID(id string) bool {
+
+	if id == "" || len(id) > 256 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == ',' || c == '-') {
+			return false
+		}
+	}
+	return true
+
+}
@@ -0,0 +1,108 @@
+package phpserialize
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+type sessionUser struct {
+	UserID int    `php:"user_id"`
+	Name   string `php:"name"`
+	Roles  []string
+}
+
+func TestSession(t *testing.T) {
+
+	testList := []struct {
+		handler SessionHandler
+		data    string
+	}{
+		{SessionPHP, `user_id|i:42;name|s:5:"alice";Roles|a:2:{i:0;s:5:"admin";i:1;s:6:"editor";}`},
+		{SessionPHPBinary, "\x07user_idi:42;\x04names:5:\"alice\";\x05Rolesa:2:{i:0;s:5:\"admin\";i:1;s:6:\"editor\";}"},
+		{SessionPHPSerialize, `a:3:{s:7:"user_id";i:42;s:4:"name";s:5:"alice";s:5:"Roles";a:2:{i:0;s:5:"admin";i:1;s:6:"editor";}}`},
+	}
+
+	for i, test := range testList {
+
+		var user sessionUser
+		if err := SessionDecode([]byte(test.data), test.handler, &user); err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if user.UserID != 42 || user.Name != "alice" || len(user.Roles) != 2 || user.Roles[1] != "editor" {
+			t.Fatalf("Test fail at index %d, got %+v", i, user)
+		}
+
+		var m map[string]interface{}
+		if err := SessionDecode([]byte(test.data), test.handler, &m); err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if m["user_id"] != int64(42) || m["name"] != "alice" {
+			t.Fatalf("Test fail at index %d, got %v", i, m)
+		}
+
+		data, err := SessionEncode(user, test.handler)
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(data) != test.data {
+			t.Fatalf("Test fail at index %d, expect:%q got %q", i, test.data, data)
+		}
+
+	}
+
+}
+
+func TestSession_Error(t *testing.T) {
+
+	var m map[string]interface{}
+	for i, data := range []string{`name|s:5:"alice"`, `name`, `name|x:1;`} {
+		if err := SessionDecode([]byte(data), SessionPHP, &m); err == nil {
+			t.Fatalf("Test fail at index %d, expect an error for %s", i, data)
+		}
+	}
+	if err := SessionDecode([]byte("\x09name"), SessionPHPBinary, &m); err == nil {
+		t.Fatal("expect an error for a short php_binary name")
+	}
+
+	if _, err := SessionEncode(map[string]int{"a|b": 1}, SessionPHP); err == nil {
+		t.Fatal("expect an error for a name with '|'")
+	}
+	if _, err := SessionEncode(map[int]int{1: 1}, SessionPHPBinary); err == nil {
+		t.Fatal("expect an error for a numeric name")
+	}
+	if _, err := SessionEncode(1, SessionPHPSerialize); err == nil {
+		t.Fatal("expect an error for a session that is not an array")
+	}
+
+}
+
+func TestSession_Undefined(t *testing.T) {
+
+	var m map[string]interface{}
+	if err := SessionDecode([]byte(`!gone|a|i:1;`), SessionPHP, &m); err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 || m["a"] != int64(1) {
+		t.Fatalf("unexpected session %v", m)
+	}
+
+}
+
+func TestDecoder_Session(t *testing.T) {
+
+	dec := NewDecoder(strings.NewReader(`a|i:1;b|s:1:"x";`))
+	dec.Session(SessionPHP)
+
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m["a"] != int64(1) || m["b"] != "x" {
+		t.Fatalf("unexpected session %v", m)
+	}
+	if err := dec.Decode(&m); err != io.EOF {
+		t.Fatalf("expect io.EOF got %v", err)
+	}
+
+}
@@ -0,0 +1,485 @@
+package phpserialize
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+)
+
+// The SPL types read both the C: form php wrote before 7.4 and the O: form
+// of __serialize, and write the O: form unless Legacy is set. Members holds
+// the properties of the object itself. Class is the class read, subclasses
+// included, and the class written, the base class when empty.
+
+// ArrayObject is php's ArrayObject, ArrayIterator or RecursiveArrayIterator.
+type ArrayObject[T any] struct {
+	Class   string
+	Flags   int
+	Storage T
+	Members map[string]interface{}
+	// IteratorClass is the iterator class set with setIteratorClass, empty
+	// for ArrayIterator. The C: form doesn't keep it.
+	IteratorClass string
+	Legacy        bool
+}
+
+// SplObjectStorage is php's SplObjectStorage, objects with the data
+// attached to them.
+type SplObjectStorage[T any] struct {
+	Class   string
+	Entries []SplObjectEntry[T]
+	Members map[string]interface{}
+	Legacy  bool
+}
+
+type SplObjectEntry[T any] struct {
+	Object T
+	Info   interface{}
+}
+
+// SplDoublyLinkedList is php's SplDoublyLinkedList, SplQueue or SplStack.
+// SplQueue is written with at least the flags 4 and SplStack with 6, like php
+// sets them.
+type SplDoublyLinkedList[T any] struct {
+	Class    string
+	Flags    int
+	Elements []T
+	Members  map[string]interface{}
+	Legacy   bool
+}
+
+// SplFixedArray is php's SplFixedArray, which has the same form before and
+// after php 8.2.
+type SplFixedArray[T any] struct {
+	Class    string
+	Elements []T
+	Members  map[string]interface{}
+}
+
+// builtinClasses creates the values objects of php's built-in classes decode
+// to in an interface{}.
+var builtinClasses = map[string]func() Unmarshaler{
+	"ArrayObject":            func() Unmarshaler { return &ArrayObject[interface{}]{} },
+	"ArrayIterator":          func() Unmarshaler { return &ArrayObject[interface{}]{} },
+	"RecursiveArrayIterator": func() Unmarshaler { return &ArrayObject[interface{}]{} },
+	"SplObjectStorage":       func() Unmarshaler { return &SplObjectStorage[interface{}]{} },
+	"SplDoublyLinkedList":    func() Unmarshaler { return &SplDoublyLinkedList[interface{}]{} },
+	"SplQueue":               func() Unmarshaler { return &SplDoublyLinkedList[interface{}]{} },
+	"SplStack":               func() Unmarshaler { return &SplDoublyLinkedList[interface{}]{} },
+	"SplFixedArray":          func() Unmarshaler { return &SplFixedArray[interface{}]{} },
+	incompleteClass:          func() Unmarshaler { return &IncompleteObject{} },
+}
+
+func (a ArrayObject[T]) MarshalPHP() ([]byte, error) {
+
+	class := orDefault(a.Class, "ArrayObject")
+	storage, err := Marshal(a.Storage)
+	if err != nil {
+		return nil, err
+	}
+	members, err := marshalMembers(a.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Legacy {
+		body := appendPHPInt([]byte("x:"), a.Flags)
+		body = append(body, storage...)
+		body = append(body, ";m:"...)
+		body = append(body, members...)
+		return appendContainer(nil, phpTypeCustom, class, len(body), body), nil
+	}
+
+	iterator := []byte(phpNullValue)
+	if a.IteratorClass != "" {
+		iterator = appendPHPString(nil, []byte(a.IteratorClass))
+	}
+	return appendList(class, appendPHPInt(nil, a.Flags), storage, members, iterator), nil
+
+}
+
+func (a *ArrayObject[T]) UnmarshalPHP(data []byte) error {
+
+	class, c, err := parseObject(data, a)
+	if err != nil || c == nil {
+		return err
+	}
+	*a = ArrayObject[T]{Class: class}
+
+	if c.tag == phpTypeCustom {
+		a.Legacy = true
+		r := splReader{data: c.payload(data)}
+		if err = r.expect("x:"); err != nil {
+			return err
+		}
+		if err = r.decode(&a.Flags); err != nil {
+			return err
+		}
+		if err = r.decode(&a.Storage); err != nil {
+			return err
+		}
+		if err = r.expect(";m:"); err != nil {
+			return err
+		}
+		return r.decode(&a.Members)
+	}
+
+	return c.decodeEntries(data, &a.Flags, &a.Storage, &a.Members, &a.IteratorClass)
+
+}
+
+func (s SplObjectStorage[T]) MarshalPHP() ([]byte, error) {
+
+	class := orDefault(s.Class, "SplObjectStorage")
+	members, err := marshalMembers(s.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	var body []byte
+	if s.Legacy {
+		body = appendPHPInt([]byte("x:"), len(s.Entries))
+	}
+	for i, entry := range s.Entries {
+		object, err := Marshal(entry.Object)
+		if err != nil {
+			return nil, err
+		}
+		info, err := Marshal(entry.Info)
+		if err != nil {
+			return nil, err
+		}
+		if s.Legacy {
+			body = append(body, object...)
+			body = append(body, ',')
+			body = append(body, info...)
+			body = append(body, ';')
+			continue
+		}
+		body = appendPHPInt(body, 2*i)
+		body = append(body, object...)
+		body = appendPHPInt(body, 2*i+1)
+		body = append(body, info...)
+	}
+
+	if s.Legacy {
+		body = append(body, "m:"...)
+		body = append(body, members...)
+		return appendContainer(nil, phpTypeCustom, class, len(body), body), nil
+	}
+	return appendList(class, appendContainer(nil, phpTypeArray, "", 2*len(s.Entries), body), members), nil
+
+}
+
+func (s *SplObjectStorage[T]) UnmarshalPHP(data []byte) error {
+
+	class, c, err := parseObject(data, s)
+	if err != nil || c == nil {
+		return err
+	}
+	*s = SplObjectStorage[T]{Class: class}
+
+	if c.tag == phpTypeCustom {
+		s.Legacy = true
+		r := splReader{data: c.payload(data)}
+		var n int
+		if err = r.expect("x:"); err != nil {
+			return err
+		}
+		if err = r.decode(&n); err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			var entry SplObjectEntry[T]
+			if err = r.decode(&entry.Object); err != nil {
+				return err
+			}
+			if err = r.expect(","); err != nil {
+				return err
+			}
+			if err = r.decode(&entry.Info); err != nil {
+				return err
+			}
+			if err = r.expect(";"); err != nil {
+				return err
+			}
+			s.Entries = append(s.Entries, entry)
+		}
+		if err = r.expect("m:"); err != nil {
+			return err
+		}
+		return r.decode(&s.Members)
+	}
+
+	var storage RawMessage
+	if err = c.decodeEntries(data, &storage, &s.Members); err != nil {
+		return err
+	}
+	list, err := parseContainer(storage)
+	if err != nil {
+		return err
+	}
+	if list == nil || list.tag != phpTypeArray || len(list.entries)%2 != 0 {
+		return &UnmarshalTypeError{Value: "SplObjectStorage data", Type: reflect.TypeOf(s), Offset: int64(c.bodyStart)}
+	}
+	for i := 0; i < len(list.entries); i += 2 {
+		var entry SplObjectEntry[T]
+		if err = Unmarshal(list.value(storage, i), &entry.Object); err != nil {
+			return err
+		}
+		if err = Unmarshal(list.value(storage, i+1), &entry.Info); err != nil {
+			return err
+		}
+		s.Entries = append(s.Entries, entry)
+	}
+	return nil
+
+}
+
+func (l SplDoublyLinkedList[T]) MarshalPHP() ([]byte, error) {
+
+	class := orDefault(l.Class, "SplDoublyLinkedList")
+	flags := l.Flags
+	switch class {
+	case "SplQueue":
+		flags |= 4
+	case "SplStack":
+		flags |= 6
+	}
+	members, err := marshalMembers(l.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.Legacy {
+		body := appendPHPInt(nil, flags)
+		for _, elem := range l.Elements {
+			v, err := Marshal(elem)
+			if err != nil {
+				return nil, err
+			}
+			body = append(body, phpSeparator)
+			body = append(body, v...)
+		}
+		return appendContainer(nil, phpTypeCustom, class, len(body), body), nil
+	}
+
+	elements, err := Marshal(l.Elements)
+	if err != nil {
+		return nil, err
+	}
+	if l.Elements == nil {
+		elements = []byte("a:0:{}")
+	}
+	return appendList(class, appendPHPInt(nil, flags), elements, members), nil
+
+}
+
+func (l *SplDoublyLinkedList[T]) UnmarshalPHP(data []byte) error {
+
+	class, c, err := parseObject(data, l)
+	if err != nil || c == nil {
+		return err
+	}
+	*l = SplDoublyLinkedList[T]{Class: class}
+
+	if c.tag == phpTypeCustom {
+		l.Legacy = true
+		r := splReader{data: c.payload(data)}
+		if err = r.decode(&l.Flags); err != nil {
+			return err
+		}
+		for r.off < len(r.data) {
+			var elem T
+			if err = r.expect(":"); err != nil {
+				return err
+			}
+			if err = r.decode(&elem); err != nil {
+				return err
+			}
+			l.Elements = append(l.Elements, elem)
+		}
+		return nil
+	}
+
+	return c.decodeEntries(data, &l.Flags, &l.Elements, &l.Members)
+
+}
+
+func (f SplFixedArray[T]) MarshalPHP() ([]byte, error) {
+
+	var body []byte
+	for i, elem := range f.Elements {
+		v, err := Marshal(elem)
+		if err != nil {
+			return nil, err
+		}
+		body = appendPHPInt(body, i)
+		body = append(body, v...)
+	}
+	members, err := Marshal(f.Members)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Members) > 0 {
+		// the members follow the elements in the same table
+		body = append(body, members[bytes.IndexByte(members, phpLeftBraces)+1:len(members)-1]...)
+	}
+	return appendContainer(nil, phpTypeObject, orDefault(f.Class, "SplFixedArray"), len(f.Elements)+len(f.Members), body), nil
+
+}
+
+func (f *SplFixedArray[T]) UnmarshalPHP(data []byte) error {
+
+	class, c, err := parseObject(data, f)
+	if err != nil || c == nil {
+		return err
+	}
+	if c.tag != phpTypeObject {
+		return &UnmarshalTypeError{Value: "custom", Type: reflect.TypeOf(f), Offset: 0}
+	}
+	*f = SplFixedArray[T]{Class: class}
+
+	// the size is the number of elements, members have string keys
+	size := int64(0)
+	for _, e := range c.entries {
+		if _, ok := e.key.(int64); ok {
+			size++
+		}
+	}
+	for i, e := range c.entries {
+		switch key := e.key.(type) {
+		case int64:
+			if key < 0 || key >= size {
+				return &UnmarshalTypeError{Value: "index " + strconv.FormatInt(key, 10), Type: reflect.TypeOf(f), Offset: int64(e.start)}
+			}
+			for int64(len(f.Elements)) <= key {
+				var zero T
+				f.Elements = append(f.Elements, zero)
+			}
+			if err = Unmarshal(c.value(data, i), &f.Elements[key]); err != nil {
+				return err
+			}
+		case string:
+			var v interface{}
+			if err = Unmarshal(c.value(data, i), &v); err != nil {
+				return err
+			}
+			if f.Members == nil {
+				f.Members = make(map[string]interface{})
+			}
+			f.Members[key] = v
+		}
+	}
+	return nil
+
+}
+
+// parseObject returns the class and the parsed container of an object or
+// custom value decoded into v, a nil container for null.
+func parseObject(data []byte, v interface{}) (string, *rawContainer, error) {
+
+	c, err := parseContainer(data)
+	if err != nil {
+		return "", nil, err
+	}
+	if c == nil && bytes.Equal(data, []byte(phpNullValue)) {
+		return "", nil, nil
+	}
+	if c == nil || c.tag == phpTypeArray {
+		return "", nil, &UnmarshalTypeError{Value: "non-object value", Offset: 0, Type: reflect.TypeOf(v)}
+	}
+	n, start := readLength(data, 2)
+	return string(data[start+1 : start+1+n]), c, nil
+
+}
+
+// payload returns the data of a custom value.
+func (c *rawContainer) payload(data []byte) []byte {
+
+	n, _ := strconv.Atoi(string(data[c.countStart:c.countEnd]))
+	return data[c.bodyStart : c.bodyStart+n]
+
+}
+
+// value returns the raw value of entry i.
+func (c *rawContainer) value(data []byte, i int) []byte {
+	return data[c.entries[i].valueStart:c.entries[i].end]
+}
+
+// decodeEntries decodes the values of the O: form, which are stored under
+// the keys 0 to n-1, into targets.
+func (c *rawContainer) decodeEntries(data []byte, targets ...interface{}) error {
+
+	for i := range c.entries {
+		key, ok := c.entries[i].key.(int64)
+		if !ok || key < 0 || key >= int64(len(targets)) {
+			continue
+		}
+		if err := Unmarshal(c.value(data, i), targets[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+
+}
+
+// splReader reads the data of the C: form, serialized values with separators
+// in between.
+type splReader struct {
+	data []byte
+	off  int
+}
+
+func (r *splReader) expect(s string) error {
+
+	if !bytes.HasPrefix(r.data[r.off:], []byte(s)) {
+		return &SyntaxError{"php serialize: expect " + strconv.Quote(s) + " in SPL data, offset: " + strconv.Itoa(r.off), int64(r.off)}
+	}
+	r.off += len(s)
+	return nil
+
+}
+
+func (r *splReader) decode(v interface{}) error {
+
+	n, err := valueLength(r.data[r.off:])
+	if err != nil {
+		return err
+	}
+	r.off += n
+	return Unmarshal(r.data[r.off-n:r.off], v)
+
+}
+
+// marshalMembers writes the properties of an SPL object, an empty array
+// when there are none.
+func marshalMembers(members map[string]interface{}) ([]byte, error) {
+
+	if members == nil {
+		return []byte("a:0:{}"), nil
+	}
+	return Marshal(members)
+
+}
+
+// appendList writes an object of class with the values under the keys 0 to
+// n-1, the way __serialize returns them.
+func appendList(class string, values ...[]byte) []byte {
+
+	var body []byte
+	for i, v := range values {
+		body = appendPHPInt(body, i)
+		body = append(body, v...)
+	}
+	return appendContainer(nil, phpTypeObject, class, len(values), body)
+
+}
+
+func appendPHPInt(b []byte, n int) []byte {
+
+	b = append(b, byte(phpTypeInteger), phpSeparator)
+	b = strconv.AppendInt(b, int64(n), 10)
+	return append(b, phpTerminator)
+
+}
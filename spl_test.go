@@ -0,0 +1,110 @@
+package phpserialize
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSPL(t *testing.T) {
+
+	testList := []string{
+		// php 7.3
+		`C:11:"ArrayObject":29:{x:i:0;a:1:{i:0;i:1;};m:a:0:{}}`,
+		`C:13:"ArrayIterator":21:{x:i:2;a:0:{};m:a:0:{}}`,
+		`C:16:"SplObjectStorage":49:{x:i:1;O:13:"SplFixedArray":0:{},s:1:"x";;m:a:0:{}}`,
+		`C:8:"SplQueue":18:{i:4;:i:1;:s:1:"a";}`,
+		`C:8:"SplStack":4:{i:6;}`,
+		// php 7.4 and later
+		`O:11:"ArrayObject":4:{i:0;i:0;i:1;a:1:{i:0;i:1;}i:2;a:1:{s:1:"p";b:1;}i:3;N;}`,
+		`O:11:"ArrayObject":4:{i:0;i:0;i:1;a:0:{}i:2;a:0:{}i:3;s:22:"RecursiveArrayIterator";}`,
+		`O:16:"SplObjectStorage":2:{i:0;a:2:{i:0;O:13:"SplFixedArray":0:{}i:1;s:1:"x";}i:1;a:0:{}}`,
+		`O:19:"SplDoublyLinkedList":3:{i:0;i:2;i:1;a:2:{i:0;i:1;i:1;s:1:"a";}i:2;a:0:{}}`,
+		`O:13:"SplFixedArray":3:{i:0;i:1;i:1;N;i:2;s:1:"c";}`,
+		`O:13:"SplFixedArray":2:{i:0;i:1;s:1:"p";i:2;}`,
+	}
+
+	for i, test := range testList {
+
+		var v interface{}
+		if err := Unmarshal([]byte(test), &v); err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		data, err := Marshal(v)
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(data) != test {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test, data)
+		}
+
+	}
+
+}
+
+type testSPLItem struct {
+	ID int `php:"id"`
+}
+
+func TestSPL_Typed(t *testing.T) {
+
+	var list SplDoublyLinkedList[testSPLItem]
+	if err := Unmarshal([]byte(`C:8:"SplQueue":36:{i:4;:O:4:"Item":1:{s:2:"id";i:1;}:N;}`), &list); err != nil {
+		t.Fatal(err)
+	}
+	if list.Class != "SplQueue" || list.Flags != 4 || !list.Legacy ||
+		!reflect.DeepEqual(list.Elements, []testSPLItem{{1}, {}}) {
+		t.Fatalf("unexpected list %+v", list)
+	}
+
+	data, err := Marshal(SplDoublyLinkedList[int]{Class: "SplStack", Elements: []int{1, 2}})
+	if expect := `O:8:"SplStack":3:{i:0;i:6;i:1;a:2:{i:0;i:1;i:1;i:2;}i:2;a:0:{}}`; err != nil || string(data) != expect {
+		t.Fatalf("expect:%s got %s %v", expect, data, err)
+	}
+
+	var ao ArrayObject[map[string]int]
+	if err = Unmarshal([]byte(`O:13:"ArrayIterator":4:{i:0;i:0;i:1;a:1:{s:1:"a";i:1;}i:2;a:0:{}i:3;N;}`), &ao); err != nil {
+		t.Fatal(err)
+	}
+	if ao.Class != "ArrayIterator" || ao.Storage["a"] != 1 || ao.Legacy {
+		t.Fatalf("unexpected ArrayObject %+v", ao)
+	}
+
+	var fixed SplFixedArray[string]
+	if err = Unmarshal([]byte(`O:13:"SplFixedArray":2:{i:0;s:1:"a";i:1;s:1:"b";}`), &fixed); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fixed.Elements, []string{"a", "b"}) {
+		t.Fatalf("unexpected SplFixedArray %+v", fixed)
+	}
+	for _, data := range []string{`O:13:"SplFixedArray":1:{i:1;i:2;}`, `O:13:"SplFixedArray":3:{i:0;i:1;s:1:"p";i:2;i:2;i:3;}`} {
+		if err = Unmarshal([]byte(data), &fixed); err == nil {
+			t.Fatalf("expect an error for %s", data)
+		}
+	}
+
+	var storage SplObjectStorage[testSPLItem]
+	if err = Unmarshal([]byte(`C:16:"SplObjectStorage":46:{x:i:1;O:4:"Item":1:{s:2:"id";i:7;},N;;m:a:0:{}}`), &storage); err != nil {
+		t.Fatal(err)
+	}
+	if len(storage.Entries) != 1 || storage.Entries[0].Object.ID != 7 || storage.Entries[0].Info != nil {
+		t.Fatalf("unexpected SplObjectStorage %+v", storage)
+	}
+
+}
+
+func TestSPL_UnknownCustom(t *testing.T) {
+
+	test := `a:1:{i:0;C:3:"Foo":3:{abc}}`
+	var v []interface{}
+	if err := Unmarshal([]byte(test), &v); err != nil {
+		t.Fatal(err)
+	}
+	if o, ok := v[0].(IncompleteObject); !ok || o.Class != "Foo" || !o.Custom || string(o.Data) != "abc" {
+		t.Fatalf("unexpected value %#v", v[0])
+	}
+	data, err := Marshal(v)
+	if err != nil || string(data) != test {
+		t.Fatalf("expect:%s got %s %v", test, data, err)
+	}
+
+}
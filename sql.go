@@ -0,0 +1,94 @@
+package phpserialize
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// Serialized is a database column holding a php serialized value, as
+// written by Doctrine's array and object types or WordPress meta tables.
+// SQL NULL and a serialized null read as a nil V, a nil V is written as
+// NULL.
+type Serialized[T any] struct {
+	V *T
+}
+
+func (s *Serialized[T]) Scan(src interface{}) error {
+
+	data, err := scanData(src)
+	if err != nil || data == nil || string(data) == phpNullValue {
+		s.V = nil
+		return err
+	}
+
+	v := new(T)
+	if err = Unmarshal(data, v); err != nil {
+		return err
+	}
+	s.V = v
+	return nil
+
+}
+
+func (s Serialized[T]) Value() (driver.Value, error) {
+
+	if s.V == nil {
+		return nil, nil
+	}
+	return columnValue(*s.V)
+
+}
+
+// Column reads and writes a serialized column into V, which has to be a
+// pointer when scanning. SQL NULL is decoded like a serialized null.
+type Column struct {
+	V interface{}
+}
+
+func (c Column) Scan(src interface{}) error {
+
+	data, err := scanData(src)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		data = []byte(phpNullValue)
+	}
+	return Unmarshal(data, c.V)
+
+}
+
+func (c Column) Value() (driver.Value, error) {
+
+	if c.V == nil {
+		return nil, nil
+	}
+	return columnValue(c.V)
+
+}
+
+func scanData(src interface{}) ([]byte, error) {
+
+	switch data := src.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return data, nil
+	case string:
+		return []byte(data), nil
+	}
+	return nil, fmt.Errorf("php serialize: cannot scan %T into a serialized column", src)
+
+}
+
+// columnValue is stored as a string so it goes into text columns with every
+// driver.
+func columnValue(v interface{}) (driver.Value, error) {
+
+	data, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+
+}
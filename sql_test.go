@@ -0,0 +1,66 @@
+package phpserialize
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+var (
+	_ sql.Scanner   = (*Serialized[int])(nil)
+	_ driver.Valuer = Serialized[int]{}
+	_ sql.Scanner   = Column{}
+	_ driver.Valuer = Column{}
+)
+
+func TestSerialized(t *testing.T) {
+
+	var s Serialized[map[string]int]
+	if err := s.Scan([]byte(`a:1:{s:1:"a";i:1;}`)); err != nil {
+		t.Fatal(err)
+	}
+	if s.V == nil || !reflect.DeepEqual(*s.V, map[string]int{"a": 1}) {
+		t.Fatalf("expect map got %v", s.V)
+	}
+
+	v, err := s.Value()
+	if err != nil || v != `a:1:{s:1:"a";i:1;}` {
+		t.Fatalf("expect serialized map got %v %v", v, err)
+	}
+
+	for _, src := range []interface{}{nil, "N;"} {
+		if err = s.Scan(src); err != nil || s.V != nil {
+			t.Fatalf("expect nil for %v got %v %v", src, s.V, err)
+		}
+	}
+	if v, err = s.Value(); err != nil || v != nil {
+		t.Fatalf("expect NULL got %v %v", v, err)
+	}
+
+	if err = s.Scan(12); err == nil {
+		t.Fatal("expect an error scanning an int")
+	}
+
+}
+
+func TestColumn(t *testing.T) {
+
+	var tags []string
+	if err := (Column{&tags}).Scan(`a:2:{i:0;s:1:"a";i:1;s:1:"b";}`); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, []string{"a", "b"}) {
+		t.Fatalf("expect [a b] got %v", tags)
+	}
+
+	v, err := Column{tags}.Value()
+	if err != nil || v != `a:2:{i:0;s:1:"a";i:1;s:1:"b";}` {
+		t.Fatalf("expect serialized list got %v %v", v, err)
+	}
+
+	if err = (Column{&tags}).Scan(nil); err != nil || tags != nil {
+		t.Fatalf("expect nil got %v %v", tags, err)
+	}
+
+}
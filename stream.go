@@ -3,6 +3,7 @@ package phpserialize
 import (
 	"bytes"
 	"io"
+	"reflect"
 
 	"github.com/pkg/errors"
 )
@@ -15,30 +16,126 @@ type Decoder struct {
 	scanned int64 // amount of data already scanned
 	scan    scanner
 	err     error
+
+	lenient  bool
+	repaired []byte
+	fixes    []Fix
+
+	session        bool
+	sessionHandler SessionHandler
 }
 
 func NewDecoder(r io.Reader) *Decoder {
 	return &Decoder{r: r}
 }
 
+// Lenient makes the Decoder repair values with wrong string, class name or
+// custom data lengths instead of failing on them, see Repair.
+func (dec *Decoder) Lenient() { dec.lenient = true }
+
+// Fixes returns the corrections made to the last decoded value.
+func (dec *Decoder) Fixes() []Fix { return dec.fixes }
+
+// Session makes the Decoder read the rest of the input as session data
+// written with handler, see SessionDecode.
+func (dec *Decoder) Session(handler SessionHandler) {
+
+	dec.session = true
+	dec.sessionHandler = handler
+
+}
+
+// OnUnknownClass sets the hook asked for the Go type of objects and custom
+// values decoded into an interface{}, other than those of php's built-in
+// classes. When it returns a nil type they decode as without the hook, to a
+// map for stdClass and an IncompleteObject for other classes. The hook is
+// passed on to the values decoded into the returned types, but not into the
+// built-in classes and other Unmarshalers, which decode their contents with
+// Unmarshal: an object inside an ArrayObject or a PHPException is decoded
+// without it.
+func (dec *Decoder) OnUnknownClass(f func(class string) (reflect.Type, error)) {
+	dec.d.onUnknownClass = f
+}
+
 func (dec *Decoder) Decode(v interface{}) error {
 
 	if dec.err != nil {
 		return dec.err
 	}
+	dec.fixes = nil
+
+	if dec.session {
+		return dec.decodeSession(v)
+	}
+
+	if err := dec.skipSpace(); err != nil {
+		return err
+	}
 
 	// scan and read whole php serialized data into buffer
 	n, err := dec.readValue()
 	if err != nil {
 		return err
 	}
-	dec.d.init(dec.buf[dec.scanp : dec.scanp+n])
+	data := dec.buf[dec.scanp : dec.scanp+n]
+	if dec.repaired != nil {
+		data, dec.repaired = dec.repaired, nil
+	}
+	dec.d.init(data)
 	dec.scanp += n
 
 	return dec.d.unmarshal(v)
 
 }
 
+func (dec *Decoder) decodeSession(v interface{}) error {
+
+	var err error
+	for err == nil {
+		err = dec.refill()
+	}
+	if err != io.EOF {
+		dec.err = err
+		return err
+	}
+	if dec.scanp == len(dec.buf) {
+		return io.EOF
+	}
+
+	data := dec.buf[dec.scanp:]
+	dec.scanp = len(dec.buf)
+	return SessionDecode(data, dec.sessionHandler, v)
+
+}
+
+// skipSpace moves past the whitespace in front of the next value, so values
+// written one per line can be decoded. It returns io.EOF when only whitespace
+// is left.
+func (dec *Decoder) skipSpace() error {
+
+	for {
+		for ; dec.scanp < len(dec.buf); dec.scanp++ {
+			switch dec.buf[dec.scanp] {
+			case ' ', '\t', '\n', '\r':
+				dec.scan.bytes++
+			default:
+				return nil
+			}
+		}
+		err := dec.refill()
+		if dec.scanp < len(dec.buf) {
+			continue
+		}
+		if err != nil {
+			if err != io.EOF {
+				dec.err = err
+			}
+			return err
+		}
+	}
+
+}
+
 func (dec *Decoder) Buffered() io.Reader {
 	return bytes.NewReader(dec.buf[dec.scanp:])
 }
@@ -59,18 +156,19 @@ Input:
 			dec.scan.bytes++
 			switch dec.scan.step(c) {
 			case scanEnd:
+				scanp++
 				break Input
 			case scanError:
+				if dec.lenient {
+					return dec.repairValue()
+				}
 				dec.err = dec.scan.err
 				return 0, dec.scan.err
 			}
 		}
 
 		if err != nil {
-			if err == io.EOF {
-				if dec.d.parserState == scanEnd {
-					break Input
-				}
+			if err == io.EOF && scanp > dec.scanp {
 				err = io.ErrUnexpectedEOF
 			}
 			dec.err = err
@@ -86,6 +184,29 @@ Input:
 
 }
 
+// repairValue reads the rest of the input and repairs the value at scanp.
+func (dec *Decoder) repairValue() (int, error) {
+
+	var err error
+	for err == nil {
+		err = dec.refill()
+	}
+	if err != io.EOF {
+		dec.err = err
+		return 0, err
+	}
+
+	data, n, fixes, err := repairPrefix(dec.buf[dec.scanp:])
+	if err != nil {
+		dec.err = err
+		return 0, err
+	}
+	dec.repaired = data
+	dec.fixes = fixes
+	return n, nil
+
+}
+
 func (dec *Decoder) refill() error {
 
 	if dec.scanp > 0 {
@@ -110,17 +231,34 @@ func (dec *Decoder) refill() error {
 }
 
 type Encoder struct {
-	w io.Writer
+	w         io.Writer
+	varExport bool
+	opts      encOpts
 }
 
 func NewEncoder(w io.Writer) *Encoder {
 	return &Encoder{w: w}
 }
 
+// VarExport makes the Encoder write values as php source like var_export,
+// see MarshalVarExport.
+func (enc *Encoder) VarExport() { enc.varExport = true }
+
+// DateTimeClass sets the class time.Time values are written as, DateTime by
+// default. A class tag option like `php:"created_at,class=Carbon\\Carbon"`
+// takes precedence. Any class with the DateTime properties is decoded into a
+// time.Time.
+func (enc *Encoder) DateTimeClass(class string) { enc.opts.dateTimeClass = class }
+
+// DateTimeZoneClass sets the class *time.Location values are written as,
+// DateTimeZone by default, see DateTimeClass.
+func (enc *Encoder) DateTimeZoneClass(class string) { enc.opts.dateTimeZoneClass = class }
+
 func (enc *Encoder) Encode(v interface{}) error {
 
 	e := newEncodeState()
 	defer encodeStatePool.Put(e)
+	e.encOpts = enc.opts
 
 	err := e.marshal(v)
 	if err != nil {
@@ -128,6 +266,11 @@ func (enc *Encoder) Encode(v interface{}) error {
 	}
 
 	b := e.Bytes()
+	if enc.varExport {
+		if b, err = SerializedToVarExport(b); err != nil {
+			return err
+		}
+	}
 	if _, err = enc.w.Write(b); err != nil {
 		return err
 	}
@@ -33,3 +33,15 @@ func (o tagOptions) Contains(optionName string) bool {
 	return false
 
 }
+
+// Value returns the value of an option written as name=value, or "".
+func (o tagOptions) Value(optionName string) string {
+
+	for _, s := range strings.Split(string(o), ",") {
+		if strings.HasPrefix(s, optionName+"=") {
+			return s[len(optionName)+1:]
+		}
+	}
+	return ""
+
+}
@@ -38,10 +38,27 @@ type field struct {
 	index     []int
 	typ       reflect.Type
 	omitEmpty bool
+	protected bool
+	private   bool
+	class     string // class of time.Time and *time.Location fields
 
 	encoder encoderFunc
 }
 
+// propertyName is the name of the field as an object property, mangled for
+// protected and private properties.
+func (f *field) propertyName(className string) string {
+
+	switch {
+	case f.protected:
+		return "\x00*\x00" + f.name
+	case f.private && className != "":
+		return "\x00" + className + "\x00" + f.name
+	}
+	return f.name
+
+}
+
 type byIndex []field
 
 func (x byIndex) Len() int { return len(x) }
@@ -135,6 +152,9 @@ func typeFields(t reflect.Type) structFields {
 						index:     index,
 						typ:       ft,
 						omitEmpty: opts.Contains("omitempty"),
+						protected: opts.Contains("protected"),
+						private:   opts.Contains("private"),
+						class:     opts.Value("class"),
 					}
 
 					fields = append(fields, field)
@@ -192,6 +212,9 @@ func typeFields(t reflect.Type) structFields {
 	for i := range fields {
 		f := &fields[i]
 		f.encoder = typeEncoder(typeByIndex(t, f.index))
+		if f.class != "" {
+			f.encoder = classEncoder(typeByIndex(t, f.index), f.class, f.encoder)
+		}
 	}
 	nameIndex := make(map[string]int, len(fields))
 	for i, field := range fields {
@@ -0,0 +1,288 @@
+package phpserialize
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MarshalVarExport returns v as php source, the way var_export prints the
+// value Marshal encodes. Structs implementing PHPClass are written with
+// __set_state, values implementing Serializer can't be exported.
+func MarshalVarExport(v interface{}) ([]byte, error) {
+
+	data, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return SerializedToVarExport(data)
+
+}
+
+// SerializedToVarExport converts a serialized value to var_export output.
+func SerializedToVarExport(data []byte) ([]byte, error) {
+
+	if len(data) == 0 {
+		return nil, &SyntaxError{"unexpected end of php serialize data", 0}
+	}
+	if err := checkValid(data, &scanner{}); err != nil {
+		return nil, err
+	}
+
+	x := varExporter{out: make([]byte, 0, len(data)*2)}
+	if _, err := x.value(data, 0, 1); err != nil {
+		return nil, err
+	}
+	return x.out, nil
+
+}
+
+type varExporter struct {
+	out []byte
+}
+
+func (x *varExporter) indent(n int) {
+	for i := 0; i < n; i++ {
+		x.out = append(x.out, ' ')
+	}
+}
+
+// value writes the valid serialized value at off and returns the offset
+// after it. level is the nesting level var_export uses for indentation.
+func (x *varExporter) value(data []byte, off int, level int) (int, error) {
+
+	switch phpValueType(data[off]) {
+	case phpTypeNull:
+		x.out = append(x.out, "NULL"...)
+		return off + 2, nil
+	case phpTypeBoolean:
+		if data[off+2] == '1' {
+			x.out = append(x.out, "true"...)
+		} else {
+			x.out = append(x.out, "false"...)
+		}
+		return off + 4, nil
+	case phpTypeInteger, phpTypeFloat:
+		end := off + 2 + bytes.IndexByte(data[off+2:], phpTerminator)
+		s := string(data[off+2 : end])
+		if phpValueType(data[off]) == phpTypeInteger {
+			x.integer(s)
+		} else {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return 0, &SyntaxError{"invalid float value " + s, int64(off)}
+			}
+			x.out = appendVarExportFloat(x.out, f)
+		}
+		return end + 1, nil
+	case phpTypeString:
+		n, start := readLength(data, off+2)
+		x.out = appendVarExportString(x.out, data[start+1:start+1+n], true)
+		return start + n + 3, nil
+	case phpTypeArray:
+		n, start := readLength(data, off+2)
+		if level > 1 {
+			x.out = append(x.out, '\n')
+			x.indent(level - 1)
+		}
+		x.out = append(x.out, "array (\n"...)
+		off, err := x.elements(data, start+1, n, level, false)
+		if err != nil {
+			return 0, err
+		}
+		if level > 1 {
+			x.indent(level - 1)
+		}
+		x.out = append(x.out, ')')
+		return off, nil
+	case phpTypeObject:
+		n, start := readLength(data, off+2)
+		name := string(data[start+1 : start+1+n])
+		n, start = readLength(data, start+n+3)
+		if level > 1 {
+			x.out = append(x.out, '\n')
+			x.indent(level - 1)
+		}
+		if name == "stdClass" {
+			x.out = append(x.out, "(object) array(\n"...)
+		} else {
+			x.out = append(x.out, '\\')
+			x.out = append(x.out, name...)
+			x.out = append(x.out, "::__set_state(array(\n"...)
+		}
+		off, err := x.elements(data, start+1, n, level, true)
+		if err != nil {
+			return 0, err
+		}
+		if level > 1 {
+			x.indent(level - 1)
+		}
+		if name == "stdClass" {
+			x.out = append(x.out, ')')
+		} else {
+			x.out = append(x.out, "))"...)
+		}
+		return off, nil
+	case phpTypeCustom:
+		n, start := readLength(data, off+2)
+		return 0, &UnsupportedValueError{Str: "custom serialized object of class " + string(data[start+1:start+1+n])}
+	}
+	panic(phasePanicMsg)
+
+}
+
+func (x *varExporter) elements(data []byte, off int, n int, level int, object bool) (int, error) {
+
+	for i := 0; i < n; i++ {
+
+		// php indents object properties one space more than array elements
+		if object {
+			x.indent(level + 2)
+		} else {
+			x.indent(level + 1)
+		}
+
+		if phpValueType(data[off]) == phpTypeInteger {
+			end := off + 2 + bytes.IndexByte(data[off+2:], phpTerminator)
+			x.integer(string(data[off+2 : end]))
+			off = end + 1
+		} else {
+			length, start := readLength(data, off+2)
+			key := string(data[start+1 : start+1+length])
+			off = start + length + 3
+			switch n, ok := phpIntKey(key); {
+			case object:
+				name, _ := unmangleProperty(key)
+				x.out = appendVarExportString(x.out, []byte(name), false)
+			case ok:
+				x.integer(strconv.FormatInt(n, 10))
+			default:
+				x.out = appendVarExportString(x.out, []byte(key), true)
+			}
+		}
+		x.out = append(x.out, " => "...)
+
+		var err error
+		if off, err = x.value(data, off, level+2); err != nil {
+			return 0, err
+		}
+		x.out = append(x.out, ",\n"...)
+
+	}
+	return off + 1, nil
+
+}
+
+func (x *varExporter) integer(s string) {
+
+	// the literal -9223372036854775808 is a float in php
+	if s == "-9223372036854775808" {
+		s = "-9223372036854775807-1"
+	}
+	x.out = append(x.out, s...)
+
+}
+
+// appendVarExportString quotes s with single quotes. var_export writes nul
+// bytes as ' . "\0" . ' except in property names.
+func appendVarExportString(b []byte, s []byte, nul bool) []byte {
+
+	b = append(b, '\'')
+	for _, c := range s {
+		switch {
+		case c == '\'' || c == '\\':
+			b = append(b, '\\', c)
+		case c == 0 && nul:
+			b = append(b, `' . "\0" . '`...)
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, '\'')
+
+}
+
+func appendVarExportFloat(b []byte, f float64) []byte {
+
+	n := len(b)
+	b = appendPHPFloat(b, f, 17)
+	if !math.IsInf(f, 0) && !math.IsNaN(f) && bytes.IndexAny(b[n:], ".E") < 0 {
+		b = append(b, ".0"...)
+	}
+	return b
+
+}
+
+// appendPrecisionFloat formats f like php's (string) cast and echo do, with
+// precision 14.
+func appendPrecisionFloat(b []byte, f float64) []byte {
+
+	if !math.IsInf(f, 0) && !math.IsNaN(f) {
+		f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'e', 13, 64), 64)
+	}
+	return appendPHPFloat(b, f, 14)
+
+}
+
+// appendPHPFloat formats f like php's %.*H with serialize_precision -1, the
+// shortest representation, switching to exponents beyond precision digits.
+func appendPHPFloat(b []byte, f float64, precision int) []byte {
+
+	switch {
+	case math.IsInf(f, 1):
+		return append(b, "INF"...)
+	case math.IsInf(f, -1):
+		return append(b, "-INF"...)
+	case math.IsNaN(f):
+		return append(b, "NAN"...)
+	}
+
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	if s[0] == '-' {
+		b = append(b, '-')
+		s = s[1:]
+	}
+	e := strings.IndexByte(s, 'e')
+	digits := strings.Replace(s[:e], ".", "", 1)
+	exp, _ := strconv.Atoi(s[e+1:])
+	decpt := exp + 1
+
+	switch {
+	case decpt < -3 || decpt > precision:
+		b = append(b, digits[0], '.')
+		if len(digits) > 1 {
+			b = append(b, digits[1:]...)
+		} else {
+			b = append(b, '0')
+		}
+		b = append(b, 'E')
+		if exp < 0 {
+			b = append(b, '-')
+			exp = -exp
+		} else {
+			b = append(b, '+')
+		}
+		b = strconv.AppendInt(b, int64(exp), 10)
+	case decpt <= 0:
+		b = append(b, "0."...)
+		for i := decpt; i < 0; i++ {
+			b = append(b, '0')
+		}
+		b = append(b, digits...)
+	default:
+		for i := 0; i < decpt; i++ {
+			if i < len(digits) {
+				b = append(b, digits[i])
+			} else {
+				b = append(b, '0')
+			}
+		}
+		if len(digits) > decpt {
+			b = append(b, '.')
+			b = append(b, digits[decpt:]...)
+		}
+	}
+	return b
+
+}
@@ -0,0 +1,83 @@
+package phpserialize
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestMarshalVarExport(t *testing.T) {
+
+	queue, count := "default", 3
+
+	testList := []struct {
+		value  interface{}
+		expect string
+	}{
+		{nil, "NULL"},
+		{true, "true"},
+		{-12, "-12"},
+		{int64(math.MinInt64), "-9223372036854775807-1"},
+		{1.0, "1.0"},
+		{-0.5, "-0.5"},
+		{0.30000000000000004, "0.30000000000000004"},
+		{1e15, "1000000000000000.0"},
+		{1e25, "1.0E+25"},
+		{1.5e-7, "1.5E-7"},
+		{0.0001, "0.0001"},
+		{math.Inf(-1), "-INF"},
+		{"it's a \\ test", `'it\'s a \\ test'`},
+		{"a\x00b", `'a' . "\0" . 'b'`},
+		{[]int{}, "array (\n)"},
+		{[]interface{}{true, nil}, "array (\n  0 => true,\n  1 => NULL,\n)"},
+		{map[string]interface{}{"a": 1, "b": []int{1}, "5": "x"},
+			"array (\n  5 => 'x',\n  'a' => 1,\n  'b' => \n  array (\n    0 => 1,\n  ),\n)"},
+		{testObject{"x"}, "\\testSerial\\testObject::__set_state(array(\n   'a' => 'x',\n))"},
+		{testVisibility{Name: "a", Queue: &queue, Secret: "s", Count: &count},
+			"\\Job::__set_state(array(\n   'name' => 'a',\n   'queue' => 'default',\n   'secret' => 's',\n   'count' => 3,\n))"},
+		{[]interface{}{testObject{"x"}},
+			"array (\n  0 => \n  \\testSerial\\testObject::__set_state(array(\n     'a' => 'x',\n  )),\n)"},
+	}
+
+	for i, test := range testList {
+
+		data, err := MarshalVarExport(test.value)
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(data) != test.expect {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.expect, data)
+		}
+
+	}
+
+	data, err := SerializedToVarExport([]byte(`O:8:"stdClass":1:{s:1:"a";a:1:{i:0;i:1;}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "(object) array(\n   'a' => \n  array (\n    0 => 1,\n  ),\n)"; string(data) != expect {
+		t.Fatalf("expect:%s got %s", expect, data)
+	}
+
+	if _, err = SerializedToVarExport([]byte(`C:3:"Foo":0:{}`)); err == nil {
+		t.Fatal("expect an error for a custom serialized object")
+	}
+	if _, err = SerializedToVarExport(nil); err == nil {
+		t.Fatal("expect an error for empty data")
+	}
+
+}
+
+func TestEncoder_VarExport(t *testing.T) {
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.VarExport()
+	if err := enc.Encode(map[string]string{"driver": "redis"}); err != nil {
+		t.Fatal(err)
+	}
+	if expect := "array (\n  'driver' => 'redis',\n)"; buf.String() != expect {
+		t.Fatalf("expect:%s got %s", expect, buf.String())
+	}
+
+}
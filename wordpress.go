@@ -0,0 +1,129 @@
+package phpserialize
+
+import (
+	"bytes"
+)
+
+// IsSerialized reports whether WordPress' is_serialized would take data for
+// a serialized value. It is a cheap check of the first and last bytes, not a
+// validation, use Valid for that. Without strict the data may be followed by
+// anything.
+func IsSerialized(data []byte, strict bool) bool {
+
+	data = wpTrim(data)
+	if string(data) == phpNullValue {
+		return true
+	}
+	if len(data) < 4 || data[1] != phpSeparator {
+		return false
+	}
+
+	if strict {
+		if last := data[len(data)-1]; last != phpTerminator && last != phpRightBraces {
+			return false
+		}
+	} else {
+		semicolon := bytes.IndexByte(data, phpTerminator)
+		brace := bytes.IndexByte(data, phpRightBraces)
+		if semicolon < 0 && brace < 0 {
+			return false
+		}
+		if (semicolon >= 0 && semicolon < 3) || (brace >= 0 && brace < 4) {
+			return false
+		}
+	}
+
+	switch data[0] {
+	case 's':
+		if strict {
+			if data[len(data)-2] != phpDoubleQuote {
+				return false
+			}
+		} else if bytes.IndexByte(data, phpDoubleQuote) < 0 {
+			return false
+		}
+		return wpHasLength(data)
+	case 'a', 'O', 'E':
+		return wpHasLength(data)
+	case 'b', 'i', 'd':
+		p := 2
+		for p < len(data) && bytes.IndexByte([]byte("0123456789.E+-"), data[p]) >= 0 {
+			p++
+		}
+		if p == 2 || p >= len(data) || data[p] != phpTerminator {
+			return false
+		}
+		return !strict || p == len(data)-1
+	}
+	return false
+
+}
+
+// MaybeUnserialize works like WordPress' maybe_unserialize: data that
+// IsSerialized is unmarshaled into v, anything else is stored in v as a
+// string. Where php returns false for broken data the error is returned.
+func MaybeUnserialize(data []byte, v interface{}) error {
+
+	if IsSerialized(data, true) {
+		return Unmarshal(wpTrim(data), v)
+	}
+	return Unmarshal(stringValue(data), v)
+
+}
+
+// MaybeUnserializeDeep is MaybeUnserialize for values that were serialized
+// more than once, strings are unwrapped as long as they still look
+// serialized.
+func MaybeUnserializeDeep(data []byte, v interface{}) error {
+
+	for {
+		trimmed := wpTrim(data)
+		if !IsSerialized(trimmed, true) || phpValueType(trimmed[0]) != phpTypeString {
+			break
+		}
+		var s string
+		if Unmarshal(trimmed, &s) != nil || !IsSerialized([]byte(s), true) {
+			break
+		}
+		data = []byte(s)
+	}
+	return MaybeUnserialize(data, v)
+
+}
+
+// MaybeSerialize returns what WordPress' maybe_serialize stores for v.
+// Arrays, maps, structs and objects are serialized, scalars are stored as the
+// string php converts them to, unless that string already looks serialized,
+// then it is serialized once more.
+func MaybeSerialize(v interface{}) ([]byte, error) {
+
+	s, err := phpString(v)
+	if _, ok := err.(*UnsupportedTypeError); ok {
+		return Marshal(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if IsSerialized(s, false) {
+		return Marshal(string(s))
+	}
+	return s, nil
+
+}
+
+// wpTrim trims data like php's trim.
+func wpTrim(data []byte) []byte {
+	return bytes.Trim(data, " \t\n\r\x00\x0b")
+}
+
+// wpHasLength matches /^X:[0-9]+:/ on data.
+func wpHasLength(data []byte) bool {
+
+	p := 2
+	for p < len(data) && data[p] >= '0' && data[p] <= '9' {
+		p++
+	}
+	return p > 2 && p < len(data) && data[p] == phpSeparator
+
+}
@@ -0,0 +1,96 @@
+package phpserialize
+
+import (
+	"testing"
+)
+
+func TestIsSerialized(t *testing.T) {
+
+	testList := []struct {
+		data   string
+		strict bool
+		expect bool
+	}{
+		{`N;`, true, true},
+		{" a:0:{}\n", true, true},
+		{`s:5:"hello";`, true, true},
+		{`s:50:"x";`, true, true},
+		{`s:5:"hello"; `, true, true},
+		{`s:5:"hello";x`, true, false},
+		{`s:5:"hello";x`, false, true},
+		{`s:5:hello;`, true, false},
+		{`i:12;`, true, true},
+		{`i:12;x`, true, false},
+		{`i:12;x`, false, true},
+		{`d:1.5E+3;`, true, true},
+		{`b:x;`, true, false},
+		{`O:3:"Foo":0:{}`, true, true},
+		{`E:7:"Foo:Bar";`, true, true},
+		{`x:1:{}`, true, false},
+		{`a:{}`, true, false},
+		{`hello`, false, false},
+		{`i:;`, false, false},
+		{`a:`, false, false},
+	}
+
+	for i, test := range testList {
+		if IsSerialized([]byte(test.data), test.strict) != test.expect {
+			t.Fatalf("Test fail at index %d, expect:%v got %v", i, test.expect, !test.expect)
+		}
+	}
+
+}
+
+func TestMaybeUnserialize(t *testing.T) {
+
+	var v map[string]int
+	if err := MaybeUnserialize([]byte("a:1:{s:1:\"a\";i:1;}\n"), &v); err != nil || v["a"] != 1 {
+		t.Fatalf("expect array got %v %v", v, err)
+	}
+
+	var s string
+	if err := MaybeUnserialize([]byte("plain"), &s); err != nil || s != "plain" {
+		t.Fatalf("expect plain got %s %v", s, err)
+	}
+	if err := MaybeUnserialize([]byte("s:8:\"s:1:\"x\";\";"), &s); err != nil || s != `s:1:"x";` {
+		t.Fatalf("expect s:1:\"x\"; got %s %v", s, err)
+	}
+	if err := MaybeUnserializeDeep([]byte("s:8:\"s:1:\"x\";\";"), &s); err != nil || s != "x" {
+		t.Fatalf("expect x got %s %v", s, err)
+	}
+	if err := MaybeUnserialize([]byte("s:3:\"x\";"), &s); err == nil {
+		t.Fatal("expect an error for broken data")
+	}
+
+}
+
+func TestMaybeSerialize(t *testing.T) {
+
+	testList := []struct {
+		value  interface{}
+		expect string
+	}{
+		{"hello", "hello"},
+		{`s:1:"x";`, `s:8:"s:1:"x";";`},
+		{`i:1;junk`, `s:8:"i:1;junk";`},
+		{12, "12"},
+		{true, "1"},
+		{0.1, "0.1"},
+		{1.0 / 3, "0.33333333333333"},
+		{1e20, "1.0E+20"},
+		{nil, ""},
+		{[]int{1}, `a:1:{i:0;i:1;}`},
+		{map[string]string{"a": "b"}, `a:1:{s:1:"a";s:1:"b";}`},
+	}
+
+	for i, test := range testList {
+		data, err := MaybeSerialize(test.value)
+		if err != nil {
+			t.Fatalf("Test fail at index %d: %v", i, err)
+		}
+		if string(data) != test.expect {
+			t.Fatalf("Test fail at index %d, expect:%s got %s", i, test.expect, data)
+		}
+	}
+
+}